@@ -0,0 +1,159 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"reflect"
+)
+
+// WithOnlineAggregation makes every insert merge the inserted IP/mask with its adjacent sibling carrying equal value
+// into their common parent (e.g. two /25s into the /24), cascading upward while possible. Parent already holding
+// other value is never overwritten. Values are compared by equal, if nil values are compared by ==.
+func WithOnlineAggregation(equal func(a, b interface{}) bool) Option {
+	return func(tree *Tree) {
+		if equal == nil {
+			equal = valuesEqual
+		}
+		tree.aggregateEqual = equal
+	}
+}
+
+// valuesEqual compares values by ==, values of not comparable types are never equal.
+func valuesEqual(a, b interface{}) bool {
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb || !ta.Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// aggregate merges the valued node with its sibling into the parent while they carry equal values.
+func (tree *Tree) aggregate(n *node) {
+	for n.parent != nil {
+		p := n.parent
+		s := p.left
+		if s == n {
+			s = p.right
+		}
+		if s == nil || s.value == nil || !tree.aggregateEqual(s.value, n.value) {
+			return
+		}
+		if p.value != nil && !tree.aggregateEqual(p.value, n.value) {
+			return
+		}
+		if p.value == nil {
+			tree.countValuedNodes++
+		}
+		tree.changed(p, p.value, n.value)
+		p.value = n.value
+		p.meta = n.meta
+		for _, c := range []*node{n, s} {
+			tree.changed(c, c.value, nil)
+			c.value = nil
+			c.meta = nil
+			tree.countValuedNodes--
+			if c.left == nil && c.right == nil {
+				if p.left == c {
+					p.left = nil
+				} else {
+					p.right = nil
+				}
+				tree.updateUnused(c)
+			}
+		}
+		n = p
+	}
+}
+
+// AggregatedWalk walks the minimal set of IP/masks (with values) giving the same longest match for every address
+// as the tree: adjacent IP/masks with equal values are collapsed into their covering IP/mask and IP/masks carrying
+// the same value as the IP/mask covering them are dropped (e.g. two /25s with the same value become a /24).
+// The tree is not changed. Values are compared by equal, if nil values are compared by ==. As in WalkTree, if
+// wtfunc returns false the IP/masks below the current one are skipped.
+func (tree *Tree) AggregatedWalk(opt OptWalk, equal func(a, b interface{}) bool, wtfunc WalkTreeFunc) error {
+	if equal == nil {
+		equal = valuesEqual
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	s := summarizer{equal: equal, now: tree.expiryNow()}
+	if uniform, value, _ := s.summarize(tree.root, make([]byte, 0, 128), nil); uniform && value != nil {
+		s.ret = append(s.ret, summary{value: value})
+	}
+	var skip []byte
+	for _, e := range s.ret {
+		if skip != nil && bytes.HasPrefix(e.walkpath, skip) {
+			continue
+		}
+		skip = nil
+		goDeeper, err := wtfunc(walkpath2net(tree.walkOpt(opt), e.walkpath), e.value)
+		if err != nil {
+			return err
+		}
+		if !goDeeper {
+			skip = e.walkpath
+		}
+	}
+	return nil
+}
+
+type summary struct {
+	walkpath []byte
+	value    interface{}
+}
+
+type summarizer struct {
+	equal func(a, b interface{}) bool
+	now   int64
+	ret   []summary
+}
+
+func (s *summarizer) same(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return s.equal(a, b)
+}
+
+// summarize returns whether every address under the node resolves to the same value (then also the value),
+// otherwise IP/masks needed under the node are appended in walk order. The last result tells whether some
+// address under the node is left to resolve to the inherited value, i.e. the covering IP/mask is needed.
+func (s *summarizer) summarize(n *node, walkpath []byte, inherited interface{}) (bool, interface{}, bool) {
+	if n == nil {
+		return true, inherited, true
+	}
+	value := inherited
+	if n.value != nil && !expired(n, s.now) {
+		value = n.value
+	}
+	mark := len(s.ret)
+	lu, lv, lr := s.summarize(n.left, append(walkpath, byte(0)), value)
+	lmark := len(s.ret)
+	ru, rv, rr := s.summarize(n.right, append(walkpath, byte(1)), value)
+	if lu && ru && s.same(lv, rv) {
+		return true, lv, s.same(lv, inherited)
+	}
+
+	// the node is split, emit its own value if some address resolves to it and uniform children with other values
+	var own []summary
+	relies := lr || rr
+	if relies && !s.same(value, inherited) {
+		own = append(own, summary{walkpath: append([]byte(nil), walkpath...), value: value})
+		relies = false
+	}
+	if lu && !lr {
+		own = append(own, summary{walkpath: append(append([]byte(nil), walkpath...), byte(0)), value: lv})
+	}
+	own = append(own, s.ret[mark:lmark]...)
+	if ru && !rr {
+		own = append(own, summary{walkpath: append(append([]byte(nil), walkpath...), byte(1)), value: rv})
+	}
+	own = append(own, s.ret[lmark:]...)
+	s.ret = append(s.ret[:mark], own...)
+	return false, nil, relies
+}
@@ -0,0 +1,133 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestOnlineAggregation(t *testing.T) {
+	tr := NewTree(WithOnlineAggregation(nil))
+	tr.AddCIDR("10.0.0.0/26", "a")
+	tr.AddCIDR("10.0.0.64/26", "a")
+	tr.AddCIDR("10.0.0.128/25", "a")
+	tr.AddCIDR("10.0.1.0/24", "b")
+
+	if inf, err := tr.FindExactCIDR("10.0.0.0/24"); err != nil || inf != "a" {
+		t.Errorf("Expected aggregated 10.0.0.0/24, got %v %v", inf, err)
+	}
+	if _, err := tr.FindExactCIDR("10.0.0.0/25"); err != ErrNotFound {
+		t.Errorf("Expected merged 10.0.0.0/25 to be gone, got %v", err)
+	}
+	if inf, _ := tr.FindCIDR("10.0.0.70"); inf != "a" {
+		t.Errorf("Wrong value, expected a, got %v", inf)
+	}
+	if inf, _ := tr.FindCIDR("10.0.1.1"); inf != "b" {
+		t.Errorf("Wrong value, expected b, got %v", inf)
+	}
+	nodes, values, _, _ := tr.GetStats()
+	if nodes != 26 || values != 2 {
+		t.Errorf("Wrong stats, expected 26 nodes and 2 values, got %d and %d", nodes, values)
+	}
+
+	// more specific prefixes below merged ones are kept
+	tr.AddCIDR("10.0.2.0/24", "c")
+	tr.AddCIDR("10.0.2.128/25", "x")
+	tr.AddCIDR("10.0.3.0/24", "c")
+	if inf, err := tr.FindExactCIDR("10.0.2.0/23"); err != nil || inf != "c" {
+		t.Errorf("Expected aggregated 10.0.2.0/23, got %v %v", inf, err)
+	}
+	if inf, _ := tr.FindCIDR("10.0.2.200"); inf != "x" {
+		t.Errorf("Wrong value, expected x, got %v", inf)
+	}
+
+	// not comparable values are never merged
+	tr.AddCIDR("10.1.0.0/25", []int{1})
+	tr.AddCIDR("10.1.0.128/25", []int{1})
+	if _, err := tr.FindExactCIDR("10.1.0.0/24"); err != ErrNotFound {
+		t.Errorf("Expected not comparable values not to be merged, got %v", err)
+	}
+}
+
+func TestAggregatedWalk(t *testing.T) {
+	tr := NewTree()
+	tr.AddCIDR("10.0.0.0/8", "b")
+	tr.AddCIDR("10.0.0.0/25", "a")
+	tr.AddCIDR("10.0.0.128/25", "a")
+	tr.AddCIDR("10.1.0.0/16", "b")
+	tr.AddCIDR("10.2.0.0/16", "c")
+	tr.AddCIDR("2001:db8::/48", "v6")
+	tr.AddCIDR("2001:db8:1::/48", "v6")
+	tr.AddCIDR("192.168.0.0/24", "x")
+	tr.AddCIDR("192.168.0.0/25", "y")
+	tr.AddCIDR("192.168.0.128/25", "z")
+
+	expected := []string{
+		"10.0.0.0/8 b",
+		"10.0.0.0/24 a",
+		"10.2.0.0/16 c",
+		"2001:db8::/47 v6",
+		"192.168.0.0/25 y",
+		"192.168.0.128/25 z",
+	}
+	var got []string
+	err := tr.AggregatedWalk(OptWalkIPAuto, nil, func(cidr net.IPNet, value interface{}) (bool, error) {
+		got = append(got, cidr.String()+" "+value.(string))
+		return true, nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("Wrong aggregated walk, expected %v, got %v", expected, got)
+	}
+	if _, values, _, _ := tr.GetStats(); values != 10 {
+		t.Errorf("Tree changed by aggregated walk, expected 10 values, got %d", values)
+	}
+
+	tr = NewTree()
+	tr.AddCIDR("0.0.0.0/1", 1)
+	tr.AddCIDR("128.0.0.0/1", 1)
+	got = nil
+	tr.AggregatedWalk(OptWalkIPAuto, nil, func(cidr net.IPNet, value interface{}) (bool, error) {
+		got = append(got, cidr.String())
+		return true, nil
+	})
+	if len(got) != 1 || got[0] != "0.0.0.0/0" {
+		t.Errorf("Wrong aggregated walk, expected [0.0.0.0/0], got %v", got)
+	}
+}
+
+func TestAggregatedWalkSkip(t *testing.T) {
+	tr := NewTree()
+	tr.AddCIDR("10.0.0.0/8", "b")
+	tr.AddCIDR("10.2.0.0/16", "c")
+	tr.AddCIDR("10.2.1.0/24", "d")
+	tr.AddCIDR("192.168.0.0/24", "x")
+
+	var got []string
+	err := tr.AggregatedWalk(OptWalkIPAuto, nil, func(cidr net.IPNet, value interface{}) (bool, error) {
+		got = append(got, cidr.String())
+		return cidr.String() != "10.2.0.0/16", nil
+	})
+	if err != nil {
+		t.Errorf("Expected no error when skipping, got %v", err)
+	}
+	expected := "10.0.0.0/8,10.2.0.0/16,192.168.0.0/24"
+	if strings.Join(got, ",") != expected {
+		t.Errorf("Wrong aggregated walk, expected %s, got %v", expected, got)
+	}
+
+	got = nil
+	err = tr.AggregatedWalk(OptWalkIPAuto, nil, func(cidr net.IPNet, value interface{}) (bool, error) {
+		got = append(got, cidr.String())
+		return false, nil
+	})
+	if err != nil || strings.Join(got, ",") != "10.0.0.0/8,192.168.0.0/24" {
+		t.Errorf("Wrong aggregated walk, expected [10.0.0.0/8 192.168.0.0/24] and no error, got %v %v", got, err)
+	}
+}
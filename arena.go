@@ -0,0 +1,67 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"math"
+)
+
+// defaultChunkGrowth is the number of nodes every chunk of the arena is larger than the previous one by default.
+const defaultChunkGrowth = 200
+
+// WithArenaGrowth sets size of the first chunk of the node arena (initial nodes) and how many times every next
+// chunk is larger than the previous one (factor, at least 1). By default the first chunk has 200 nodes and every
+// next one is 200 nodes larger than the previous (200, 400, 600 ...), so large trees get many small chunks.
+func WithArenaGrowth(initial int, factor float64) Option {
+	if initial < 1 {
+		initial = 1
+	}
+	if factor < 1 {
+		factor = 1
+	}
+	return WithArenaGrowthFunc(func(last int) int {
+		if last == 0 {
+			return initial
+		}
+		return int(math.Ceil(float64(last) * factor))
+	})
+}
+
+// WithArenaGrowthFunc sets function returning number of nodes of the next chunk of the node arena, given the number
+// of nodes of the chunk just filled up (zero for the first chunk).
+func WithArenaGrowthFunc(fn func(last int) int) Option {
+	return func(tree *Tree) {
+		tree.growth = fn
+	}
+}
+
+// chunkSize returns size of the chunk of the arena following the filled one of last nodes.
+func (tree *Tree) chunkSize(last int) int {
+	if tree.growth == nil {
+		return last + defaultChunkGrowth
+	}
+	if size := tree.growth(last); size > 0 {
+		return size
+	}
+	return 1
+}
+
+// WithCapacityHint sizes the node arena for about nPrefixes IP/masks to be added, instead of preallocating nodes by
+// bit depth like WithPreallocate. The arena grows as usual if the estimate falls short.
+func WithCapacityHint(nPrefixes int) Option {
+	return func(tree *Tree) {
+		tree.capacityHint = nPrefixes
+	}
+}
+
+// estimateNodes returns number of nodes taken by n IP/masks of a routing table: prefixes share the first log2(n)
+// levels of the tree, every one takes the rest of about 32 bits on its own.
+func estimateNodes(n int) int {
+	perPrefix := 32 - math.Log2(float64(n))
+	if perPrefix < 8 {
+		perPrefix = 8
+	}
+	return int(float64(n) * perPrefix)
+}
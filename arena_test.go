@@ -0,0 +1,56 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestArenaGrowth(t *testing.T) {
+	for name, tc := range map[string]struct {
+		opts   []Option
+		chunks []int
+	}{
+		"default": {nil, []int{200, 400, 600}},
+		"growth":  {[]Option{WithArenaGrowth(100, 2)}, []int{100, 200, 400, 800}},
+		"func": {[]Option{WithArenaGrowthFunc(func(last int) int {
+			return 1000
+		})}, []int{1000}},
+	} {
+		tr := NewTree(tc.opts...)
+		var chunks []int
+		allocated := 0
+		for i := uint32(0); len(chunks) < len(tc.chunks); i++ {
+			tr.AddCIDR(fmt.Sprintf("10.%d.%d.%d", i>>16&0xff, i>>8&0xff, i&0xff), i)
+			if _, _, total, _ := tr.GetStats(); total != allocated {
+				chunks = append(chunks, total-allocated)
+				allocated = total
+			}
+		}
+		for i := range tc.chunks {
+			if chunks[i] != tc.chunks[i] {
+				t.Errorf("Wrong chunks of %s, expected %v, got %v", name, tc.chunks, chunks)
+				break
+			}
+		}
+	}
+}
+
+func TestWithCapacityHint(t *testing.T) {
+	tr := NewTree(WithCapacityHint(10000))
+	_, _, total, _ := tr.GetStats()
+	if total < 10000*16 {
+		t.Errorf("Wrong number of allocated nodes, expected at least %d, got %d", 10000*16, total)
+	}
+	FillRandom(tr, 10000)
+	nodes, _, total2, _ := tr.GetStats()
+	if total2-nodes > total/2 || total2 > 2*total {
+		t.Errorf("Wrong arena size for %d nodes, got %d allocated (hint gave %d)", nodes, total2, total)
+	}
+	if _, _, total, _ = NewTree(WithCapacityHint(0)).GetStats(); total != 200 {
+		t.Errorf("Wrong number of allocated nodes without hint, expected 200, got %d", total)
+	}
+}
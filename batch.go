@@ -0,0 +1,55 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+// FindCIDRBatch is FindCIDR of many cidrs taking the lock once, values[i] is the value found for cidrs[i].
+// Errors are nil if all cidrs are valid, otherwise errs[i] is the error of cidrs[i] (nil for valid ones). IP/masks
+// with zone are not supported (see WithZones).
+func (tree *Tree) FindCIDRBatch(cidrs []string) (values []interface{}, errs []error) {
+	values = make([]interface{}, len(cidrs))
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	for i, cidr := range cidrs {
+		err := tree.checkZone([]byte(cidr))
+		if err == nil {
+			values[i], err = tree.findCIDRb([]byte(cidr))
+		}
+		if err != nil {
+			if errs == nil {
+				errs = make([]error, len(cidrs))
+			}
+			errs[i] = err
+		}
+	}
+	return values, errs
+}
+
+// Find32Batch is Find32 of many IPv4 addresses taking the lock once, values[i] is the value found for ips[i].
+func (tree *Tree) Find32Batch(ips []uint32) (values []interface{}) {
+	values = make([]interface{}, len(ips))
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	for i, ip := range ips {
+		values[i] = tree.find32(ip, 0xffffffff, findBest)
+	}
+	return values
+}
+
+// Find128Batch is Find128 of many IPv6 addresses taking the lock once, values[i] is the value found for ips[i].
+func (tree *Tree) Find128Batch(ips [][16]byte) (values []interface{}) {
+	values = make([]interface{}, len(ips))
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	for i := range ips {
+		values[i] = tree.find(ips[i][:], fullmask6, findBest)
+	}
+	return values
+}
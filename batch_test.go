@@ -0,0 +1,43 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestFindBatch(t *testing.T) {
+	tr := NewTree(WithLocking(true))
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("2001:db8::/32", 2)
+
+	values, errs := tr.FindCIDRBatch([]string{"10.1.1.1", "2001:db8::1", "8.8.8.8"})
+	if errs != nil {
+		t.Errorf("Wrong errors, expected nil, got %v", errs)
+	}
+	if len(values) != 3 || values[0] != 1 || values[1] != 2 || values[2] != nil {
+		t.Errorf("Wrong values, expected [1 2 <nil>], got %v", values)
+	}
+	values, errs = tr.FindCIDRBatch([]string{"10.1.1.1", "10.1.1.x"})
+	if len(errs) != 2 || errs[0] != nil || !errors.Is(errs[1], ErrBadIP) {
+		t.Errorf("Wrong errors, expected [<nil> ErrBadIP], got %v", errs)
+	}
+	if values[0] != 1 || values[1] != nil {
+		t.Errorf("Wrong values, expected [1 <nil>], got %v", values)
+	}
+
+	values = tr.Find32Batch([]uint32{0x0a000001, 0x08080808})
+	if values[0] != 1 || values[1] != nil {
+		t.Errorf("Wrong values, expected [1 <nil>], got %v", values)
+	}
+	var ip [16]byte
+	copy(ip[:], net.ParseIP("2001:db8::1"))
+	values = tr.Find128Batch([][16]byte{ip, {}})
+	if values[0] != 2 || values[1] != nil {
+		t.Errorf("Wrong values, expected [2 <nil>], got %v", values)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func benchTree(b *testing.B, opts ...Option) (*Tree, []string) {
+	tr := NewTree(opts...)
+	prefixes := FillRandom(tr, 10000)
+	b.ResetTimer()
+	return tr, prefixes
+}
+
+func BenchmarkSetCIDR(b *testing.B) {
+	g := NewPrefixGenerator(2)
+	prefixes := make([]string, 10000)
+	for i := range prefixes {
+		prefixes[i] = g.Prefix()
+	}
+	tr := NewTree()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tr.SetCIDR(prefixes[i%len(prefixes)], i)
+	}
+}
+
+func BenchmarkFindCIDRIPv4(b *testing.B) {
+	tr, _ := benchTree(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tr.FindCIDR("10.20.30.40")
+	}
+}
+
+func BenchmarkFindCIDRIPv6(b *testing.B) {
+	tr, _ := benchTree(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tr.FindCIDR("2001:db8:1:2::1")
+	}
+}
+
+func BenchmarkFind32(b *testing.B) {
+	tr, _ := benchTree(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tr.Find32(uint32(i) * 2654435761)
+	}
+}
+
+func BenchmarkFind128(b *testing.B) {
+	tr, _ := benchTree(b)
+	ip := [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ip[2] = byte(i)
+		tr.Find128(ip)
+	}
+}
+
+func BenchmarkFindAddr(b *testing.B) {
+	tr, _ := benchTree(b)
+	addr := netip.MustParseAddr("2001:db8:1:2::1")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tr.FindAddr(addr)
+	}
+}
+
+func BenchmarkFindExactCIDR(b *testing.B) {
+	tr, prefixes := benchTree(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tr.FindExactCIDR(prefixes[i%len(prefixes)])
+	}
+}
+
+func BenchmarkFindCIDRParallel(b *testing.B) {
+	tr, _ := benchTree(b, WithLocking(true))
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			tr.FindCIDR("10.20.30.40")
+		}
+	})
+}
+
+func BenchmarkWalkTree(b *testing.B) {
+	tr, _ := benchTree(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tr.WalkTree(OptWalkIPAuto, func(cidr net.IPNet, value interface{}) (bool, error) { return true, nil })
+	}
+}
+
+// TestFindAllocs keeps the lookup hot paths from allocating, IPv6 FindCIDR allocates only by parsing the address.
+func TestFindAllocs(t *testing.T) {
+	tr := NewTree()
+	FillRandom(tr, 1000)
+	tr.AddCIDR("10.20.0.0/16", "v4")
+	tr.AddCIDR("2001:db8::/32", "v6")
+	ip := [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 1}
+	addr4, addr6 := netip.MustParseAddr("10.20.30.40"), netip.AddrFrom16(ip)
+	for name, tc := range map[string]struct {
+		max float64
+		fn  func()
+	}{
+		"FindCIDR IPv4":      {0, func() { tr.FindCIDR("10.20.30.40") }},
+		"FindCIDR IPv6":      {3, func() { tr.FindCIDR("2001:db8:1:2::1") }},
+		"FindExactCIDR IPv4": {0, func() { tr.FindExactCIDR("10.20.0.0/16") }},
+		"Find32":             {0, func() { tr.Find32(0x0a141e28) }},
+		"Find128":            {0, func() { tr.Find128(ip) }},
+		"FindAddr IPv4":      {0, func() { tr.FindAddr(addr4) }},
+		"FindAddr IPv6":      {0, func() { tr.FindAddr(addr6) }},
+	} {
+		if allocs := testing.AllocsPerRun(100, tc.fn); allocs > tc.max {
+			t.Errorf("Wrong allocations of %s, expected at most %v, got %v", name, tc.max, allocs)
+		}
+	}
+}
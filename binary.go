@@ -0,0 +1,87 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+func init() {
+	gob.Register(&Tree{})
+}
+
+// WithBinaryValueCodec sets functions encoding values in MarshalBinary and decoding them in UnmarshalBinary,
+// by default values are encoded by encoding/gob (values of own types have to be registered by gob.Register).
+func WithBinaryValueCodec(encode func(value interface{}) ([]byte, error), decode func(data []byte) (interface{}, error)) Option {
+	return func(tree *Tree) {
+		tree.binaryEncode, tree.binaryDecode = encode, decode
+	}
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler (used by encoding/gob too), the tree is written in the format
+// of Marshal. Metadata and expiration of values are not written.
+func (tree *Tree) MarshalBinary() ([]byte, error) {
+	encode := tree.binaryEncode
+	if encode == nil {
+		encode = gobEncodeValue
+	}
+	if tree.root == nil {
+		tree = NewTree()
+	}
+	var buf bytes.Buffer
+	if err := tree.Marshal(&buf, encode); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler (used by encoding/gob too), content of the tree
+// (a zero Tree is initialized first) is replaced by the tree of the data.
+func (tree *Tree) UnmarshalBinary(data []byte) error {
+	decode := tree.binaryDecode
+	if decode == nil {
+		decode = gobDecodeValue
+	}
+	loaded, err := UnmarshalTree(bytes.NewReader(data), decode)
+	if err != nil {
+		return err
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	tree.generation++
+	tree.root, tree.free, tree.alloc = loaded.root, loaded.free, loaded.alloc
+	tree.countNodes, tree.countValuedNodes = loaded.countNodes, loaded.countValuedNodes
+	tree.countAllocNodes, tree.countFreeNodes = loaded.countAllocNodes, loaded.countFreeNodes
+	tree.hasExpiry = false
+	if tree.lru != nil {
+		tree.lru.reset()
+	}
+	if tree.misses != nil {
+		tree.misses.invalidate(nil, 0)
+	}
+	if tree.index != nil {
+		tree.index.reset()
+	}
+	return nil
+}
+
+func gobEncodeValue(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gobDecodeValue(data []byte) (interface{}, error) {
+	var value interface{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,75 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"encoding/gob"
+	"strconv"
+	"testing"
+)
+
+func TestGob(t *testing.T) {
+	type config struct {
+		Name  string
+		Allow *Tree
+	}
+	tr := NewTree()
+	for cidr, v := range map[string]interface{}{"10.0.0.0/8": "private", "192.168.1.0/24": 24, "2001:db8::/32": true} {
+		if err := tr.AddCIDR(cidr, v); err != nil {
+			t.Error(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(config{Name: "edge", Allow: tr}); err != nil {
+		t.Fatal(err)
+	}
+	var c config
+	if err := gob.NewDecoder(&buf).Decode(&c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "edge" || c.Allow == nil {
+		t.Fatalf("Wrong config decoded: %+v", c)
+	}
+	for ip, expected := range map[string]interface{}{"10.1.1.1": "private", "192.168.1.1": 24, "2001:db8::1": true, "8.8.8.8": nil} {
+		inf, err := c.Allow.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != expected {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, expected, inf)
+		}
+	}
+}
+
+func TestBinaryValueCodec(t *testing.T) {
+	encode := func(value interface{}) ([]byte, error) { return []byte(strconv.Itoa(value.(int))), nil }
+	decode := func(data []byte) (interface{}, error) { return strconv.Atoi(string(data)) }
+	tr := NewTree(WithBinaryValueCodec(encode, decode))
+	if err := tr.AddCIDR("10.0.0.0/8", 10); err != nil {
+		t.Error(err)
+	}
+	data, err := tr.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := NewTree(WithBinaryValueCodec(encode, decode))
+	if err = dst.AddCIDR("1.1.1.1", 1); err != nil {
+		t.Error(err)
+	}
+	if err = dst.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if inf, _ := dst.FindCIDR("10.1.1.1"); inf != 10 {
+		t.Errorf("Wrong value, expected 10, got %v", inf)
+	}
+	if inf, _ := dst.FindCIDR("1.1.1.1"); inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+	if err = dst.UnmarshalBinary(data[:3]); err != ErrBadFormat {
+		t.Errorf("Wrong error, expected %v, got %v", ErrBadFormat, err)
+	}
+}
@@ -0,0 +1,394 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"sort"
+	"time"
+)
+
+// PrefixValue is a CIDR and its value for bulk inserts.
+type PrefixValue struct {
+	CIDR  string
+	Value interface{}
+}
+
+// NewTreeFromSlice creates Tree (configured by opts) and fills it with all cidr/value pairs, see BulkAdd.
+func NewTreeFromSlice(entries []PrefixValue, opts ...Option) (*Tree, error) {
+	tree := NewTree(opts...)
+	if err := tree.BulkAdd(entries); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// BulkAdd adds values associated with IP/masks to the tree in one locked pass, much faster than AddCIDR
+// for each of them. Will return error for invalid CIDR (nothing is added then) or if value already exists
+// (entries ordered before it in address order are added).
+func (tree *Tree) BulkAdd(entries []PrefixValue) error {
+	parsed := make([]prefixEntry, len(entries))
+	for i := range entries {
+		if err := tree.checkStrict([]byte(entries[i].CIDR)); err != nil {
+			return err
+		}
+		e, err := tree.parseEntry([]byte(entries[i].CIDR))
+		if err != nil {
+			return err
+		}
+		e.value = entries[i].Value
+		parsed[i] = e
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	return tree.insertEntries(parsed, false)
+}
+
+// prefixEntry is a parsed IP/mask waiting to be inserted into the tree.
+type prefixEntry struct {
+	v4         bool
+	ip32, mk32 uint32
+	ip         net.IP
+	mask       net.IPMask
+	value      interface{}
+}
+
+// NewTreeFromMap creates Tree (configured by opts) and fills it with all cidr/value pairs of the map.
+// Will return error for invalid CIDR or if two keys of the map represent the same IP/mask.
+func NewTreeFromMap(m map[string]interface{}, opts ...Option) (*Tree, error) {
+	tree := NewTree(opts...)
+	entries := make([]prefixEntry, 0, len(m))
+	for cidr, val := range m {
+		if err := tree.checkStrict([]byte(cidr)); err != nil {
+			return nil, err
+		}
+		e, err := tree.parseEntry([]byte(cidr))
+		if err != nil {
+			return nil, err
+		}
+		e.value = val
+		entries = append(entries, e)
+	}
+	if err := tree.insertEntries(entries, false); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// NewTreeFromPrefixMap creates Tree (configured by opts) and fills it with all prefix/value pairs of the map.
+// Will return error for invalid prefix or if two keys of the map represent the same IP/mask.
+func NewTreeFromPrefixMap(m map[netip.Prefix]interface{}, opts ...Option) (*Tree, error) {
+	tree := NewTree(opts...)
+	entries := make([]prefixEntry, 0, len(m))
+	for p, val := range m {
+		if err := tree.checkStrictPrefix(p); err != nil {
+			return nil, err
+		}
+		e, err := tree.prefix2entry(p)
+		if err != nil {
+			return nil, err
+		}
+		e.value = val
+		entries = append(entries, e)
+	}
+	if err := tree.insertEntries(entries, false); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// parseEntry parses the cidr, IPv4-mapped addresses are taken as IPv4 ones if the tree is WithUnmapIPv4.
+func (tree *Tree) parseEntry(cidr []byte) (prefixEntry, error) {
+	if err := tree.checkZone(cidr); err != nil {
+		return prefixEntry{}, err
+	}
+	return parseEntryAs(cidr, tree.isIPv4(cidr))
+}
+
+func parseEntry(cidr []byte) (prefixEntry, error) {
+	return parseEntryAs(cidr, isIPv4(cidr))
+}
+
+func parseEntryAs(cidr []byte, v4 bool) (prefixEntry, error) {
+	if v4 {
+		ip, mask, err := parsecidr4(cidr)
+		if err != nil {
+			return prefixEntry{}, err
+		}
+		return prefixEntry{v4: true, ip32: ip, mk32: mask}, nil
+	}
+	ip, mask, err := parsecidr6(cidr)
+	if err != nil {
+		return prefixEntry{}, err
+	}
+	if len(ip) != len(mask) {
+		return prefixEntry{}, badIP(cidr, "address and mask lengths differ")
+	}
+	return prefixEntry{ip: ip, mask: mask}, nil
+}
+
+func (tree *Tree) prefix2entry(p netip.Prefix) (prefixEntry, error) {
+	if !p.IsValid() {
+		return prefixEntry{}, ErrBadIP
+	}
+	p = tree.unmapPrefix(p).Masked()
+	if p.Addr().Is4() {
+		b := p.Addr().As4()
+		return prefixEntry{v4: true, ip32: binary.BigEndian.Uint32(b[:]), mk32: 0xffffffff << (32 - p.Bits())}, nil
+	}
+	b := p.Addr().As16()
+	return prefixEntry{ip: net.IP(b[:]), mask: net.CIDRMask(p.Bits(), net.IPv6len*8)}, nil
+}
+
+// checkStrictPrefix is checkStrict for netip.Prefix.
+func (tree *Tree) checkStrictPrefix(p netip.Prefix) error {
+	if !p.IsValid() || !tree.strict && tree.onNormalize == nil {
+		return nil
+	}
+	u := tree.unmapPrefix(p)
+	masked := u.Masked()
+	switch {
+	case masked == u:
+		return nil
+	case tree.strict:
+		return badIP([]byte(p.String()), "host bits set beyond mask")
+	}
+	tree.onNormalize(p.String(), net.IPNet{IP: masked.Addr().AsSlice(), Mask: net.CIDRMask(masked.Bits(), masked.Addr().BitLen())})
+	return nil
+}
+
+func net2entry(n net.IPNet) (prefixEntry, error) {
+	ones, bits := n.Mask.Size()
+	if ip4 := n.IP.To4(); ip4 != nil && (bits == net.IPv4len*8 || bits == net.IPv6len*8 && ones >= 96) {
+		if bits == net.IPv6len*8 {
+			ones -= 96
+		}
+		return prefixEntry{v4: true, ip32: binary.BigEndian.Uint32(ip4) & (0xffffffff << (32 - ones)), mk32: 0xffffffff << (32 - ones)}, nil
+	}
+	ip16 := n.IP.To16()
+	if ip16 == nil || bits != net.IPv6len*8 {
+		return prefixEntry{}, ErrBadIP
+	}
+	mask := net.CIDRMask(ones, bits)
+	return prefixEntry{ip: ip16.Mask(mask), mask: mask}, nil
+}
+
+func ip2entry(ip net.IP) (prefixEntry, error) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return prefixEntry{v4: true, ip32: binary.BigEndian.Uint32(ip4), mk32: 0xffffffff}, nil
+	}
+	ip16 := ip.To16()
+	if ip16 == nil {
+		return prefixEntry{}, ErrBadIP
+	}
+	return prefixEntry{ip: ip16, mask: net.CIDRMask(net.IPv6len*8, net.IPv6len*8)}, nil
+}
+
+// sortEntries orders entries by address and then by mask so covering prefixes are inserted before their subnets,
+// this way every insert walks an already built stem and only appends the missing tail of nodes.
+func sortEntries(entries []prefixEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := &entries[i], &entries[j]
+		if a.v4 != b.v4 {
+			return a.v4
+		}
+		if a.v4 {
+			if a.ip32 != b.ip32 {
+				return a.ip32 < b.ip32
+			}
+			return a.mk32 < b.mk32
+		}
+		if c := bytes.Compare(a.ip, b.ip); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(a.mask, b.mask) < 0
+	})
+}
+
+// key returns the entry as the tree key, IPv4 takes first 32 bits of the key.
+func (e *prefixEntry) key() (key [16]byte, bits int) {
+	if e.v4 {
+		binary.BigEndian.PutUint32(key[:], e.ip32&e.mk32)
+		return key, masklen32(e.mk32)
+	}
+	copy(key[:], e.ip)
+	bits, _ = e.mask.Size()
+	return key, bits
+}
+
+// insertEntries inserts entries in address order in one pass, the arena is sized up front for all new nodes
+// and every insert starts from the deepest node it shares with the previous one instead of from the root.
+func (tree *Tree) insertEntries(entries []prefixEntry, overwrite bool) error {
+	sortEntries(entries)
+	if tree.aggregateEqual != nil {
+		// aggregation releases nodes of the path, insert one by one
+		for i := range entries {
+			if err := tree.insertEntry(&entries[i], overwrite); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if tree.lru != nil {
+		defer tree.evict()
+	}
+	if tree.metrics != nil {
+		defer tree.observe("bulk insert", time.Now())
+	}
+	if tree.guard != nil {
+		tree.guard.enterWrite("insert")
+		defer tree.guard.exitWrite()
+	}
+	tree.generation++
+
+	// count nodes to be created: below the path the tree already has and the path the previous entry made
+	var prev [16]byte
+	var reserve, prevBits int
+	for i := range entries {
+		key, bits := tree.entryKey(&entries[i])
+		have := tree.pathBits(key, bits)
+		common := commonBits(prev, key, bits)
+		if common > prevBits {
+			common = prevBits
+		}
+		if common > have {
+			have = common
+		}
+		reserve += bits - have
+		prev, prevBits = key, bits
+	}
+	tree.reserve(reserve - tree.countFreeNodes)
+
+	path := make([]*node, 1, net.IPv6len*8+1)
+	path[0] = tree.root
+	prev, prevBits = [16]byte{}, 0
+	for i := range entries {
+		key, bits := tree.entryKey(&entries[i])
+		depth := commonBits(prev, key, bits)
+		if depth > prevBits {
+			depth = prevBits
+		}
+		path = path[:depth+1]
+		n := path[depth]
+		for ; depth < bits; depth++ {
+			right := keyBit(key, depth)
+			next := n.left
+			if right {
+				next = n.right
+			}
+			if next == nil {
+				next = tree.newnode()
+				tree.countNodes++
+				next.parent = n
+				if right {
+					n.right = next
+				} else {
+					n.left = next
+				}
+			}
+			n = next
+			path = append(path, n)
+		}
+		prev, prevBits = key, bits
+
+		if tree.misses != nil {
+			tree.misses.invalidate(key[:], bits)
+		}
+		if n.value != nil && !overwrite && !expired(n, tree.expiryNow()) {
+			return ErrNodeBusy
+		}
+		switch {
+		case n.value == nil && entries[i].value != nil:
+			tree.countValuedNodes++
+		case n.value != nil && entries[i].value == nil:
+			tree.countValuedNodes--
+		}
+		old := n.value
+		n.value = entries[i].value
+		tree.changed(n, old, n.value)
+		tree.inserted(n)
+		if n.value == nil && n.left == nil && n.right == nil {
+			// nil value leaves no empty leaf, the path may be released so the next entry starts at the root
+			tree.trimEmpty(n)
+			path, prevBits = path[:1], 0
+		}
+	}
+	return nil
+}
+
+// commonBits returns number of leading bits (up to max) equal in both keys.
+func commonBits(a, b [16]byte, max int) int {
+	for i := 0; i < max; i++ {
+		if keyBit(a, i) != keyBit(b, i) {
+			return i
+		}
+	}
+	return max
+}
+
+// pathBits returns how many bits of the key (up to bits) the tree already has nodes for.
+func (tree *Tree) pathBits(key [16]byte, bits int) int {
+	n := tree.root
+	for depth := 0; depth < bits; depth++ {
+		if keyBit(key, depth) {
+			n = n.right
+		} else {
+			n = n.left
+		}
+		if n == nil {
+			return depth
+		}
+	}
+	return bits
+}
+
+// reserve makes sure next n nodes are allocated from one arena chunk.
+func (tree *Tree) reserve(n int) {
+	if tree.pool != nil || n <= cap(tree.alloc)-len(tree.alloc) {
+		return
+	}
+	tree.countAllocNodes += n
+	tree.alloc = make([]node, n)[:0]
+}
+
+func (tree *Tree) insertEntry(e *prefixEntry, overwrite bool) error {
+	if e.v4 {
+		return tree.insert32(e.ip32, e.mk32, e.value, overwrite)
+	}
+	return tree.insert(e.ip, e.mask, e.value, overwrite)
+}
+
+func (tree *Tree) deleteEntry(e *prefixEntry, wholeRange bool) error {
+	if e.v4 {
+		return tree.delete32(e.ip32, e.mk32, wholeRange)
+	}
+	return tree.delete(e.ip, e.mask, wholeRange)
+}
+
+func (tree *Tree) findEntry(e *prefixEntry, what findWhat) interface{} {
+	if e.v4 {
+		return tree.find32(e.ip32, e.mk32, what)
+	}
+	return tree.find(e.ip, e.mask, what)
+}
+
+func (tree *Tree) findAllEntry(e *prefixEntry) []interface{} {
+	if e.v4 {
+		return tree.findAll32(e.ip32, e.mk32)
+	}
+	return tree.findAll(e.ip, e.mask)
+}
+
+func (tree *Tree) entryNode(e *prefixEntry) *node {
+	if e.v4 {
+		return tree.node32(e.ip32, e.mk32)
+	}
+	return tree.node(e.ip, e.mask)
+}
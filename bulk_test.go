@@ -0,0 +1,214 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func TestNewTreeFromMap(t *testing.T) {
+	tr, err := NewTreeFromMap(map[string]interface{}{
+		"1.2.3.0/24":     1,
+		"1.2.0.0/16":     2,
+		"1.2.3.128/25":   3,
+		"dead::/16":      4,
+		"dead:beef::/32": 5,
+		"10.0.0.1":       6,
+	}, WithLocking(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tr.safe {
+		t.Error("Option was not applied")
+	}
+	for cidr, exp := range map[string]int{
+		"1.2.3.1":      1,
+		"1.2.4.1":      2,
+		"1.2.3.200":    3,
+		"dead::1":      4,
+		"dead:beef::1": 5,
+		"10.0.0.1":     6,
+	} {
+		inf, err := tr.FindCIDR(cidr)
+		if err != nil {
+			t.Error(err)
+		} else if inf == nil || inf.(int) != exp {
+			t.Errorf("Wrong value for %s, expected %d, got %v", cidr, exp, inf)
+		}
+	}
+	_, values, _, _ := tr.GetStats()
+	if values != 6 {
+		t.Errorf("Wrong valued nodes count, expected 6, got %d", values)
+	}
+
+	if _, err = NewTreeFromMap(map[string]interface{}{"1.2.3.0/24": 1, "1.2.3.4/24": 2}); err != ErrNodeBusy {
+		t.Errorf("Expected ErrNodeBusy for duplicate prefix, got %v", err)
+	}
+	if _, err = NewTreeFromMap(map[string]interface{}{"1.2.3.x/24": 1}); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Expected ErrBadIP, got %v", err)
+	}
+}
+
+func TestNewTreeFromPrefixMap(t *testing.T) {
+	tr, err := NewTreeFromPrefixMap(map[netip.Prefix]interface{}{
+		netip.MustParsePrefix("1.2.3.0/24"):     1,
+		netip.MustParsePrefix("1.2.0.0/16"):     2,
+		netip.MustParsePrefix("dead:beef::/32"): 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for cidr, exp := range map[string]int{
+		"1.2.3.1":      1,
+		"1.2.4.1":      2,
+		"dead:beef::1": 3,
+	} {
+		inf, err := tr.FindCIDR(cidr)
+		if err != nil {
+			t.Error(err)
+		} else if inf == nil || inf.(int) != exp {
+			t.Errorf("Wrong value for %s, expected %d, got %v", cidr, exp, inf)
+		}
+	}
+	if _, err = NewTreeFromPrefixMap(map[netip.Prefix]interface{}{{}: 1}); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Expected ErrBadIP for invalid prefix, got %v", err)
+	}
+	hosty := map[netip.Prefix]interface{}{netip.MustParsePrefix("10.0.0.5/24"): 1}
+	if _, err = NewTreeFromPrefixMap(hosty, WithStrictHostBits(true)); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Expected ErrBadIP for host bits on strict tree, got %v", err)
+	}
+	var normalized string
+	tr, err = NewTreeFromPrefixMap(hosty, WithOnNormalize(func(cidr string, network net.IPNet) {
+		normalized = cidr + " " + network.String()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if normalized != "10.0.0.5/24 10.0.0.0/24" {
+		t.Errorf("Wrong normalization, expected 10.0.0.5/24 10.0.0.0/24, got %q", normalized)
+	}
+	if inf, _ := tr.FindExactCIDR("10.0.0.0/24"); inf != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+}
+
+func TestBulkAdd(t *testing.T) {
+	entries := []PrefixValue{
+		{"10.1.2.0/24", 3},
+		{"10.0.0.0/8", 1},
+		{"10.1.0.0/16", 2},
+		{"10.1.3.0/24", 4},
+		{"dead:beef::/32", 6},
+		{"dead::/16", 5},
+		{"192.168.0.1", 7},
+	}
+	tr, err := NewTreeFromSlice(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := NewTree()
+	for _, e := range entries {
+		ref.AddCIDR(e.CIDR, e.Value)
+	}
+	n1, v1, _, _ := tr.GetStats()
+	n2, v2, _, _ := ref.GetStats()
+	if n1 != n2 || v1 != v2 {
+		t.Errorf("Wrong stats, expected %d/%d, got %d/%d", n2, v2, n1, v1)
+	}
+	for _, e := range entries {
+		inf, err := tr.FindExactCIDR(e.CIDR)
+		if err != nil || inf != e.Value {
+			t.Errorf("Wrong value for %s, expected %v, got %v %v", e.CIDR, e.Value, inf, err)
+		}
+	}
+
+	// more entries into the existing tree
+	err = tr.BulkAdd([]PrefixValue{{"10.1.2.128/25", 8}, {"11.0.0.0/8", 9}})
+	if err != nil {
+		t.Error(err)
+	}
+	if inf, _ := tr.FindCIDR("10.1.2.200"); inf.(int) != 8 {
+		t.Errorf("Wrong value, expected 8, got %v", inf)
+	}
+	if _, v, _, _ := tr.GetStats(); v != 9 {
+		t.Errorf("Wrong valued nodes, expected 9, got %d", v)
+	}
+
+	if err = tr.BulkAdd([]PrefixValue{{"10.0.0.0/8", 10}}); err != ErrNodeBusy {
+		t.Errorf("Expected ErrNodeBusy, got %v", err)
+	}
+	if err = tr.BulkAdd([]PrefixValue{{"12.0.0.0/8", 10}, {"1.2.3.x", 11}}); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Expected ErrBadIP, got %v", err)
+	}
+	if inf, _ := tr.FindCIDR("12.0.0.1"); inf != nil {
+		t.Errorf("Expected nothing added on parse error, got %v", inf)
+	}
+}
+
+func TestBulkAddNilValues(t *testing.T) {
+	tr := NewTree()
+	if err := tr.BulkAdd([]PrefixValue{{"10.0.0.0/8", nil}, {"11.0.0.0/8", 1}}); err != nil {
+		t.Error(err)
+	}
+	if _, v, _, _ := tr.GetStats(); v != 1 {
+		t.Errorf("Wrong valued nodes, expected 1, got %d", v)
+	}
+
+	// overwriting value with nil
+	e, _ := parseEntry([]byte("11.0.0.0/8"))
+	if err := tr.insertEntries([]prefixEntry{e}, true); err != nil {
+		t.Error(err)
+	}
+	if _, v, _, _ := tr.GetStats(); v != 0 {
+		t.Errorf("Wrong valued nodes after overwrite, expected 0, got %d", v)
+	}
+
+	lru := NewTreeLRU(2)
+	if err := lru.BulkAdd([]PrefixValue{{"10.0.0.0/8", nil}, {"11.0.0.0/8", 1}}); err != nil {
+		t.Error(err)
+	}
+	if err := lru.AddCIDR("12.0.0.0/8", 2); err != nil {
+		t.Error(err)
+	}
+	if inf, _ := lru.FindCIDR("11.0.0.1"); inf != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+}
+
+func TestBulkAddReserve(t *testing.T) {
+	tr := NewTree()
+	var entries []PrefixValue
+	for i := 0; i < 256; i++ {
+		entries = append(entries, PrefixValue{fmt.Sprintf("10.0.%d.0/24", i), i})
+	}
+	if err := tr.BulkAdd(entries); err != nil {
+		t.Fatal(err)
+	}
+	tr.alloc = tr.alloc[:cap(tr.alloc)] // no room left in the current chunk
+	alloc := tr.countAllocNodes
+
+	// paths of these exist already, no node is created
+	if err := tr.BulkAdd([]PrefixValue{{"10.0.0.0/16", "a"}, {"10.0.0.0/20", "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if tr.countAllocNodes != alloc {
+		t.Errorf("Wrong allocated nodes, expected %d, got %d", alloc, tr.countAllocNodes)
+	}
+
+	// 8 new nodes below the existing /24 and 1 for 10.1.0.0/16 next to 10.0.0.0/16
+	if err := tr.BulkAdd([]PrefixValue{{"10.0.1.1/32", "c"}, {"10.1.0.0/16", "d"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := tr.countAllocNodes - alloc; got != 9 {
+		t.Errorf("Wrong allocated nodes, expected 9 more, got %d more", got)
+	}
+	if err := tr.Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// WithValueIndex makes the tree keep index of IP/masks by key(value), so FindByIndex does not walk the whole tree.
+// Values are indexed by themselves if key is nil, values (or keys) of not comparable types are not indexed.
+// The index is built by the first FindByIndex and then updated by every change of the tree.
+func WithValueIndex(key func(value interface{}) interface{}) Option {
+	return func(tree *Tree) {
+		if key == nil {
+			key = func(value interface{}) interface{} { return value }
+		}
+		tree.index = &valueIndex{key: key}
+	}
+}
+
+// valueIndex has own lock, it is built by lookups holding only read lock of the tree.
+type valueIndex struct {
+	key   func(value interface{}) interface{}
+	nets  map[interface{}]map[*node]indexedNet // nil until built
+	nodes map[*node]interface{}                // key each indexed node is kept under
+	sync.Mutex
+}
+
+type indexedNet struct {
+	walkpath []byte // orders IP/masks as the walk does
+	net      net.IPNet
+}
+
+// FindByValue returns all IP/masks (in walk order) whose values match.
+func (tree *Tree) FindByValue(match func(value interface{}) bool) []net.IPNet {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	var ret []net.IPNet
+	tree.walkNodes(OptWalkIPAuto, func(cidr net.IPNet, n *node) (bool, error) {
+		if match(n.value) {
+			ret = append(ret, cidr)
+		}
+		return true, nil
+	})
+	return ret
+}
+
+// FindByIndex returns all IP/masks (in walk order) whose values have the key in the index kept by the tree
+// (see WithValueIndex). Without index the tree is walked for values equal to the key.
+func (tree *Tree) FindByIndex(key interface{}) []net.IPNet {
+	if key == nil || !reflect.TypeOf(key).Comparable() {
+		return nil
+	}
+	if tree.index == nil {
+		return tree.FindByValue(func(value interface{}) bool { return valuesEqual(value, key) })
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	idx := tree.index
+	idx.Lock()
+	defer idx.Unlock()
+	if idx.nets == nil {
+		tree.buildIndex()
+	}
+	in := make([]indexedNet, 0, len(idx.nets[key]))
+	now := tree.expiryNow()
+	for n, i := range idx.nets[key] {
+		if !expired(n, now) {
+			in = append(in, i)
+		}
+	}
+	if len(in) == 0 {
+		return nil
+	}
+	sort.Slice(in, func(i, j int) bool { return bytes.Compare(in[i].walkpath, in[j].walkpath) < 0 })
+	ret := make([]net.IPNet, len(in))
+	for i := range in {
+		ret[i] = in[i].net
+	}
+	return ret
+}
+
+// PrefixesFor returns all IP/masks (in walk order) whose values have the key in the companion index of the tree,
+// e.g. all prefixes originated by an AS with the index of WithValueIndex keyed by origin ASN, see FindByIndex.
+func (tree *Tree) PrefixesFor(key interface{}) []net.IPNet {
+	return tree.FindByIndex(key)
+}
+
+// buildIndex indexes all values of the tree (including expired ones, they are skipped by lookups).
+func (tree *Tree) buildIndex() {
+	idx := tree.index
+	idx.nets = make(map[interface{}]map[*node]indexedNet)
+	idx.nodes = make(map[*node]interface{})
+	tree.walkNodes(OptWalkIPAuto|optWalkExpired, func(cidr net.IPNet, n *node) (bool, error) {
+		idx.add(n, n.value, cidr)
+		return true, nil
+	})
+}
+
+// add indexes node n holding value, the index must be built.
+func (idx *valueIndex) add(n *node, value interface{}, cidr net.IPNet) {
+	k := idx.key(value)
+	if k == nil || !reflect.TypeOf(k).Comparable() {
+		return
+	}
+	nets := idx.nets[k]
+	if nets == nil {
+		nets = make(map[*node]indexedNet)
+		idx.nets[k] = nets
+	}
+	nets[n] = indexedNet{walkpath: nodeWalkpath(n), net: cidr}
+	idx.nodes[n] = k
+}
+
+// remove drops node n from the index, the index must be built.
+func (idx *valueIndex) remove(n *node) {
+	k, ok := idx.nodes[n]
+	if !ok {
+		return
+	}
+	delete(idx.nodes, n)
+	delete(idx.nets[k], n)
+	if len(idx.nets[k]) == 0 {
+		delete(idx.nets, k)
+	}
+}
+
+// changed updates the index for the new value of the node (nil if the value is removed).
+func (idx *valueIndex) changed(tree *Tree, n *node, value interface{}) {
+	idx.Lock()
+	defer idx.Unlock()
+	if idx.nets == nil {
+		return
+	}
+	idx.remove(n)
+	if value != nil {
+		idx.add(n, value, walkpath2net(tree.walkOpt(OptWalkIPAuto), nodeWalkpath(n)))
+	}
+}
+
+// removing drops all nodes of the subtree of n from the index.
+func (idx *valueIndex) removing(tree *Tree, n *node) {
+	idx.Lock()
+	defer idx.Unlock()
+	if idx.nets == nil {
+		return
+	}
+	if n == tree.root {
+		idx.nets, idx.nodes = make(map[interface{}]map[*node]indexedNet), make(map[*node]interface{})
+		return
+	}
+	tree.walk(OptWalkIPAuto|optWalkExpired, func(cidr net.IPNet, n *node) (bool, error) {
+		idx.remove(n)
+		return true, nil
+	}, nodeWalkpath(n), n)
+}
+
+// reset drops the index, it is built again by the next lookup (after nodes of the tree are replaced wholesale).
+func (idx *valueIndex) reset() {
+	idx.Lock()
+	defer idx.Unlock()
+	idx.nets, idx.nodes = nil, nil
+}
+
+// moved replaces node src copied to dst (by Compact).
+func (idx *valueIndex) moved(src, dst *node) {
+	idx.Lock()
+	defer idx.Unlock()
+	if k, ok := idx.nodes[src]; ok {
+		in := idx.nets[k][src]
+		delete(idx.nets[k], src)
+		delete(idx.nodes, src)
+		idx.nets[k][dst] = in
+		idx.nodes[dst] = k
+	}
+}
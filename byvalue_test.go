@@ -0,0 +1,174 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type customer struct {
+	name string
+}
+
+func netsString(nets []net.IPNet) string {
+	var s []string
+	for _, n := range nets {
+		s = append(s, n.String())
+	}
+	return strings.Join(s, ",")
+}
+
+func TestFindByValue(t *testing.T) {
+	tr := NewTree()
+	tr.AddCIDR("10.0.0.0/8", "x")
+	tr.AddCIDR("10.1.0.0/16", "y")
+	tr.AddCIDR("192.168.0.0/24", "x")
+	tr.AddCIDR("2001:db8::/48", "x")
+
+	nets := tr.FindByValue(func(value interface{}) bool { return value == "x" })
+	if got := netsString(nets); got != "10.0.0.0/8,2001:db8::/48,192.168.0.0/24" {
+		t.Errorf("Wrong networks, got %s", got)
+	}
+	if got := netsString(tr.FindByIndex("y")); got != "10.1.0.0/16" {
+		t.Errorf("Wrong networks without index, got %s", got)
+	}
+}
+
+func TestFindByIndex(t *testing.T) {
+	tr := NewTree(WithValueIndex(func(value interface{}) interface{} {
+		return value.(*customer).name
+	}))
+	a, b := &customer{"a"}, &customer{"b"}
+	tr.AddCIDR("10.0.0.0/8", a)
+	tr.AddCIDR("10.1.0.0/16", b)
+	tr.AddCIDR("192.168.0.0/24", a)
+
+	if got := netsString(tr.FindByIndex("a")); got != "10.0.0.0/8,192.168.0.0/24" {
+		t.Errorf("Wrong networks, got %s", got)
+	}
+
+	// index follows changes of the tree
+	tr.DeleteCIDR("192.168.0.0/24")
+	tr.AddCIDR("172.16.0.0/12", a)
+	if got := netsString(tr.FindByIndex("a")); got != "10.0.0.0/8,172.16.0.0/12" {
+		t.Errorf("Wrong networks after change, got %s", got)
+	}
+	ref, err := tr.NodeRef("10.1.0.0/16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref.SetValue(a)
+	if got := netsString(tr.FindByIndex("a")); got != "10.0.0.0/8,10.1.0.0/16,172.16.0.0/12" {
+		t.Errorf("Wrong networks after SetValue, got %s", got)
+	}
+	if nets := tr.FindByIndex("b"); nets != nil {
+		t.Errorf("Expected no networks, got %v", nets)
+	}
+	if nets := tr.FindByIndex([]int{1}); nets != nil {
+		t.Errorf("Expected no networks for not comparable key, got %v", nets)
+	}
+}
+
+type route struct {
+	nexthop string
+	origin  uint32
+}
+
+func TestPrefixesFor(t *testing.T) {
+	tr := NewTree(WithValueIndex(func(value interface{}) interface{} {
+		return value.(route).origin
+	}))
+	tr.AddCIDR("8.8.8.0/24", route{"a", 15169})
+	tr.AddCIDR("8.8.4.0/24", route{"b", 15169})
+	tr.AddCIDR("1.1.1.0/24", route{"a", 13335})
+	tr.AddCIDR("2001:4860::/48", route{"a", 15169})
+
+	if got := netsString(tr.PrefixesFor(uint32(15169))); got != "8.8.4.0/24,8.8.8.0/24,2001:4860::/48" {
+		t.Errorf("Wrong prefixes, got %s", got)
+	}
+	tr.DeleteCIDR("8.8.4.0/24")
+	tr.SetCIDR("1.1.1.0/24", route{"b", 15169})
+	if got := netsString(tr.PrefixesFor(uint32(15169))); got != "1.1.1.0/24,8.8.8.0/24,2001:4860::/48" {
+		t.Errorf("Wrong prefixes after change, got %s", got)
+	}
+	if nets := tr.PrefixesFor(uint32(13335)); nets != nil {
+		t.Errorf("Expected no prefixes, got %v", nets)
+	}
+}
+
+func TestValueIndexUpdates(t *testing.T) {
+	tr := NewTree(WithValueIndex(nil))
+	tr.AddCIDR("10.0.0.0/8", "x")
+	tr.AddCIDR("10.1.0.0/16", "y")
+	if got := netsString(tr.FindByIndex("x")); got != "10.0.0.0/8" {
+		t.Errorf("Wrong networks, got %s", got)
+	}
+
+	// the built index is updated by writes, not rebuilt
+	built := reflect.ValueOf(tr.index.nodes).Pointer()
+	tr.AddCIDR("10.2.0.0/16", "x")
+	tr.SetCIDR("10.1.0.0/16", "x")
+	tr.AddCIDR("2001:db8::/48", "x")
+	tr.AddCIDR("172.16.0.0/12", "y")
+	tr.DeleteWholeRangeCIDR("172.16.0.0/12")
+	if reflect.ValueOf(tr.index.nodes).Pointer() != built {
+		t.Error("Expected the index to be updated in place")
+	}
+	if got := netsString(tr.FindByIndex("x")); got != "10.0.0.0/8,10.1.0.0/16,10.2.0.0/16,2001:db8::/48" {
+		t.Errorf("Wrong networks after changes, got %s", got)
+	}
+	if nets := tr.FindByIndex("y"); nets != nil {
+		t.Errorf("Expected no networks, got %v", nets)
+	}
+
+	tr.DeleteCIDR("10.1.0.0/16")
+	tr.Compact()
+	if got := netsString(tr.FindByIndex("x")); got != "10.0.0.0/8,10.2.0.0/16,2001:db8::/48" {
+		t.Errorf("Wrong networks after compact, got %s", got)
+	}
+	tr.DeleteCIDR("10.0.0.0/8")
+	if got := netsString(tr.FindByIndex("x")); got != "10.2.0.0/16,2001:db8::/48" {
+		t.Errorf("Wrong networks after compact and delete, got %s", got)
+	}
+	tr.Clear()
+	if nets := tr.FindByIndex("x"); nets != nil {
+		t.Errorf("Expected no networks after clear, got %v", nets)
+	}
+	tr.AddCIDR("10.3.0.0/16", "x")
+	if got := netsString(tr.FindByIndex("x")); got != "10.3.0.0/16" {
+		t.Errorf("Wrong networks after clear, got %s", got)
+	}
+}
+
+func TestValueIndexUnmarshalBinary(t *testing.T) {
+	src := NewTree()
+	src.AddCIDR("192.168.0.0/16", 2)
+	data, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := NewTree(WithValueIndex(nil))
+	tr.AddCIDR("10.0.0.0/8", 1)
+	if got := netsString(tr.FindByIndex(1)); got != "10.0.0.0/8" {
+		t.Errorf("Wrong networks, got %s", got)
+	}
+	if err = tr.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got := netsString(tr.FindByIndex(1)); got != "" {
+		t.Errorf("Wrong networks after load, expected none, got %s", got)
+	}
+	if got := netsString(tr.FindByIndex(2)); got != "192.168.0.0/16" {
+		t.Errorf("Wrong networks after load, expected 192.168.0.0/16, got %s", got)
+	}
+	tr.AddCIDR("10.0.0.0/8", 2)
+	if got := netsString(tr.FindByIndex(2)); got != "10.0.0.0/8,192.168.0.0/16" {
+		t.Errorf("Wrong networks after add, got %s", got)
+	}
+}
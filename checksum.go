@@ -0,0 +1,39 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// Checksum returns SHA-256 hash of the IP/mask to value mapping of the tree, equal for trees holding equal values
+// for the same IP/masks however they were built. Values are hashed by hashValue, by their type and default format
+// (fmt "%T %v") if it is nil.
+func (tree *Tree) Checksum(hashValue func(value interface{}) []byte) [sha256.Size]byte {
+	if hashValue == nil {
+		hashValue = func(value interface{}) []byte { return []byte(fmt.Sprintf("%T %v", value, value)) }
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	h := sha256.New()
+	var buf [binary.MaxVarintLen64]byte
+	tree.walkNodes(tree.walkOpt(OptWalkIPAuto), func(cidr net.IPNet, n *node) (bool, error) {
+		ones, _ := cidr.Mask.Size()
+		h.Write([]byte{byte(len(cidr.IP)), byte(ones)})
+		h.Write(cidr.IP)
+		v := hashValue(n.value)
+		h.Write(buf[:binary.PutUvarint(buf[:], uint64(len(v)))])
+		h.Write(v)
+		return true, nil
+	})
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
@@ -0,0 +1,44 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	a, b := NewTree(), NewTree()
+	a.AddCIDR("10.0.0.0/8", 1)
+	a.AddCIDR("2001:db8::/48", "x")
+	a.AddCIDR("192.168.0.0/16", 3)
+	a.AddCIDR("172.16.0.0/12", 4)
+	a.DeleteCIDR("172.16.0.0/12")
+
+	b.AddCIDR("192.168.0.0/16", 3)
+	b.AddCIDR("2001:db8::/48", "x")
+	b.AddCIDR("10.0.0.0/8", 1)
+	if a.Checksum(nil) != b.Checksum(nil) {
+		t.Error("Wrong checksum, expected equal checksums of equal trees")
+	}
+
+	b.SetCIDR("10.0.0.0/8", "1")
+	if a.Checksum(nil) == b.Checksum(nil) {
+		t.Error("Wrong checksum, expected different checksums of different value types")
+	}
+	byString := func(value interface{}) []byte { return []byte(fmt.Sprint(value)) }
+	if a.Checksum(byString) != b.Checksum(byString) {
+		t.Error("Wrong checksum, expected equal checksums of values hashed equal")
+	}
+	b.SetCIDR("10.0.0.0/8", 1)
+	b.DeleteCIDR("10.0.0.0/8")
+	b.AddCIDR("10.0.0.0/9", 1)
+	if a.Checksum(nil) == b.Checksum(nil) {
+		t.Error("Wrong checksum, expected different checksums of different prefixes")
+	}
+	if NewTree().Checksum(nil) == a.Checksum(nil) {
+		t.Error("Wrong checksum, expected different checksum of empty tree")
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+// CloneValueFunc is the type of function used to copy values when tree content is copied into another tree
+// (Clone, ExtractSubtree), use it to deep copy or reference count values of reference types (maps, slices, pointers).
+type CloneValueFunc func(value interface{}) interface{}
+
+// WithCloneValue sets function copying values whenever tree content is copied, values are shared by default.
+func WithCloneValue(fn CloneValueFunc) Option {
+	return func(tree *Tree) {
+		tree.cloneValue = fn
+	}
+}
+
+// Clone returns a copy of the tree created with the same options, values are copied with the CloneValueFunc if set.
+// Nodes are copied into one arena of exact size, so the copy has no free nodes.
+func (tree *Tree) Clone() *Tree {
+	return tree.CloneFunc(tree.cloneValue)
+}
+
+// CloneFunc is Clone copying values with fn instead of the CloneValueFunc of the tree, values are shared if fn is nil.
+// Trees of zones are cloned too.
+func (tree *Tree) CloneFunc(fn CloneValueFunc) *Tree {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	dst := tree.emptyCopy()
+	arena := arenaCopy(tree.root, tree.countNodes, func(dn, n *node) {
+		if n.value != nil {
+			dn.value = n.value
+			if fn != nil {
+				dn.value = fn(n.value)
+			}
+			dn.meta = copyMeta(n.meta)
+		}
+	})
+	dst.root = &arena[0]
+	dst.alloc = arena
+	dst.countNodes = len(arena)
+	dst.countValuedNodes = tree.countValuedNodes
+	dst.countAllocNodes = len(arena)
+	dst.countFreeNodes = 0
+	dst.defaultRoute = tree.defaultRoute
+	dst.hasExpiry, dst.clock = tree.hasExpiry, tree.clock
+	tree.eachZone(func(zone string, zt *Tree) {
+		dst.zones.trees[zone] = zt.CloneFunc(fn)
+	})
+	return dst
+}
+
+// ExtractSubtree returns a new tree (created with the same options) holding copies of all values under the cidr,
+// including value of the cidr itself. Values are copied with the CloneValueFunc if set.
+func (tree *Tree) ExtractSubtree(cidr string) (*Tree, error) {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	n, err := tree.nodeCIDRb([]byte(cidr))
+	if err != nil {
+		return nil, err
+	}
+	dst := tree.emptyCopy()
+	dst.hasExpiry, dst.clock = tree.hasExpiry, tree.clock
+	if n == nil {
+		return dst, nil
+	}
+
+	// rebuild path from root to the subtree without values
+	var path []bool
+	for p := n; p.parent != nil; p = p.parent {
+		path = append(path, p.parent.right == p)
+	}
+	dn := dst.root
+	for i := len(path) - 1; i >= 0; i-- {
+		next := dst.newnode()
+		dst.countNodes++
+		next.parent = dn
+		if path[i] {
+			dn.right = next
+		} else {
+			dn.left = next
+		}
+		dn = next
+	}
+	if n.value != nil {
+		dn.value = dst.copyValue(n.value)
+		dn.meta = copyMeta(n.meta)
+		dst.countValuedNodes++
+	}
+	dst.copyChildren(dn, n)
+	return dst, nil
+}
+
+// emptyCopy creates empty tree with the options of the tree (and extra ones), skipping preallocation.
+func (tree *Tree) emptyCopy(extra ...Option) *Tree {
+	opts := append(append(append([]Option(nil), tree.opts...), WithPreallocate(0), WithCapacityHint(0)), extra...)
+	return NewTree(opts...)
+}
+
+func (tree *Tree) copyValue(value interface{}) interface{} {
+	if value == nil || tree.cloneValue == nil {
+		return value
+	}
+	return tree.cloneValue(value)
+}
+
+// copyChildren copies subtrees below src node to below dst node, counting nodes and values into the tree.
+func (tree *Tree) copyChildren(dst, src *node) {
+	if src.left != nil {
+		dst.left = tree.copySubtree(dst, src.left)
+	}
+	if src.right != nil {
+		dst.right = tree.copySubtree(dst, src.right)
+	}
+}
+
+func (tree *Tree) copySubtree(parent, src *node) *node {
+	n := tree.newnode()
+	tree.countNodes++
+	n.parent = parent
+	if src.value != nil {
+		n.value = tree.copyValue(src.value)
+		n.meta = copyMeta(src.meta)
+		tree.countValuedNodes++
+	}
+	tree.copyChildren(n, src)
+	return n
+}
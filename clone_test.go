@@ -0,0 +1,149 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClone(t *testing.T) {
+	tr := NewTree()
+	tr.AddCIDR("0.0.0.0/0", []int{0})
+	tr.AddCIDR("10.0.0.0/8", []int{1})
+	tr.AddCIDR("10.1.0.0/16", []int{2})
+	tr.AddCIDR("dead::/16", []int{3})
+
+	cl := tr.Clone()
+	n1, v1, _, _ := tr.GetStats()
+	n2, v2, _, _ := cl.GetStats()
+	if n1 != n2 || v1 != v2 {
+		t.Errorf("Wrong clone stats, expected %d/%d, got %d/%d", n1, v1, n2, v2)
+	}
+	inf, err := cl.FindCIDR("10.1.2.3")
+	if err != nil {
+		t.Error(err)
+	} else if inf.([]int)[0] != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+
+	// values are shared without clone function
+	inf.([]int)[0] = 20
+	inf, _ = tr.FindCIDR("10.1.2.3")
+	if inf.([]int)[0] != 20 {
+		t.Errorf("Expected shared value, got %v", inf)
+	}
+
+	// changing clone does not affect the original
+	cl.DeleteCIDR("10.1.0.0/16")
+	if inf, _ = tr.FindCIDR("10.1.2.3"); inf.([]int)[0] != 20 {
+		t.Errorf("Original tree was changed by the clone, got %v", inf)
+	}
+}
+
+func TestCloneValue(t *testing.T) {
+	tr := NewTree(WithCloneValue(func(value interface{}) interface{} {
+		return append([]int(nil), value.([]int)...)
+	}))
+	tr.AddCIDR("10.0.0.0/8", []int{1})
+	tr.AddCIDR("10.1.0.0/16", []int{2})
+	tr.AddCIDR("11.0.0.0/8", []int{3})
+
+	cl := tr.Clone()
+	inf, _ := cl.FindCIDR("10.1.2.3")
+	inf.([]int)[0] = 20
+	if inf, _ = tr.FindCIDR("10.1.2.3"); inf.([]int)[0] != 2 {
+		t.Errorf("Expected deep copied value, original changed to %v", inf)
+	}
+
+	sub, err := tr.ExtractSubtree("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, values, _, _ := sub.GetStats()
+	if values != 2 {
+		t.Errorf("Wrong number of extracted values, expected 2, got %d", values)
+	}
+	inf, _ = sub.FindCIDR("10.1.2.3")
+	if inf == nil || inf.([]int)[0] != 2 {
+		t.Errorf("Wrong extracted value, expected 2, got %v", inf)
+	}
+	inf.([]int)[0] = 20
+	if inf, _ = tr.FindCIDR("10.1.2.3"); inf.([]int)[0] != 2 {
+		t.Errorf("Expected deep copied value, original changed to %v", inf)
+	}
+	if inf, _ = sub.FindCIDR("11.0.0.1"); inf != nil {
+		t.Errorf("Value outside extracted subtree found: %v", inf)
+	}
+
+	sub, err = tr.ExtractSubtree("12.0.0.0/8")
+	if err != nil {
+		t.Error(err)
+	}
+	if _, values, _, _ = sub.GetStats(); values != 0 {
+		t.Errorf("Expected empty tree, got %d values", values)
+	}
+}
+
+func TestCloneFunc(t *testing.T) {
+	tr := NewTree()
+	tr.AddCIDR("10.0.0.0/8", []int{1})
+	tr.AddCIDR("10.1.0.0/16", []int{2})
+	tr.AddCIDR("2001:db8::/48", []int{3})
+	tr.DeleteCIDR("10.1.0.0/16")
+	tr.AddCIDR("10.1.0.0/16", []int{2})
+
+	var copied int
+	cl := tr.CloneFunc(func(value interface{}) interface{} {
+		copied++
+		return append([]int(nil), value.([]int)...)
+	})
+	if copied != 3 {
+		t.Errorf("Wrong number of copied values, expected 3, got %d", copied)
+	}
+	nodes, values, alloc, free := cl.GetStats()
+	n1, v1, _, _ := tr.GetStats()
+	if nodes != n1 || values != v1 || alloc != nodes || free != 0 {
+		t.Errorf("Wrong clone stats, expected %d/%d/%d/0, got %d/%d/%d/%d", n1, v1, n1, nodes, values, alloc, free)
+	}
+	inf, _ := cl.FindCIDR("10.1.2.3")
+	inf.([]int)[0] = 20
+	if inf, _ = tr.FindCIDR("10.1.2.3"); inf.([]int)[0] != 2 {
+		t.Errorf("Expected deep copied value, original changed to %v", inf)
+	}
+
+	// clone keeps working as a regular tree
+	if err := cl.AddCIDR("10.2.0.0/16", []int{4}); err != nil {
+		t.Error(err)
+	}
+	if err := cl.DeleteCIDR("10.0.0.0/8"); err != nil {
+		t.Error(err)
+	}
+	if inf, _ = cl.FindCIDR("10.2.0.1"); inf.([]int)[0] != 4 {
+		t.Errorf("Wrong value, expected 4, got %v", inf)
+	}
+}
+
+func TestCloneTTL(t *testing.T) {
+	tr := NewTree()
+	clock := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr.clock = func() time.Time { return clock }
+	tr.AddCIDRWithTTL("10.0.0.0/8", 1, time.Minute)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	clock = clock.Add(time.Hour)
+
+	sub, err := tr.ExtractSubtree("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, c := range map[string]*Tree{"original": tr, "clone": tr.Clone(), "subtree": sub} {
+		if inf, _ := c.FindCIDR("10.2.0.1"); inf != nil {
+			t.Errorf("Expired value found in %s, got %v", name, inf)
+		}
+		if inf, _ := c.FindCIDR("10.1.0.1"); inf != 2 {
+			t.Errorf("Wrong value in %s, expected 2, got %v", name, inf)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+// Command nradix loads files of prefixes into nradix.Tree and queries them:
+//
+//	nradix lookup FILE [ADDR...]  longest match of each address (read from stdin, one per line, if none given)
+//	nradix stats FILE             statistics of the tree
+//	nradix aggregate FILE         minimal set of prefixes giving the same longest matches
+//	nradix diff OLD NEW           prefixes added (+), removed (-) and changed (~) in NEW relative to OLD
+//
+// Every line of FILE holds a CIDR optionally followed by whitespace and value, empty lines and lines starting
+// with '#' are skipped. Prefixes without value are stored with value true.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/gilwo/nradix"
+)
+
+const usage = `usage:
+	nradix lookup FILE [ADDR...]
+	nradix stats FILE
+	nradix aggregate FILE
+	nradix diff OLD NEW
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command of args and returns the exit status: 0 on success, 1 if some lookup failed
+// and 2 for bad usage or unreadable file.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) < 2 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	cmd, files := args[0], args[1:]
+	switch {
+	case cmd == "lookup":
+		files = files[:1]
+	case cmd == "diff" && len(files) == 2, (cmd == "stats" || cmd == "aggregate") && len(files) == 1:
+	default:
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	trees := make([]*nradix.Tree, len(files))
+	for i, name := range files {
+		tree, err := load(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "nradix: %v\n", err)
+			return 2
+		}
+		trees[i] = tree
+	}
+
+	w := bufio.NewWriter(stdout)
+	defer w.Flush()
+	switch cmd {
+	case "lookup":
+		return lookup(trees[0], args[2:], stdin, w, stderr)
+	case "stats":
+		stats(trees[0], w)
+	case "aggregate":
+		trees[0].AggregatedWalk(nradix.OptWalkIPAuto, nil, func(cidr net.IPNet, value interface{}) (bool, error) {
+			fmt.Fprintf(w, "%s\t%v\n", cidr.String(), value)
+			return true, nil
+		})
+	case "diff":
+		for _, d := range trees[0].Diff(trees[1]) {
+			switch d.Kind {
+			case nradix.DiffAdded:
+				fmt.Fprintf(w, "+ %s\t%v\n", d.Net.String(), d.New)
+			case nradix.DiffRemoved:
+				fmt.Fprintf(w, "- %s\t%v\n", d.Net.String(), d.Old)
+			case nradix.DiffChanged:
+				fmt.Fprintf(w, "~ %s\t%v -> %v\n", d.Net.String(), d.Old, d.New)
+			}
+		}
+	}
+	return 0
+}
+
+func load(name string) (*nradix.Tree, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tree := nradix.NewTree()
+	if err = tree.LoadFrom(f, nradix.FormatFields, nil); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return tree, nil
+}
+
+// lookup prints "ADDR PREFIX VALUE" for every address, "ADDR -" if nothing matches.
+func lookup(tree *nradix.Tree, addrs []string, stdin io.Reader, w io.Writer, stderr io.Writer) int {
+	status := 0
+	find := func(addr string) {
+		value, prefix, err := tree.FindCIDRNet(addr)
+		switch {
+		case err != nil:
+			fmt.Fprintf(stderr, "nradix: %v\n", err)
+			status = 1
+		case value == nil:
+			fmt.Fprintf(w, "%s\t-\n", addr)
+		default:
+			fmt.Fprintf(w, "%s\t%s\t%v\n", addr, prefix.String(), value)
+		}
+	}
+	if len(addrs) > 0 {
+		for _, addr := range addrs {
+			find(addr)
+		}
+		return status
+	}
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() {
+		if addr := strings.TrimSpace(scanner.Text()); addr != "" {
+			find(addr)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(stderr, "nradix: %v\n", err)
+		return 2
+	}
+	return status
+}
+
+func stats(tree *nradix.Tree, w io.Writer) {
+	st := tree.Stats()
+	fmt.Fprintf(w, "prefixes\t%d\n", st.ValuedNodes)
+	fmt.Fprintf(w, "nodes\t%d\n", st.Nodes)
+	fmt.Fprintf(w, "allocated\t%d\n", st.AllocNodes)
+	fmt.Fprintf(w, "free\t%d\n", st.FreeNodes)
+	fmt.Fprintf(w, "bytes\t%d\n", st.NodeBytes+st.MetaBytes)
+	fmt.Fprintf(w, "depth\t%d\n", st.MaxDepth)
+	for depth, count := range st.DepthHistogram {
+		if count > 0 {
+			fmt.Fprintf(w, "/%d\t%d\n", depth, count)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	oldFile := filepath.Join(dir, "old.txt")
+	newFile := filepath.Join(dir, "new.txt")
+	os.WriteFile(oldFile, []byte("# feed\n10.0.0.0/25 a\n10.0.0.128/25 a\n192.168.0.0/16 b\n"), 0o644)
+	os.WriteFile(newFile, []byte("10.0.0.0/25 a\n10.0.0.128/25 c\n172.16.0.0/12\n"), 0o644)
+
+	for _, tc := range []struct {
+		args     []string
+		stdin    string
+		status   int
+		expected string
+	}{
+		{[]string{"lookup", oldFile, "10.0.0.200", "8.8.8.8"}, "", 0, "10.0.0.200\t10.0.0.128/25\ta\n8.8.8.8\t-\n"},
+		{[]string{"lookup", oldFile}, "192.168.1.1\n\nbad\n", 1, "192.168.1.1\t192.168.0.0/16\tb\n"},
+		{[]string{"aggregate", oldFile}, "", 0, "10.0.0.0/24\ta\n192.168.0.0/16\tb\n"},
+		{[]string{"diff", oldFile, newFile}, "", 0, "~ 10.0.0.128/25\ta -> c\n+ 172.16.0.0/12\ttrue\n- 192.168.0.0/16\tb\n"},
+		{[]string{"diff", oldFile}, "", 2, ""},
+		{[]string{"stats", filepath.Join(dir, "missing.txt")}, "", 2, ""},
+	} {
+		var stdout, stderr bytes.Buffer
+		status := run(tc.args, strings.NewReader(tc.stdin), &stdout, &stderr)
+		if status != tc.status {
+			t.Errorf("Wrong status of %v, expected %d, got %d (%s)", tc.args, tc.status, status, stderr.String())
+		}
+		if stdout.String() != tc.expected {
+			t.Errorf("Wrong output of %v, expected %q, got %q", tc.args, tc.expected, stdout.String())
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{"stats", oldFile}, nil, &stdout, &stderr); status != 0 {
+		t.Errorf("Wrong status, expected 0, got %d", status)
+	}
+	if !strings.HasPrefix(stdout.String(), "prefixes\t3\n") || !strings.Contains(stdout.String(), "/25\t2\n") {
+		t.Errorf("Wrong stats, got %q", stdout.String())
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+// WithAutoShrink makes the tree compact itself (see Compact) after a delete once the number of free nodes exceeds
+// ratio times the number of nodes in use. Zero (the default) disables automatic compaction.
+func WithAutoShrink(ratio float64) Option {
+	return func(tree *Tree) {
+		tree.shrinkRatio = ratio
+	}
+}
+
+func (tree *Tree) autoShrink() {
+	if tree.shrinkRatio > 0 && float64(tree.countFreeNodes) > tree.shrinkRatio*float64(tree.countNodes) {
+		tree.compact()
+	}
+}
+
+// Compact moves all nodes in use into a new arena of exact size. Free nodes kept for reuse by deletes and the old
+// arena chunks are released, so their memory can be returned to the runtime after large deletions.
+// NodeRefs taken before Compact become stale. Tree created WithPool returns its free nodes to the pool instead.
+func (tree *Tree) Compact() {
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	if tree.guard != nil {
+		tree.guard.enterWrite("Compact")
+		defer tree.guard.exitWrite()
+	}
+	tree.compact()
+}
+
+// compact moves all nodes in use into a new arena of exact size, dropping the free list and old arena chunks.
+func (tree *Tree) compact() {
+	if tree.pool != nil {
+		tree.releaseFree()
+		return
+	}
+	tree.generation++
+	arena := arenaCopy(tree.root, tree.countNodes, func(dst, src *node) {
+		dst.value = src.value
+		dst.meta = src.meta
+		if tree.lru != nil {
+			tree.lru.moved(src, dst)
+		}
+		if tree.index != nil {
+			tree.index.moved(src, dst)
+		}
+	})
+	tree.root = &arena[0]
+	tree.alloc = arena
+	tree.free = nil
+	tree.countAllocNodes = len(arena)
+	tree.countFreeNodes = 0
+}
+
+// arenaCopy copies the subtree of root (having count nodes) into a new arena of exact size in preorder,
+// root becoming the first node of the arena. Links are set up by arenaCopy, the rest of the node by fill.
+func arenaCopy(root *node, count int, fill func(dst, src *node)) []node {
+	arena := make([]node, count)
+	var used int
+	var place func(parent, src *node) *node
+	place = func(parent, src *node) *node {
+		n := &arena[used]
+		used++
+		n.parent = parent
+		fill(n, src)
+		if src.left != nil {
+			n.left = place(n, src.left)
+		}
+		if src.right != nil {
+			n.right = place(n, src.right)
+		}
+		return n
+	}
+	place(nil, root)
+	return arena[:used]
+}
+
+// Clear removes all values of the tree without walking it (unless there are OnChange subscribers): the last chunk
+// of the node arena is reused for new nodes and the rest of the arena is released. Tree created
+// WithPool returns its nodes to the pool (see Release). Trees of zones are dropped. NodeRefs taken before Clear
+// become stale.
+func (tree *Tree) Clear() {
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	if tree.guard != nil {
+		tree.guard.enterWrite("Clear")
+		defer tree.guard.exitWrite()
+	}
+	if tree.zones != nil {
+		tree.zones.Lock()
+		tree.zones.trees = make(map[string]*Tree)
+		tree.zones.Unlock()
+	}
+	if tree.pool != nil {
+		tree.release()
+		return
+	}
+	tree.generation++
+	tree.removing(tree.root)
+	chunk := tree.alloc[:cap(tree.alloc)]
+	for i := range chunk {
+		chunk[i] = node{}
+	}
+	tree.alloc = chunk[:0]
+	tree.free = nil
+	tree.countAllocNodes, tree.countFreeNodes = len(chunk), 0
+	tree.countNodes, tree.countValuedNodes = 1, 0
+	tree.root = tree.newnode()
+	tree.hasExpiry = false
+	if tree.misses != nil {
+		tree.misses.invalidate(nil, 0)
+	}
+	if tree.lru != nil {
+		tree.lru.reset()
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestAutoShrink(t *testing.T) {
+	tr := NewTree(WithAutoShrink(1))
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("12.0.0.0/8", 3)
+
+	// 8 freed nodes against 12 in use: no compaction yet
+	tr.DeleteCIDR("10.1.0.0/16")
+	nodes, values, total, free := tr.GetStats()
+	if free != 8 {
+		t.Errorf("Wrong free nodes, expected 8, got %d", free)
+	}
+
+	// 11 freed nodes against 9 in use: compacted
+	tr.DeleteCIDR("10.0.0.0/8")
+	nodes, values, total, free = tr.GetStats()
+	if nodes != 9 || values != 1 || total != 9 || free != 0 {
+		t.Errorf("Wrong stats after compaction, got %d, %d, %d, %d", nodes, values, total, free)
+	}
+	inf, err := tr.FindCIDR("12.1.1.1")
+	if err != nil {
+		t.Error(err)
+	} else if inf.(int) != 3 {
+		t.Errorf("Wrong value, expected 3, got %v", inf)
+	}
+
+	// tree keeps working on the compacted arena
+	tr.AddCIDR("10.1.0.0/16", 4)
+	if inf, _ = tr.FindCIDR("10.1.1.1"); inf.(int) != 4 {
+		t.Errorf("Wrong value, expected 4, got %v", inf)
+	}
+	if nodes, values, total, _ = tr.GetStats(); nodes != 20 || values != 2 || total != 9+209 {
+		t.Errorf("Wrong stats after insert, got %d, %d, %d", nodes, values, total)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	tr := NewTree()
+	for i := 0; i < 100; i++ {
+		tr.AddCIDR(fmt.Sprintf("10.%d.0.0/16", i), i)
+	}
+	for i := 0; i < 100; i += 2 {
+		tr.DeleteCIDR(fmt.Sprintf("10.%d.0.0/16", i))
+	}
+	ref, _ := tr.NodeRef("10.1.0.0/16")
+	nodes, values, _, free := tr.GetStats()
+	if free == 0 {
+		t.Errorf("Expected free nodes before compaction")
+	}
+
+	tr.Compact()
+	n, v, total, free := tr.GetStats()
+	if n != nodes || v != values || total != nodes || free != 0 {
+		t.Errorf("Wrong stats after compaction, got %d, %d, %d, %d", n, v, total, free)
+	}
+	if ref.Valid() {
+		t.Error("Expected stale node reference after compaction")
+	}
+	for i := 0; i < 100; i++ {
+		inf, err := tr.FindCIDR(fmt.Sprintf("10.%d.1.1", i))
+		if err != nil {
+			t.Error(err)
+		}
+		if i%2 == 0 && inf != nil || i%2 == 1 && inf != i {
+			t.Errorf("Wrong value for 10.%d.1.1, got %v", i, inf)
+		}
+	}
+}
+
+func TestClear(t *testing.T) {
+	tr := NewTree(WithMissCache(16))
+	FillRandom(tr, 1000)
+	tr.AddCIDR("0.0.0.0/0", "default")
+	tr.FindCIDR("1.2.3.4")
+	_, _, allocated, _ := tr.GetStats()
+
+	tr.Clear()
+	nodes, valued, total, free := tr.GetStats()
+	if nodes != 1 || valued != 0 || free != 0 || total >= allocated {
+		t.Errorf("Wrong stats after Clear, got %d %d %d %d", nodes, valued, total, free)
+	}
+	if inf, _ := tr.FindCIDR("1.2.3.4"); inf != nil {
+		t.Errorf("Wrong value after Clear, expected nil, got %v", inf)
+	}
+	if err := tr.WalkTree(OptWalkIPAuto, func(cidr net.IPNet, value interface{}) (bool, error) {
+		t.Errorf("Unexpected %s after Clear", cidr.String())
+		return true, nil
+	}); err != nil {
+		t.Error(err)
+	}
+
+	// the tree is reused for the next load
+	prefixes := FillRandom(tr, 1000)
+	for i, cidr := range prefixes {
+		if inf, err := tr.FindExactCIDR(cidr); err != nil || inf != i {
+			t.Errorf("Wrong value for %s, expected %d, got %v (%v)", cidr, i, inf, err)
+			break
+		}
+	}
+	if _, valued, _, _ := tr.GetStats(); valued != 1000 {
+		t.Errorf("Wrong number of values, expected 1000, got %d", valued)
+	}
+}
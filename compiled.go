@@ -0,0 +1,147 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+)
+
+// strideBits is number of key bits resolved by one level of the compiled table.
+const strideBits = 8
+
+// Compiled is a read-only multibit (8 bit stride) table compiled from the tree for fast address lookups: IPv4
+// address takes at most 4 steps and IPv6 address at most 16, instead of one step per bit. Prefixes are expanded
+// into all table entries they cover (controlled prefix expansion). The table is a snapshot: once the tree changes
+// the table becomes stale and its lookups go to the tree until it is compiled again.
+type Compiled struct {
+	tree       *Tree
+	generation uint64
+	version    uint64
+	root       *strideTable
+	rootValue  interface{}
+	fallback   bool
+}
+
+type strideTable [1 << strideBits]strideEntry
+
+type strideEntry struct {
+	value interface{}
+	bits  int // prefix length of the value, longer prefixes expanded into the same entry win
+	child *strideTable
+}
+
+// Compile builds Compiled table of the tree. Trees with expiring values (see AddCIDRWithTTL) are not compiled,
+// lookups of such Compiled always go to the tree. Lookups of Compiled are not counted by WithHitCounting.
+func (tree *Tree) Compile() *Compiled {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	c := &Compiled{tree: tree, generation: tree.generation, version: tree.version}
+	if tree.hasExpiry {
+		c.fallback = true
+		return c
+	}
+	c.root = new(strideTable)
+	c.rootValue = tree.root.value
+	if c.rootValue == nil {
+		c.rootValue = tree.defaultRoute
+	}
+	var key [net.IPv6len]byte
+	c.add(tree.root.left, key, 1)
+	setKeyBit(&key, 0, true)
+	c.add(tree.root.right, key, 1)
+	return c
+}
+
+// add expands values of the subtree of node n (with the key of bits length) into the table, shorter prefixes first.
+func (c *Compiled) add(n *node, key [net.IPv6len]byte, bits int) {
+	if n == nil {
+		return
+	}
+	if n.value != nil {
+		c.expand(key, bits, n.value)
+	}
+	if bits == net.IPv6len*8 {
+		return
+	}
+	c.add(n.left, key, bits+1)
+	setKeyBit(&key, bits, true)
+	c.add(n.right, key, bits+1)
+}
+
+func (c *Compiled) expand(key [net.IPv6len]byte, bits int, value interface{}) {
+	level := (bits - 1) / strideBits
+	t := c.root
+	for i := 0; i < level; i++ {
+		e := &t[key[i]]
+		if e.child == nil {
+			e.child = new(strideTable)
+		}
+		t = e.child
+	}
+	free := (level+1)*strideBits - bits
+	first := int(key[level])
+	for i := first; i < first+1<<free; i++ {
+		if t[i].bits <= bits {
+			t[i].value, t[i].bits = value, bits
+		}
+	}
+}
+
+// Stale tells whether the tree changed since the table was compiled.
+func (c *Compiled) Stale() bool {
+	tree := c.tree
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	return c.stale()
+}
+
+func (c *Compiled) stale() bool {
+	return c.generation != c.tree.generation || c.version != c.tree.version
+}
+
+// FindIP returns value of the longest prefix covering ip, nil if there is none.
+func (c *Compiled) FindIP(ip net.IP) interface{} {
+	e, err := ip2entry(ip)
+	if err != nil {
+		return nil
+	}
+	return c.find(&e)
+}
+
+// FindCIDR returns value of the longest prefix covering IP of the cidr, like FindCIDR of the tree. Cidr with mask
+// shorter than the whole address is looked up in the tree.
+func (c *Compiled) FindCIDR(cidr string) (interface{}, error) {
+	e, err := c.tree.parseEntry([]byte(cidr))
+	if err != nil {
+		return nil, err
+	}
+	return c.find(&e), nil
+}
+
+func (c *Compiled) find(e *prefixEntry) interface{} {
+	tree := c.tree
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	key, bits := tree.entryKey(e)
+	if c.fallback || c.stale() || !(e.v4 && bits == 32 || bits == net.IPv6len*8) {
+		return tree.findEntry(e, findBest)
+	}
+	ret := c.rootValue
+	t := c.root
+	for i := 0; t != nil && i < bits/strideBits; i++ {
+		en := &t[key[i]]
+		if en.value != nil {
+			ret = en.value
+		}
+		t = en.child
+	}
+	return ret
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestCompiled(t *testing.T) {
+	tr := NewTree()
+	cidrs := []string{
+		"0.0.0.0/0",
+		"10.0.0.0/8",
+		"10.1.0.0/16",
+		"10.1.2.0/23",
+		"10.1.2.128/25",
+		"10.1.2.129/32",
+		"192.168.0.0/13",
+		"2001:db8::/33",
+		"2001:db8::/64",
+		"2001:db8::1/128",
+	}
+	for i, cidr := range cidrs {
+		if err := tr.AddCIDR(cidr, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := tr.Compile()
+	if c.Stale() {
+		t.Error("Expected fresh compiled table")
+	}
+	rnd := rand.New(rand.NewSource(1))
+	ips := []string{"10.1.2.129", "10.1.2.130", "10.1.3.1", "10.2.0.1", "192.169.1.1", "2001:db8::1", "2001:db8::2", "2001:db8:1::1", "::1"}
+	for i := 0; i < 1000; i++ {
+		ips = append(ips, fmt.Sprintf("10.1.%d.%d", rnd.Intn(4), rnd.Intn(256)), fmt.Sprintf("2001:db8::%x", rnd.Intn(4)))
+	}
+	for _, ip := range ips {
+		expected, _ := tr.FindCIDR(ip)
+		inf, err := c.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != expected {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, expected, inf)
+		}
+		if inf = c.FindIP(net.ParseIP(ip)); inf != expected {
+			t.Errorf("Wrong value for IP %s, expected %v, got %v", ip, expected, inf)
+		}
+	}
+	if inf, _ := c.FindCIDR("10.1.2.0/24"); inf != 3 {
+		t.Errorf("Wrong value for prefix lookup, expected 3, got %v", inf)
+	}
+
+	// stale table falls back to the tree
+	tr.AddCIDR("10.1.3.0/24", 100)
+	if !c.Stale() {
+		t.Error("Expected stale compiled table")
+	}
+	if inf, _ := c.FindCIDR("10.1.3.1"); inf != 100 {
+		t.Errorf("Wrong value from stale table, expected 100, got %v", inf)
+	}
+	ref, _ := tr.NodeRef("10.1.3.0/24")
+	c = tr.Compile()
+	ref.SetValue(200)
+	if inf, _ := c.FindCIDR("10.1.3.1"); inf != 200 {
+		t.Errorf("Wrong value after SetValue, expected 200, got %v", inf)
+	}
+
+	// expiring values are not compiled
+	tr.AddCIDRWithTTL("11.0.0.0/8", 300, time.Hour)
+	c = tr.Compile()
+	if inf, _ := c.FindCIDR("11.0.0.1"); inf != 300 {
+		t.Errorf("Wrong value, expected 300, got %v", inf)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+// Package nradixgrpc implements the Radix service of nradix.proto over nradix.Tree. Service does the work of every
+// call on messages mirroring those of the proto, so it is independent of gRPC: the server generated by protoc
+// (protoc-gen-go and protoc-gen-go-grpc, not vendored here) delegates its calls to Service, copying the fields,
+// and maps errors with ErrorCode.
+package nradixgrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/gilwo/nradix"
+)
+
+// Messages of the Radix service, see nradix.proto.
+type (
+	LookupRequest struct {
+		IP string
+	}
+	LookupResponse struct {
+		Found  bool
+		Prefix string
+		Value  []byte
+	}
+	InsertRequest struct {
+		Prefix  string
+		Value   []byte
+		Replace bool
+	}
+	DeleteRequest struct {
+		Prefix string
+	}
+	WalkRequest struct {
+		Prefix string
+	}
+	Entry struct {
+		Prefix string
+		Value  []byte
+	}
+	BulkLoadResponse struct {
+		Count int64
+	}
+)
+
+// Service implements calls of the Radix service on the tree, values are stored as []byte. The tree should be
+// created with locking (nradix.WithLocking) as calls are served concurrently.
+type Service struct {
+	tree *nradix.Tree
+}
+
+// NewService creates Service of the tree.
+func NewService(tree *nradix.Tree) *Service {
+	return &Service{tree: tree}
+}
+
+// Lookup returns the longest match of the address, the default route of the tree (nradix.WithDefaultRoute) is
+// returned as the /0 prefix of the address family.
+func (s *Service) Lookup(ctx context.Context, req *LookupRequest) (*LookupResponse, error) {
+	value, prefix, err := s.tree.FindCIDRNet(req.IP)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		value = s.tree.DefaultRoute()
+		prefix = zeroPrefix(req.IP)
+	}
+	if value == nil {
+		return &LookupResponse{}, nil
+	}
+	return &LookupResponse{Found: true, Prefix: prefix.String(), Value: valueBytes(value)}, nil
+}
+
+// Insert adds (or, if Replace, sets) value of the IP/mask.
+func (s *Service) Insert(ctx context.Context, req *InsertRequest) error {
+	if req.Replace {
+		return s.tree.SetCIDR(req.Prefix, req.Value)
+	}
+	return s.tree.AddCIDR(req.Prefix, req.Value)
+}
+
+// Delete removes value of the IP/mask.
+func (s *Service) Delete(ctx context.Context, req *DeleteRequest) error {
+	return s.tree.DeleteCIDR(req.Prefix)
+}
+
+// StreamWalk sends all IP/masks with values inside the prefix (all of the tree if empty) in walk order, it stops
+// at the first error of send or when ctx is done.
+func (s *Service) StreamWalk(ctx context.Context, req *WalkRequest, send func(*Entry) error) error {
+	fn := func(cidr net.IPNet, value interface{}) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		return true, send(&Entry{Prefix: cidr.String(), Value: valueBytes(value)})
+	}
+	if req.Prefix == "" {
+		return s.tree.WalkTree(nradix.OptWalkIPAuto, fn)
+	}
+	return s.tree.WalkSubtree(req.Prefix, fn)
+}
+
+// BulkLoad adds values of all entries received until recv returns io.EOF, all of them are added by one BulkAdd.
+func (s *Service) BulkLoad(ctx context.Context, recv func() (*Entry, error)) (*BulkLoadResponse, error) {
+	var entries []nradix.PrefixValue
+	for {
+		e, err := recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, nradix.PrefixValue{CIDR: e.Prefix, Value: e.Value})
+	}
+	if err := s.tree.BulkAdd(entries); err != nil {
+		return nil, err
+	}
+	return &BulkLoadResponse{Count: int64(len(entries))}, nil
+}
+
+// Code is gRPC status code (numbered as google.golang.org/grpc/codes).
+type Code uint32
+
+const (
+	CodeOK              Code = 0
+	CodeInvalidArgument Code = 3
+	CodeNotFound        Code = 5
+	CodeAlreadyExists   Code = 6
+	CodeInternal        Code = 13
+)
+
+// ErrorCode returns gRPC status code of error of Service.
+func ErrorCode(err error) Code {
+	switch {
+	case err == nil:
+		return CodeOK
+	case errors.Is(err, nradix.ErrBadIP):
+		return CodeInvalidArgument
+	case err == nradix.ErrNotFound:
+		return CodeNotFound
+	case err == nradix.ErrNodeBusy:
+		return CodeAlreadyExists
+	}
+	return CodeInternal
+}
+
+// zeroPrefix returns 0.0.0.0/0 for IPv4 (or IPv4-mapped) address, ::/0 otherwise.
+func zeroPrefix(ip string) net.IPNet {
+	if i := strings.IndexByte(ip, '/'); i >= 0 {
+		ip = ip[:i]
+	}
+	if a, err := netip.ParseAddr(ip); err == nil && a.Unmap().Is4() {
+		return net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 8*net.IPv4len)}
+	}
+	return net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 8*net.IPv6len)}
+}
+
+func valueBytes(value interface{}) []byte {
+	if b, ok := value.([]byte); ok {
+		return b
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradixgrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/gilwo/nradix"
+)
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(nradix.NewTree(nradix.WithLocking(true)))
+
+	loaded := []*Entry{
+		{Prefix: "10.0.0.0/8", Value: []byte("a")},
+		{Prefix: "10.1.0.0/16", Value: []byte("b")},
+		{Prefix: "2001:db8::/48", Value: []byte("c")},
+	}
+	i := 0
+	resp, err := s.BulkLoad(ctx, func() (*Entry, error) {
+		if i == len(loaded) {
+			return nil, io.EOF
+		}
+		i++
+		return loaded[i-1], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Count != 3 {
+		t.Errorf("Wrong count, expected 3, got %v", resp.Count)
+	}
+
+	if err = s.Insert(ctx, &InsertRequest{Prefix: "10.1.2.0/24", Value: []byte("d")}); err != nil {
+		t.Error(err)
+	}
+	err = s.Insert(ctx, &InsertRequest{Prefix: "10.1.2.0/24", Value: []byte("e")})
+	if ErrorCode(err) != CodeAlreadyExists {
+		t.Errorf("Wrong code, expected %v, got %v", CodeAlreadyExists, ErrorCode(err))
+	}
+	if err = s.Insert(ctx, &InsertRequest{Prefix: "10.1.2.0/24", Value: []byte("e"), Replace: true}); err != nil {
+		t.Error(err)
+	}
+
+	lr, err := s.Lookup(ctx, &LookupRequest{IP: "10.1.2.3"})
+	if err != nil {
+		t.Error(err)
+	}
+	if !lr.Found || lr.Prefix != "10.1.2.0/24" || string(lr.Value) != "e" {
+		t.Errorf("Wrong lookup, expected 10.1.2.0/24 e, got %+v", lr)
+	}
+	if lr, err = s.Lookup(ctx, &LookupRequest{IP: "11.0.0.1"}); err != nil || lr.Found {
+		t.Errorf("Wrong lookup, expected not found, got %+v %v", lr, err)
+	}
+	_, err = s.Lookup(ctx, &LookupRequest{IP: "bad"})
+	if ErrorCode(err) != CodeInvalidArgument {
+		t.Errorf("Wrong code, expected %v, got %v", CodeInvalidArgument, ErrorCode(err))
+	}
+
+	if err = s.Delete(ctx, &DeleteRequest{Prefix: "10.1.2.0/24"}); err != nil {
+		t.Error(err)
+	}
+	err = s.Delete(ctx, &DeleteRequest{Prefix: "10.1.2.0/24"})
+	if ErrorCode(err) != CodeNotFound {
+		t.Errorf("Wrong code, expected %v, got %v", CodeNotFound, ErrorCode(err))
+	}
+
+	var walked []string
+	err = s.StreamWalk(ctx, &WalkRequest{Prefix: "10.0.0.0/8"}, func(e *Entry) error {
+		walked = append(walked, e.Prefix+"="+string(e.Value))
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(walked) != 2 || walked[0] != "10.0.0.0/8=a" || walked[1] != "10.1.0.0/16=b" {
+		t.Errorf("Wrong walk, expected [10.0.0.0/8=a 10.1.0.0/16=b], got %v", walked)
+	}
+
+	stop := errors.New("stop")
+	n := 0
+	err = s.StreamWalk(ctx, &WalkRequest{}, func(e *Entry) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Errorf("Wrong walk, expected one entry and %v, got %v and %v", stop, n, err)
+	}
+}
+
+func TestServiceDefaultRoute(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(nradix.NewTree(nradix.WithLocking(true), nradix.WithDefaultRoute([]byte("default"))))
+	s.Insert(ctx, &InsertRequest{Prefix: "10.0.0.0/8", Value: []byte("ten")})
+	for ip, expected := range map[string]string{
+		"10.1.1.1":        "10.0.0.0/8 ten",
+		"11.1.1.1":        "0.0.0.0/0 default",
+		"::ffff:11.1.1.1": "0.0.0.0/0 default",
+		"2001:db8::1":     "::/0 default",
+	} {
+		lr, err := s.Lookup(ctx, &LookupRequest{IP: ip})
+		if err != nil || !lr.Found || lr.Prefix+" "+string(lr.Value) != expected {
+			t.Errorf("Wrong lookup of %s, expected %s, got %+v %v", ip, expected, lr, err)
+		}
+	}
+}
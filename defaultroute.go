@@ -0,0 +1,45 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+// WithDefaultRoute sets value returned by lookups matching nothing, see SetDefaultRoute.
+func WithDefaultRoute(val interface{}) Option {
+	return func(tree *Tree) {
+		tree.setDefaultRoute(val)
+	}
+}
+
+// SetDefaultRoute sets value returned by longest match lookups (FindCIDR, Find32, Find128, FindAddr, FindPrefix
+// and Compiled) which match no IP/mask, nil removes it. The default is not stored for any IP/mask, so it is not
+// walked, exact lookups and lookups returning IP/masks don't see it.
+func (tree *Tree) SetDefaultRoute(val interface{}) {
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	tree.version++
+	tree.setDefaultRoute(val)
+}
+
+func (tree *Tree) setDefaultRoute(val interface{}) {
+	tree.defaultRoute = val
+}
+
+// DefaultRoute returns value set by SetDefaultRoute.
+func (tree *Tree) DefaultRoute() interface{} {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	return tree.defaultRoute
+}
+
+// notFound returns result of the lookup finding no value, the default route for longest match.
+func (tree *Tree) notFound(what findWhat) interface{} {
+	if what == findBest {
+		return tree.defaultRoute
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"testing"
+)
+
+func TestDefaultRoute(t *testing.T) {
+	tr := NewTree(WithDefaultRoute("upstream"), WithMissCache(16))
+	if err := tr.AddCIDR("10.0.0.0/8", "lan"); err != nil {
+		t.Error(err)
+	}
+	for i := 0; i < 2; i++ {
+		for cidr, expected := range map[string]interface{}{
+			"10.1.1.1":    "lan",
+			"8.8.8.8":     "upstream",
+			"2001:db8::1": "upstream",
+		} {
+			inf, err := tr.FindCIDR(cidr)
+			if err != nil {
+				t.Error(err)
+			}
+			if inf != expected {
+				t.Errorf("Wrong value for %s, expected %v, got %v", cidr, expected, inf)
+			}
+		}
+	}
+	if inf := tr.Find32(0x08080808); inf != "upstream" {
+		t.Errorf("Wrong value, expected upstream, got %v", inf)
+	}
+	if inf, err := tr.FindExactCIDR("0.0.0.0/0"); err != ErrNotFound {
+		t.Errorf("Wrong exact value, expected ErrNotFound, got %v, %v", inf, err)
+	}
+	if inf, _ := tr.FindAllCIDR("8.8.8.8"); len(inf) != 0 {
+		t.Errorf("Wrong values, expected none, got %v", inf)
+	}
+	tr.WalkTree(OptWalkIPAuto, func(cidr net.IPNet, value interface{}) (bool, error) {
+		if value == "upstream" {
+			t.Errorf("Default route walked as %v", cidr)
+		}
+		return true, nil
+	})
+
+	if inf, _ := tr.Clone().FindCIDR("8.8.8.8"); inf != "upstream" {
+		t.Errorf("Wrong value of clone, expected upstream, got %v", inf)
+	}
+
+	c := tr.Compile()
+	if inf := c.FindIP(net.ParseIP("9.9.9.9")); inf != "upstream" {
+		t.Errorf("Wrong compiled value, expected upstream, got %v", inf)
+	}
+	tr.SetDefaultRoute(nil)
+	if !c.Stale() {
+		t.Errorf("Compiled table is not stale after SetDefaultRoute")
+	}
+	if inf, _ := tr.FindCIDR("8.8.8.8"); inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+	if tr.DefaultRoute() != nil {
+		t.Errorf("Wrong default route, expected nil, got %v", tr.DefaultRoute())
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+)
+
+// DiffKind tells how IP/mask differs between two trees.
+type DiffKind int
+
+const (
+	DiffAdded   DiffKind = iota // IP/mask has value only in the other tree
+	DiffRemoved                 // IP/mask has value only in the tree
+	DiffChanged                 // IP/mask has different values in the trees
+)
+
+// DiffEntry is a difference of IP/mask between the tree (Old value) and the other tree (New value).
+type DiffEntry struct {
+	Kind     DiffKind
+	Net      net.IPNet
+	Old, New interface{}
+}
+
+// Diff returns IP/masks added, removed or changed in other tree relative to the tree, in walk order.
+// Values are compared by ==, values of not comparable types are always reported as changed, see DiffFunc.
+func (tree *Tree) Diff(other *Tree) []DiffEntry {
+	return tree.DiffFunc(other, valuesEqual)
+}
+
+// DiffFunc is Diff comparing values by equal. If only one of the trees is WithIPv4Mapped, IP/masks of other tree
+// are converted to the layout of the tree first.
+func (tree *Tree) DiffFunc(other *Tree, equal func(a, b interface{}) bool) []DiffEntry {
+	if other == tree {
+		return nil
+	}
+	defer tree.lockPair(other, false)()
+	if other.mapped4 != tree.mapped4 {
+		other = other.withLayoutOf(tree)
+	}
+	d := differ{old: tree, new: other, equal: equal, oldNow: tree.expiryNow(), newNow: other.expiryNow()}
+	d.diff(make([]byte, 0, 128), tree.root, other.root)
+	return d.ret
+}
+
+// withLayoutOf returns a plain copy of values of the tree (not expired ones) keyed the way the dst tree keys them.
+func (tree *Tree) withLayoutOf(dst *Tree) *Tree {
+	var opts []Option
+	if dst.mapped4 {
+		opts = append(opts, WithIPv4Mapped())
+	}
+	conv := NewTree(opts...)
+	tree.walkNodes(OptWalkIPAuto, func(cidr net.IPNet, n *node) (bool, error) {
+		e, err := net2entry(cidr)
+		if err != nil {
+			return true, nil
+		}
+		e.value = n.value
+		return true, conv.insertEntry(&e, true)
+	})
+	return conv
+}
+
+type differ struct {
+	old, new       *Tree
+	equal          func(a, b interface{}) bool
+	oldNow, newNow int64
+	ret            []DiffEntry
+}
+
+// diff compares subtrees of the same path in both trees, the parts present in one tree only are walked whole.
+func (d *differ) diff(walkpath []byte, a, b *node) {
+	switch {
+	case a == nil && b == nil:
+		return
+	case a == nil:
+		d.new.walk(OptWalkIPAuto, func(cidr net.IPNet, n *node) (bool, error) {
+			d.ret = append(d.ret, DiffEntry{Kind: DiffAdded, Net: cidr, New: n.value})
+			return true, nil
+		}, walkpath, b)
+		return
+	case b == nil:
+		d.old.walk(OptWalkIPAuto, func(cidr net.IPNet, n *node) (bool, error) {
+			d.ret = append(d.ret, DiffEntry{Kind: DiffRemoved, Net: cidr, Old: n.value})
+			return true, nil
+		}, walkpath, a)
+		return
+	}
+	av, bv := a.value, b.value
+	if av != nil && expired(a, d.oldNow) {
+		av = nil
+	}
+	if bv != nil && expired(b, d.newNow) {
+		bv = nil
+	}
+	switch {
+	case av == nil && bv != nil:
+		d.ret = append(d.ret, DiffEntry{Kind: DiffAdded, Net: walkpath2net(d.old.walkOpt(OptWalkIPAuto), walkpath), New: bv})
+	case av != nil && bv == nil:
+		d.ret = append(d.ret, DiffEntry{Kind: DiffRemoved, Net: walkpath2net(d.old.walkOpt(OptWalkIPAuto), walkpath), Old: av})
+	case av != nil && bv != nil && !d.equal(av, bv):
+		d.ret = append(d.ret, DiffEntry{Kind: DiffChanged, Net: walkpath2net(d.old.walkOpt(OptWalkIPAuto), walkpath), Old: av, New: bv})
+	}
+	d.diff(append(walkpath, byte(0)), a.left, b.left)
+	d.diff(append(walkpath, byte(1)), a.right, b.right)
+}
@@ -0,0 +1,92 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := NewTree()
+	a.AddCIDR("10.0.0.0/8", 1)
+	a.AddCIDR("10.1.0.0/16", 2)
+	a.AddCIDR("192.168.0.0/24", 3)
+	a.AddCIDR("2001:db8::/48", 4)
+
+	b := NewTree()
+	b.AddCIDR("10.0.0.0/8", 1)
+	b.AddCIDR("10.1.0.0/16", 5)
+	b.AddCIDR("10.1.1.0/24", 6)
+	b.AddCIDR("2001:db8:1::/48", 7)
+
+	expected := []struct {
+		kind     DiffKind
+		cidr     string
+		old, new interface{}
+	}{
+		{DiffChanged, "10.1.0.0/16", 2, 5},
+		{DiffAdded, "10.1.1.0/24", nil, 6},
+		{DiffRemoved, "2001:db8::/48", 4, nil},
+		{DiffAdded, "2001:db8:1::/48", nil, 7},
+		{DiffRemoved, "192.168.0.0/24", 3, nil},
+	}
+	diff := a.Diff(b)
+	if len(diff) != len(expected) {
+		t.Fatalf("Wrong diff, expected %d entries, got %v", len(expected), diff)
+	}
+	for i, e := range expected {
+		d := diff[i]
+		if d.Kind != e.kind || d.Net.String() != e.cidr || d.Old != e.old || d.New != e.new {
+			t.Errorf("Wrong diff entry %d, expected %v, got %+v", i, e, d)
+		}
+	}
+
+	if diff := a.Diff(a); diff != nil {
+		t.Errorf("Expected no diff with itself, got %v", diff)
+	}
+	if diff := b.DiffFunc(a, func(x, y interface{}) bool { return true }); len(diff) != 4 {
+		t.Errorf("Wrong diff, expected 4 entries without changes, got %v", diff)
+	}
+}
+
+func TestDiffMapped(t *testing.T) {
+	a := NewTree(WithIPv4Mapped())
+	a.AddCIDR("10.0.0.0/8", 1)
+	a.AddCIDR("192.168.0.0/24", 2)
+	a.AddCIDR("2001:db8::/48", 3)
+
+	b := NewTree()
+	b.AddCIDR("10.0.0.0/8", 1)
+	b.AddCIDR("192.168.0.0/24", 5)
+	b.AddCIDR("2001:db8::/48", 3)
+	b.AddCIDR("172.16.0.0/12", 6)
+
+	for _, tc := range []struct {
+		old, new *Tree
+		expected string
+	}{
+		{a, b, "172.16.0.0/12 <nil> 6,192.168.0.0/24 2 5"},
+		{b, a, "172.16.0.0/12 6 <nil>,192.168.0.0/24 5 2"},
+	} {
+		nets := map[string]int{}
+		tc.old.WalkTree(OptWalkIPAuto, func(cidr net.IPNet, value interface{}) (bool, error) {
+			nets[cidr.String()] = len(cidr.IP)
+			return true, nil
+		})
+		var got []string
+		for _, d := range tc.old.Diff(tc.new) {
+			got = append(got, fmt.Sprintf("%s %v %v", d.Net.String(), d.Old, d.New))
+			if l, ok := nets[d.Net.String()]; ok && l != len(d.Net.IP) {
+				t.Errorf("Wrong IP length of %s, expected %d as walked, got %d", d.Net.String(), l, len(d.Net.IP))
+			}
+		}
+		if strings.Join(got, ",") != tc.expected {
+			t.Errorf("Wrong diff, expected %s, got %v", tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// DumpDOT writes nodes of the tree down to maxDepth levels (all of them if maxDepth is negative) as Graphviz
+// DOT graph: nodes holding value are boxes labeled with IP/mask and value, edges are labeled with the key bit.
+// Subtrees cut off by maxDepth are drawn as triangles labeled with their number of nodes.
+func (tree *Tree) DumpDOT(w io.Writer, maxDepth int) error {
+	return tree.dumpDOT(w, [16]byte{}, 0, maxDepth)
+}
+
+// DumpSubtreeDOT is DumpDOT of the part of the tree under the cidr, maxDepth counts levels below the cidr.
+func (tree *Tree) DumpSubtreeDOT(w io.Writer, cidr string, maxDepth int) error {
+	key, bits, err := tree.cidrKey(cidr)
+	if err != nil {
+		return err
+	}
+	return tree.dumpDOT(w, key, bits, maxDepth)
+}
+
+func (tree *Tree) dumpDOT(w io.Writer, key [16]byte, bits int, maxDepth int) error {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	walkpath := make([]byte, 0, 128)
+	n := tree.root
+	for i := 0; i < bits && n != nil; i++ {
+		if keyBit(key, i) {
+			n = n.right
+			walkpath = append(walkpath, byte(1))
+		} else {
+			n = n.left
+			walkpath = append(walkpath, byte(0))
+		}
+	}
+	d := dotWriter{tree: tree, w: bufio.NewWriter(w), maxDepth: maxDepth}
+	fmt.Fprintf(d.w, "digraph nradix {\n\tnode [shape=ellipse, fontsize=10];\n")
+	if n != nil {
+		d.node(n, walkpath, 0)
+	}
+	fmt.Fprintf(d.w, "}\n")
+	return d.w.Flush()
+}
+
+type dotWriter struct {
+	tree     *Tree
+	w        *bufio.Writer
+	maxDepth int
+	ids      int
+}
+
+// node writes node n (depth levels below the dumped subtree) with its children and returns its id.
+func (d *dotWriter) node(n *node, walkpath []byte, depth int) int {
+	id := d.ids
+	d.ids++
+	cidr := walkpath2net(d.tree.walkOpt(OptWalkIPAuto), walkpath)
+	label := "/" + fmt.Sprint(len(walkpath))
+	if cidr.IP != nil {
+		label = cidr.String()
+	}
+	if n.value != nil {
+		fmt.Fprintf(d.w, "\tn%d [label=%q, shape=box, style=filled];\n", id, fmt.Sprintf("%s\n%v", label, n.value))
+	} else {
+		fmt.Fprintf(d.w, "\tn%d [label=%q];\n", id, label)
+	}
+	for bit, c := range []*node{n.left, n.right} {
+		if c == nil {
+			continue
+		}
+		if d.maxDepth >= 0 && depth >= d.maxDepth {
+			var st SubtreeStats
+			countSubtree(c, &st)
+			fmt.Fprintf(d.w, "\tn%d [label=\"%d nodes\\n%d values\", shape=triangle];\n", d.ids, st.Nodes, st.ValuedNodes)
+			fmt.Fprintf(d.w, "\tn%d -> n%d [label=\"%d\", style=dashed];\n", id, d.ids, bit)
+			d.ids++
+			continue
+		}
+		child := d.node(c, append(walkpath, byte(bit)), depth+1)
+		fmt.Fprintf(d.w, "\tn%d -> n%d [label=\"%d\"];\n", id, child, bit)
+	}
+	return id
+}
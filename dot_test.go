@@ -0,0 +1,56 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDumpDOT(t *testing.T) {
+	tr := NewTree()
+	tr.AddCIDR("10.0.0.0/8", "ten")
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("192.168.0.0/16", 3)
+
+	var buf bytes.Buffer
+	if err := tr.DumpDOT(&buf, -1); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "digraph nradix {\n") || !strings.HasSuffix(out, "}\n") {
+		t.Errorf("Wrong graph:\n%s", out)
+	}
+	for _, expected := range []string{`label="10.0.0.0/8\nten", shape=box`, `label="10.1.0.0/16\n2", shape=box`, `label="192.168.0.0/16\n3", shape=box`} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Wrong graph, expected %s in:\n%s", expected, out)
+		}
+	}
+	nodes, _, _, _ := tr.GetStats()
+	if n := strings.Count(out, "[label=\"") - strings.Count(out, "->"); n != nodes {
+		t.Errorf("Wrong number of nodes, expected %d, got %d", nodes, n)
+	}
+	if strings.Contains(out, "triangle") {
+		t.Errorf("Wrong graph, expected no cut subtrees in:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := tr.DumpSubtreeDOT(&buf, "10.0.0.0/8", 2); err != nil {
+		t.Fatal(err)
+	}
+	out = buf.String()
+	if !strings.Contains(out, `label="10.0.0.0/8\nten"`) || strings.Contains(out, "192.168") {
+		t.Errorf("Wrong subtree graph:\n%s", out)
+	}
+	// 10.0.0.0/10 is cut off with 6 nodes down to 10.1.0.0/16
+	if !strings.Contains(out, `label="6 nodes\n1 values", shape=triangle`) {
+		t.Errorf("Wrong cut subtree in:\n%s", out)
+	}
+
+	if err := tr.DumpSubtreeDOT(&buf, "bad", 1); err == nil {
+		t.Errorf("Should have gotten error for bad cidr")
+	}
+}
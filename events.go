@@ -0,0 +1,107 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+)
+
+// Op is a kind of change of value reported to OnChange subscribers.
+type Op int
+
+const (
+	OpAdd    Op = iota + 1 // value added to IP/mask without value
+	OpSet                  // value of IP/mask replaced
+	OpDelete               // value of IP/mask removed
+)
+
+func (op Op) String() string {
+	switch op {
+	case OpAdd:
+		return "add"
+	case OpSet:
+		return "set"
+	case OpDelete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+type subscriber struct {
+	fn func(op Op, cidr net.IPNet, old, new interface{})
+}
+
+// OnChange subscribes fn to changes of values of the tree (by all methods except UnmarshalBinary, including
+// expiration and online aggregation moving values), it returns function cancelling the subscription.
+// fn is called under the lock of the tree, right before the change is visible, and must not use the tree.
+func (tree *Tree) OnChange(fn func(op Op, cidr net.IPNet, old, new interface{})) (cancel func()) {
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	sub := &subscriber{fn: fn}
+	tree.subscribers = append(tree.subscribers, sub)
+	return func() {
+		if tree.safe {
+			tree.Lock()
+			defer tree.Unlock()
+		}
+		for i, s := range tree.subscribers {
+			if s == sub {
+				tree.subscribers = append(tree.subscribers[:i:i], tree.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// changed reports change of value of the node from old to new.
+func (tree *Tree) changed(n *node, old, new interface{}) {
+	if tree.index != nil {
+		tree.index.changed(tree, n, new)
+	}
+	if tree.lru != nil {
+		if new == nil {
+			tree.lru.drop(n)
+		} else {
+			tree.lru.use(n)
+		}
+	}
+	if len(tree.subscribers) == 0 {
+		return
+	}
+	op := OpSet
+	switch {
+	case old == nil && new == nil:
+		return
+	case old == nil:
+		op = OpAdd
+	case new == nil:
+		op = OpDelete
+	}
+	cidr := walkpath2net(tree.walkOpt(OptWalkIPAuto), nodeWalkpath(n))
+	for _, s := range tree.subscribers {
+		s.fn(op, cidr, old, new)
+	}
+}
+
+// removing reports removal of all values of the subtree of the node.
+func (tree *Tree) removing(n *node) {
+	if tree.index != nil {
+		tree.index.removing(tree, n)
+	}
+	if len(tree.subscribers) == 0 && tree.lru == nil {
+		return
+	}
+	tree.walk(OptWalkIPAuto|optWalkExpired, func(cidr net.IPNet, n *node) (bool, error) {
+		if tree.lru != nil {
+			tree.lru.drop(n)
+		}
+		for _, s := range tree.subscribers {
+			s.fn(OpDelete, cidr, n.value, nil)
+		}
+		return true, nil
+	}, nodeWalkpath(n), n)
+}
@@ -0,0 +1,66 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestOnChange(t *testing.T) {
+	tr := NewTree()
+	var events []string
+	cancel := tr.OnChange(func(op Op, cidr net.IPNet, old, new interface{}) {
+		events = append(events, fmt.Sprintf("%v %v %v %v", op, cidr.String(), old, new))
+	})
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.0.0.0/8", 2)
+	tr.SetCIDR("10.0.0.0/8", 3)
+	tr.AddCIDR("10.1.0.0/16", 4)
+	tr.AddCIDR("2001:db8::/48", 5)
+	tr.DeleteCIDR("10.0.0.0/8")
+	tr.AddCIDR("10.0.0.0/8", 6)
+	tr.DeleteWholeRangeCIDR("10.0.0.0/8")
+	tr.BulkAdd([]PrefixValue{{"192.168.0.0/16", 7}})
+	ref, _ := tr.NodeRef("192.168.0.0/16")
+	ref.SetValue(8)
+	cancel()
+	tr.DeleteCIDR("2001:db8::/48")
+
+	expected := []string{
+		"add 10.0.0.0/8 <nil> 1",
+		"set 10.0.0.0/8 1 3",
+		"add 10.1.0.0/16 <nil> 4",
+		"add 2001:db8::/48 <nil> 5",
+		"delete 10.0.0.0/8 3 <nil>",
+		"add 10.0.0.0/8 <nil> 6",
+		"delete 10.0.0.0/8 6 <nil>",
+		"delete 10.1.0.0/16 4 <nil>",
+		"add 192.168.0.0/16 <nil> 7",
+		"set 192.168.0.0/16 7 8",
+	}
+	if fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Errorf("Wrong events, expected\n%v\ngot\n%v", expected, events)
+	}
+
+	tr = NewTree(WithOnlineAggregation(nil))
+	events = nil
+	tr.OnChange(func(op Op, cidr net.IPNet, old, new interface{}) {
+		events = append(events, fmt.Sprintf("%v %v %v %v", op, cidr.String(), old, new))
+	})
+	tr.AddCIDR("10.0.0.0/25", 1)
+	tr.AddCIDR("10.0.0.128/25", 1)
+	expected = []string{
+		"add 10.0.0.0/25 <nil> 1",
+		"add 10.0.0.128/25 <nil> 1",
+		"add 10.0.0.0/24 <nil> 1",
+		"delete 10.0.0.128/25 1 <nil>",
+		"delete 10.0.0.0/25 1 <nil>",
+	}
+	if fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Errorf("Wrong aggregation events, expected\n%v\ngot\n%v", expected, events)
+	}
+}
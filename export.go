@@ -0,0 +1,89 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+)
+
+// ExportFormat is a text format of IP/masks written by Export.
+type ExportFormat struct {
+	header, footer string
+	line           func(cidr net.IPNet, value interface{}) string
+	opt            OptWalk
+}
+
+var (
+	// ExportCIDR writes one CIDR per line.
+	ExportCIDR = ExportFormat{line: func(cidr net.IPNet, value interface{}) string {
+		return cidr.String() + "\n"
+	}}
+	// ExportIPRoute writes "route add CIDR via VALUE" lines (for "ip -batch"), value is the gateway.
+	ExportIPRoute = ExportFormat{line: func(cidr net.IPNet, value interface{}) string {
+		return fmt.Sprintf("route add %s via %v\n", cidr.String(), value)
+	}}
+)
+
+// ExportNftables writes command adding CIDRs as elements of nftables set ("family table set", like
+// "inet filter blocklist") for "nft -f", the set needs interval flag. Nothing is written for an empty tree.
+func ExportNftables(set string) ExportFormat {
+	return ExportFormat{
+		header: "add element " + set + " {\n",
+		footer: "}\n",
+		line: func(cidr net.IPNet, value interface{}) string {
+			return "\t" + cidr.String() + ",\n"
+		},
+	}
+}
+
+// ExportIPSet writes "add SET CIDR" lines for "ipset restore", the set has to be of hash:net type.
+func ExportIPSet(set string) ExportFormat {
+	return ExportFormat{line: func(cidr net.IPNet, value interface{}) string {
+		return "add " + set + " " + cidr.String() + "\n"
+	}}
+}
+
+// Family returns the format writing only IP/masks of the family given as OptWalkIPv4 or OptWalkIPv6
+// (sets of nftables and ipset hold one family). Families are told apart as by WalkTree, so IPv6 export of a tree
+// without WithIPv4Mapped includes IPv4 IP/masks as IPv6 of the same leading bits.
+func (f ExportFormat) Family(opt OptWalk) ExportFormat {
+	f.opt = opt & OptWalkIPAuto
+	return f
+}
+
+// Export writes IP/masks with values of the tree in address order in the format.
+func (tree *Tree) Export(w io.Writer, format ExportFormat) error {
+	opt := format.opt
+	if opt == 0 {
+		opt = OptWalkIPAuto
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	bw := bufio.NewWriter(w)
+	var written bool
+	err := tree.walkNodes(opt, func(cidr net.IPNet, n *node) (bool, error) {
+		if cidr.IP == nil {
+			return true, nil
+		}
+		if !written {
+			written = true
+			bw.WriteString(format.header)
+		}
+		_, err := bw.WriteString(format.line(cidr, n.value))
+		return true, err
+	})
+	if err != nil {
+		return err
+	}
+	if written {
+		bw.WriteString(format.footer)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,52 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	tr := NewTree()
+	for cidr, gw := range map[string]string{"10.0.0.0/8": "192.168.0.1", "172.16.0.0/12": "192.168.0.2", "2001:db8::/48": "fe80::1"} {
+		if err := tr.AddCIDR(cidr, gw); err != nil {
+			t.Error(err)
+		}
+	}
+	for _, tc := range []struct {
+		format   ExportFormat
+		expected string
+	}{
+		{ExportCIDR, "10.0.0.0/8\n2001:db8::/48\n172.16.0.0/12\n"},
+		{ExportIPRoute, "route add 10.0.0.0/8 via 192.168.0.1\nroute add 2001:db8::/48 via fe80::1\nroute add 172.16.0.0/12 via 192.168.0.2\n"},
+		{ExportNftables("ip filter nets").Family(OptWalkIPv4), "add element ip filter nets {\n\t10.0.0.0/8,\n\t172.16.0.0/12,\n}\n"},
+	} {
+		var buf bytes.Buffer
+		if err := tr.Export(&buf, tc.format); err != nil {
+			t.Error(err)
+		}
+		if buf.String() != tc.expected {
+			t.Errorf("Wrong export, expected %q, got %q", tc.expected, buf.String())
+		}
+	}
+
+	mapped := NewTree(WithIPv4Mapped())
+	if err := mapped.Merge(tr, nil); err != nil {
+		t.Error(err)
+	}
+	var buf bytes.Buffer
+	if err := mapped.Export(&buf, ExportIPSet("nets6").Family(OptWalkIPv6)); err != nil {
+		t.Error(err)
+	}
+	if buf.String() != "add nets6 2001:db8::/48\n" {
+		t.Errorf("Wrong export, expected %q, got %q", "add nets6 2001:db8::/48\n", buf.String())
+	}
+
+	buf.Reset()
+	if err := NewTree().Export(&buf, ExportNftables("ip filter nets")); err != nil || buf.Len() != 0 {
+		t.Errorf("Wrong export of empty tree: %q, %v", buf.String(), err)
+	}
+}
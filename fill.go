@@ -0,0 +1,70 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"net"
+)
+
+// PrefixGenerator generates pseudo-random IP/masks, the same seed gives the same sequence, so benchmarks and tests
+// of downstream value types are repeatable. Prefix lengths follow a routing table: mostly /24 for IPv4 and /48 for
+// IPv6, IPv6 prefixes are always longer than 32 bits so they never take nodes of IPv4 ones.
+type PrefixGenerator struct {
+	rnd *rand.Rand
+}
+
+// NewPrefixGenerator creates PrefixGenerator with the seed.
+func NewPrefixGenerator(seed int64) *PrefixGenerator {
+	return &PrefixGenerator{rnd: rand.New(rand.NewSource(seed))}
+}
+
+var (
+	prefixLens4 = []int{8, 12, 16, 16, 19, 20, 21, 22, 22, 23, 23, 24, 24, 24, 24, 24, 24, 24, 24, 28, 32}
+	prefixLens6 = []int{33, 36, 40, 44, 48, 48, 48, 48, 48, 56, 64, 128}
+)
+
+// IPv4 returns random IPv4 IP/mask.
+func (g *PrefixGenerator) IPv4() string {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, g.rnd.Uint32())
+	bits := prefixLens4[g.rnd.Intn(len(prefixLens4))]
+	n := net.IPNet{IP: ip.Mask(net.CIDRMask(bits, net.IPv4len*8)), Mask: net.CIDRMask(bits, net.IPv4len*8)}
+	return n.String()
+}
+
+// IPv6 returns random IPv6 IP/mask.
+func (g *PrefixGenerator) IPv6() string {
+	ip := make(net.IP, net.IPv6len)
+	binary.BigEndian.PutUint64(ip[:8], g.rnd.Uint64())
+	binary.BigEndian.PutUint64(ip[8:], g.rnd.Uint64())
+	ip[0] = 0x20 | ip[0]&0x1f // global unicast 2000::/3
+	bits := prefixLens6[g.rnd.Intn(len(prefixLens6))]
+	n := net.IPNet{IP: ip.Mask(net.CIDRMask(bits, net.IPv6len*8)), Mask: net.CIDRMask(bits, net.IPv6len*8)}
+	return n.String()
+}
+
+// Prefix returns random IP/mask, IPv4 with probability of 3/4.
+func (g *PrefixGenerator) Prefix() string {
+	if g.rnd.Intn(4) == 0 {
+		return g.IPv6()
+	}
+	return g.IPv4()
+}
+
+// FillRandom adds n distinct random IP/masks (of PrefixGenerator with seed 1) to the tree with values 0..n-1 in
+// order they were generated and returns the IP/masks. IP/masks already having value in the tree are skipped.
+func FillRandom(tree *Tree, n int) []string {
+	g := NewPrefixGenerator(1)
+	ret := make([]string, 0, n)
+	for len(ret) < n {
+		cidr := g.Prefix()
+		if tree.AddCIDR(cidr, len(ret)) == nil {
+			ret = append(ret, cidr)
+		}
+	}
+	return ret
+}
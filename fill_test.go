@@ -0,0 +1,45 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"testing"
+)
+
+func TestPrefixGenerator(t *testing.T) {
+	a, b := NewPrefixGenerator(7), NewPrefixGenerator(7)
+	for i := 0; i < 100; i++ {
+		pa, pb := a.Prefix(), b.Prefix()
+		if pa != pb {
+			t.Errorf("Wrong prefix, expected %s, got %s", pa, pb)
+		}
+		if _, err := parseEntry([]byte(pa)); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestFillRandom(t *testing.T) {
+	tr := NewTree()
+	tr.AddCIDR("10.0.0.0/8", "taken")
+	prefixes := FillRandom(tr, 500)
+	if len(prefixes) != 500 {
+		t.Errorf("Wrong number of prefixes, expected 500, got %d", len(prefixes))
+	}
+	if _, valued, _, _ := tr.GetStats(); valued != 501 {
+		t.Errorf("Wrong number of values, expected 501, got %d", valued)
+	}
+	for i, cidr := range prefixes {
+		if inf, err := tr.FindExactCIDR(cidr); err != nil || inf != i {
+			t.Errorf("Wrong value for %s, expected %d, got %v (%v)", cidr, i, inf, err)
+		}
+	}
+	a, b := FillRandom(NewTree(), 100), FillRandom(NewTree(), 100)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Errorf("Wrong prefix of the second fill, expected %s, got %s", a[i], b[i])
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+)
+
+// ReadOnlyTree is an immutable copy of the Tree made by Freeze. It has no methods changing it and its lookups
+// and walks take no locks, so it can be used from many goroutines at no locking cost.
+type ReadOnlyTree struct {
+	tree *Tree
+}
+
+// Freeze returns ReadOnlyTree holding the current content of the tree, values are shared with the tree
+// (not copied with the CloneValueFunc). Later changes of the tree don't affect it.
+func (tree *Tree) Freeze() *ReadOnlyTree {
+	frozen := tree.CloneFunc(nil)
+	frozen.safe = false
+	frozen.guard = nil
+	return &ReadOnlyTree{tree: frozen}
+}
+
+// GetStats get tree stats, see Tree.GetStats.
+func (r *ReadOnlyTree) GetStats() (treeNodes, valuetreeNodes, totalNodes, freetotalNodes int) {
+	return r.tree.GetStats()
+}
+
+// FindCIDR finds the value of the longest IP/mask covering the cidr, see Tree.FindCIDR.
+func (r *ReadOnlyTree) FindCIDR(cidr string) (interface{}, error) {
+	return r.tree.FindCIDR(cidr)
+}
+
+// Find32 finds the value of the longest IP/mask covering the IPv4 address, see Tree.Find32.
+func (r *ReadOnlyTree) Find32(ip uint32) interface{} {
+	return r.tree.Find32(ip)
+}
+
+// Find128 finds the value of the longest IP/mask covering the IPv6 address, see Tree.Find128.
+func (r *ReadOnlyTree) Find128(ip [16]byte) interface{} {
+	return r.tree.Find128(ip)
+}
+
+// FindCIDRNet finds the value of the longest IP/mask covering the cidr together with the IP/mask,
+// see Tree.FindCIDRNet.
+func (r *ReadOnlyTree) FindCIDRNet(cidr string) (interface{}, net.IPNet, error) {
+	return r.tree.FindCIDRNet(cidr)
+}
+
+// FindExactCIDR finds the value of exactly the cidr, see Tree.FindExactCIDR.
+func (r *ReadOnlyTree) FindExactCIDR(cidr string) (interface{}, error) {
+	return r.tree.FindExactCIDR(cidr)
+}
+
+// FindAllCIDR finds values of all IP/masks covering the cidr, see Tree.FindAllCIDR.
+func (r *ReadOnlyTree) FindAllCIDR(cidr string) ([]interface{}, error) {
+	return r.tree.FindAllCIDR(cidr)
+}
+
+// WalkTree walks IP/masks with values, see Tree.WalkTree.
+func (r *ReadOnlyTree) WalkTree(opt OptWalk, wtfunc WalkTreeFunc) error {
+	return r.tree.WalkTree(opt, wtfunc)
+}
+
+// Thaw returns a new Tree (created with the options of the frozen tree) holding the content of the ReadOnlyTree.
+func (r *ReadOnlyTree) Thaw() *Tree {
+	return r.tree.CloneFunc(nil)
+}
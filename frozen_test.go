@@ -0,0 +1,51 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	tr := NewTree(WithLocking(true))
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("2001:db8::/32", 2)
+	frozen := tr.Freeze()
+	if frozen.tree.safe {
+		t.Errorf("Frozen tree uses locking")
+	}
+	tr.SetCIDR("10.0.0.0/8", 3)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if inf, _ := frozen.FindCIDR("10.1.1.1"); inf != 1 {
+					t.Errorf("Wrong value, expected 1, got %v", inf)
+					return
+				}
+				if inf, _ := frozen.FindCIDR("2001:db8::1"); inf != 2 {
+					t.Errorf("Wrong value, expected 2, got %v", inf)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	thawed := frozen.Thaw()
+	if !thawed.safe {
+		t.Errorf("Thawed tree doesn't use locking")
+	}
+	if err := thawed.AddCIDR("192.168.0.0/16", 4); err != nil {
+		t.Error(err)
+	}
+	if inf, _ := frozen.FindCIDR("192.168.1.1"); inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+}
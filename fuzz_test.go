@@ -0,0 +1,85 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"testing"
+)
+
+func FuzzParseCIDR(f *testing.F) {
+	for _, seed := range []string{"10.0.0.0/8", "1.2.3.4", "2001:db8::/32", "::ffff:1.2.3.0/120", "::/0", "1.2.3.4/33", "1..2.3/8", ""} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, cidr string) {
+		tr := NewTree()
+		err := tr.AddCIDR(cidr, 1)
+		if _, _, perr := net.ParseCIDR(cidr); perr == nil && err != nil {
+			t.Fatalf("%q rejected, net.ParseCIDR accepts it: %v", cidr, err)
+		}
+		if err != nil {
+			return
+		}
+		if v, err := tr.FindExactCIDR(cidr); v != 1 || err != nil {
+			t.Fatalf("%q not found after add: %v %v", cidr, v, err)
+		}
+		if err = tr.DeleteCIDR(cidr); err != nil {
+			t.Fatalf("%q not deleted: %v", cidr, err)
+		}
+		if err = tr.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// FuzzTreeOps runs interleaved adds, sets and deletes of IPv4 prefixes (5 bytes each: op and key, the last
+// key byte giving the mask) and compares the tree with a map.
+func FuzzTreeOps(f *testing.F) {
+	f.Add([]byte{0, 10, 0, 0, 8, 0, 10, 1, 0, 16, 2, 10, 0, 0, 8, 1, 10, 1, 0, 16})
+	f.Add([]byte{0, 192, 168, 1, 24, 0, 192, 168, 0, 23, 2, 192, 168, 1, 24, 3, 0, 0, 0, 0})
+	f.Add([]byte{2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		tr := NewTree()
+		model := make(map[string]int)
+		for i := 0; i+5 <= len(data); i += 5 {
+			op, b := data[i]%4, data[i+1:i+5]
+			cidr := (&net.IPNet{IP: net.IPv4(b[0], b[1], b[2], 0).Mask(net.CIDRMask(int(b[3]%33), 32)), Mask: net.CIDRMask(int(b[3]%33), 32)}).String()
+			switch op {
+			case 0:
+				err := tr.AddCIDR(cidr, i)
+				if _, ok := model[cidr]; ok != (err == ErrNodeBusy) {
+					t.Fatalf("add %s: %v", cidr, err)
+				}
+				if err == nil {
+					model[cidr] = i
+				}
+			case 1:
+				if err := tr.SetCIDR(cidr, i); err != nil {
+					t.Fatalf("set %s: %v", cidr, err)
+				}
+				model[cidr] = i
+			case 2:
+				err := tr.DeleteCIDR(cidr)
+				if _, ok := model[cidr]; ok != (err == nil) {
+					t.Fatalf("delete %s: %v", cidr, err)
+				}
+				delete(model, cidr)
+			case 3:
+				tr.Compact()
+			}
+			if err := tr.Validate(); err != nil {
+				t.Fatalf("after op %d on %s: %v", op, cidr, err)
+			}
+		}
+		for cidr, expected := range model {
+			if v, err := tr.FindExactCIDR(cidr); v != expected || err != nil {
+				t.Fatalf("%s: expected %d, got %v %v", cidr, expected, v, err)
+			}
+		}
+		if _, values, _, _ := tr.GetStats(); values != len(model) {
+			t.Fatalf("Wrong number of values, expected %d, got %d", len(model), values)
+		}
+	})
+}
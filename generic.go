@@ -0,0 +1,146 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+)
+
+// ErrValueType is returned by TypedTree for value found in the tree which is not of its type.
+var ErrValueType = errors.New("Value is not of the type of the tree")
+
+// TypedTree is a typed facade of the Tree for values of type T, so callers don't write type assertions on every
+// lookup. It does not avoid boxing: values are kept in the underlying Tree (available with Untyped) as interface{},
+// so adding a value of non-pointer type may allocate and every lookup asserts the value back to T. Values of other
+// types (put through Untyped, default route and the like) are reported with ErrValueType. Use HandleTree to keep
+// values in a caller managed slice without boxing.
+type TypedTree[T any] struct {
+	tree *Tree
+}
+
+// NewTypedTree creates TypedTree configured by opts.
+func NewTypedTree[T any](opts ...Option) *TypedTree[T] {
+	return &TypedTree[T]{tree: NewTree(opts...)}
+}
+
+// Untyped returns the underlying Tree.
+func (t *TypedTree[T]) Untyped() *Tree {
+	return t.tree
+}
+
+// GetStats get tree stats, see Tree.GetStats.
+func (t *TypedTree[T]) GetStats() (treeNodes, valuetreeNodes, totalNodes, freetotalNodes int) {
+	return t.tree.GetStats()
+}
+
+// AddCIDR adds value associated with IP/mask to the tree. Will return error for invalid CIDR or if value already exists.
+func (t *TypedTree[T]) AddCIDR(cidr string, val T) error {
+	return t.tree.AddCIDR(cidr, val)
+}
+
+// SetCIDR adds value associated with IP/mask to the tree. Will return error for invalid CIDR.
+func (t *TypedTree[T]) SetCIDR(cidr string, val T) error {
+	return t.tree.SetCIDR(cidr, val)
+}
+
+// DeleteCIDR removes value associated with IP/mask from the tree.
+func (t *TypedTree[T]) DeleteCIDR(cidr string) error {
+	return t.tree.DeleteCIDR(cidr)
+}
+
+// DeleteWholeRangeCIDR removes all values associated with IPs in the entire subnet specified by the CIDR.
+func (t *TypedTree[T]) DeleteWholeRangeCIDR(cidr string) error {
+	return t.tree.DeleteWholeRangeCIDR(cidr)
+}
+
+// FindCIDR returns value saved in longest covered IP, ok is false if there is none.
+func (t *TypedTree[T]) FindCIDR(cidr string) (val T, ok bool, err error) {
+	v, err := t.tree.FindCIDR(cidr)
+	return typed[T](v, err)
+}
+
+// FindExactCIDR returns value saved for an exact match, ErrNotFound is returned if there is none.
+func (t *TypedTree[T]) FindExactCIDR(cidr string) (T, error) {
+	v, err := t.tree.FindExactCIDR(cidr)
+	val, _, err := typed[T](v, err)
+	return val, err
+}
+
+// FindAllCIDR returns values saved in all covered IPs, from least to most specific.
+func (t *TypedTree[T]) FindAllCIDR(cidr string) ([]T, error) {
+	values, err := t.tree.FindAllCIDR(cidr)
+	return typedAll[T](values, err)
+}
+
+// AddPrefix adds value associated with the prefix to the tree. Will return error for invalid prefix or if value already exists.
+func (t *TypedTree[T]) AddPrefix(p netip.Prefix, val T) error {
+	return t.tree.AddPrefix(p, val)
+}
+
+// SetPrefix adds value associated with the prefix to the tree. Will return error for invalid prefix.
+func (t *TypedTree[T]) SetPrefix(p netip.Prefix, val T) error {
+	return t.tree.SetPrefix(p, val)
+}
+
+// DeletePrefix removes value associated with the prefix from the tree.
+func (t *TypedTree[T]) DeletePrefix(p netip.Prefix) error {
+	return t.tree.DeletePrefix(p)
+}
+
+// FindAddr returns value saved in longest covered prefix of the address, ok is false if there is none.
+func (t *TypedTree[T]) FindAddr(a netip.Addr) (val T, ok bool, err error) {
+	v, err := t.tree.FindAddr(a)
+	return typed[T](v, err)
+}
+
+// FindPrefix returns value saved in longest covered prefix, ok is false if there is none.
+func (t *TypedTree[T]) FindPrefix(p netip.Prefix) (val T, ok bool, err error) {
+	v, err := t.tree.FindPrefix(p)
+	return typed[T](v, err)
+}
+
+// WalkTypedTreeFunc is the type of function for caller of TypedTree.WalkTree, see WalkTreeFunc.
+type WalkTypedTreeFunc[T any] func(cidr net.IPNet, value T) (bool, error)
+
+// WalkTree walks the tree (depth first) and calls the `WalkTypedTreeFunc` for each node with a value.
+func (t *TypedTree[T]) WalkTree(opt OptWalk, wtfunc WalkTypedTreeFunc[T]) error {
+	return t.tree.WalkTree(opt, func(cidr net.IPNet, value interface{}) (bool, error) {
+		val, ok := value.(T)
+		if !ok {
+			return false, ErrValueType
+		}
+		return wtfunc(cidr, val)
+	})
+}
+
+func typed[T any](v interface{}, err error) (val T, ok bool, rerr error) {
+	if err != nil || v == nil {
+		return val, false, err
+	}
+	if val, ok = v.(T); !ok {
+		return val, false, ErrValueType
+	}
+	return val, true, nil
+}
+
+func typedAll[T any](values []interface{}, err error) ([]T, error) {
+	if err != nil {
+		return nil, err
+	}
+	var ret []T
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		val, ok := v.(T)
+		if !ok {
+			return nil, ErrValueType
+		}
+		ret = append(ret, val)
+	}
+	return ret, nil
+}
@@ -0,0 +1,79 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func TestTypedTree(t *testing.T) {
+	tr := NewTypedTree[string]()
+	tr.AddCIDR("10.0.0.0/8", "ten")
+	tr.AddCIDR("10.1.0.0/16", "ten-one")
+	tr.AddPrefix(netip.MustParsePrefix("dead::/16"), "dead")
+
+	val, ok, err := tr.FindCIDR("10.1.1.1")
+	if err != nil || !ok || val != "ten-one" {
+		t.Errorf("Wrong value, expected ten-one, got %q %v %v", val, ok, err)
+	}
+	if val, ok, _ = tr.FindCIDR("11.1.1.1"); ok || val != "" {
+		t.Errorf("Expected no value, got %q %v", val, ok)
+	}
+	if val, ok, _ = tr.FindAddr(netip.MustParseAddr("dead::1")); !ok || val != "dead" {
+		t.Errorf("Wrong value, expected dead, got %q %v", val, ok)
+	}
+	if val, err = tr.FindExactCIDR("10.0.0.0/8"); err != nil || val != "ten" {
+		t.Errorf("Wrong exact value, expected ten, got %q %v", val, err)
+	}
+	if _, err = tr.FindExactCIDR("10.0.0.0/9"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	all, err := tr.FindAllCIDR("10.1.2.3")
+	if err != nil || len(all) != 2 || all[0] != "ten" || all[1] != "ten-one" {
+		t.Errorf("Wrong values, got %v %v", all, err)
+	}
+
+	var walked []string
+	tr.WalkTree(OptWalkIPAuto, func(cidr net.IPNet, value string) (bool, error) {
+		walked = append(walked, value)
+		return true, nil
+	})
+	if len(walked) != 3 {
+		t.Errorf("Wrong walk, got %v", walked)
+	}
+
+	tr.DeleteCIDR("10.1.0.0/16")
+	if _, values, _, _ := tr.GetStats(); values != 2 {
+		t.Errorf("Wrong valued nodes, expected 2, got %d", values)
+	}
+	if tr.Untyped() == nil {
+		t.Error("Expected underlying tree")
+	}
+}
+
+func TestTypedTreeValueType(t *testing.T) {
+	tr := NewTypedTree[int](WithDefaultRoute("none"))
+	tr.AddCIDR("10.0.0.0/8", 1)
+	if val, ok, err := tr.FindCIDR("10.0.0.1"); err != nil || !ok || val != 1 {
+		t.Errorf("Wrong value, expected 1 true <nil>, got %v %v %v", val, ok, err)
+	}
+	if val, ok, err := tr.FindCIDR("192.168.0.1"); err != ErrValueType || ok || val != 0 {
+		t.Errorf("Wrong value of default route, expected 0 false %v, got %v %v %v", ErrValueType, val, ok, err)
+	}
+
+	tr.Untyped().AddCIDR("10.1.0.0/16", "string")
+	if _, err := tr.FindExactCIDR("10.1.0.0/16"); err != ErrValueType {
+		t.Errorf("Expected %v, got %v", ErrValueType, err)
+	}
+	if _, err := tr.FindAllCIDR("10.1.0.1"); err != ErrValueType {
+		t.Errorf("Expected %v, got %v", ErrValueType, err)
+	}
+	err := tr.WalkTree(OptWalkIPAuto, func(cidr net.IPNet, value int) (bool, error) { return true, nil })
+	if err != ErrValueType {
+		t.Errorf("Expected %v, got %v", ErrValueType, err)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// LoadGeoLite2CSV streams MaxMind GeoLite2 (or GeoIP2) blocks CSV file, Country or City, IPv4 or IPv6, to the tree.
+// Value stored for the network is value(geoname ID), the ID is geoname_id or registered_country_geoname_id when
+// geoname_id is empty. Networks without ID or for which value returns nil are skipped, return the same value for
+// the same ID to keep one value per location. Will return error (with line number) for bad file, invalid network
+// or if value already exists, networks of the lines before it are kept.
+func (tree *Tree) LoadGeoLite2CSV(r io.Reader, value func(geonameID uint32) interface{}) error {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return err
+	}
+	network, geoname, registered := -1, -1, -1
+	for i, name := range header {
+		switch name {
+		case "network":
+			network = i
+		case "geoname_id":
+			geoname = i
+		case "registered_country_geoname_id":
+			registered = i
+		}
+	}
+	if network < 0 || geoname < 0 {
+		return fmt.Errorf("line 1: missing network or geoname_id column")
+	}
+
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		id := record[geoname]
+		if id == "" && registered >= 0 {
+			id = record[registered]
+		}
+		if id == "" {
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+		n, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return fmt.Errorf("line %d: bad geoname ID %q", line, id)
+		}
+		val := value(uint32(n))
+		if val == nil {
+			continue
+		}
+		if err = tree.addCIDRb([]byte(record[network]), val); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const geoLite2Blocks = `network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,is_anonymous_proxy,is_satellite_provider
+1.0.0.0/24,2077456,2077456,,0,0
+1.0.1.0/24,1814991,1814991,,0,0
+1.0.4.0/22,,2077456,,0,0
+1.0.8.0/21,,,,1,0
+2001:200::/32,1861060,1861060,,0,0
+`
+
+func TestLoadGeoLite2CSV(t *testing.T) {
+	countries := map[uint32]interface{}{2077456: "AU", 1814991: "CN"}
+	tr := NewTree()
+	err := tr.LoadGeoLite2CSV(strings.NewReader(geoLite2Blocks), func(id uint32) interface{} { return countries[id] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ip, expected := range map[string]interface{}{
+		"1.0.0.1":     "AU",
+		"1.0.1.1":     "CN",
+		"1.0.5.1":     "AU",
+		"1.0.9.1":     nil,
+		"2001:200::1": nil,
+	} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != expected {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, expected, inf)
+		}
+	}
+
+	bad := strings.Replace(geoLite2Blocks, "1.0.1.0/24", "1.0.1.x/24", 1)
+	err = NewTree().LoadGeoLite2CSV(strings.NewReader(bad), func(id uint32) interface{} { return id })
+	if !errors.Is(err, ErrBadIP) || !strings.HasPrefix(err.Error(), "line 3: ") {
+		t.Errorf("Expected ErrBadIP on line 3, got %v", err)
+	}
+	err = NewTree().LoadGeoLite2CSV(strings.NewReader("cidr,value\n"), func(id uint32) interface{} { return id })
+	if err == nil {
+		t.Errorf("Expected error for missing columns")
+	}
+}
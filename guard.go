@@ -0,0 +1,58 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// WithMisuseGuard makes a tree created without locking detect concurrent use: insert or delete running at the same
+// time as any other insert, delete, lookup or walk panics with description of both operations instead of silently
+// corrupting the tree. Every operation pays for two atomic operations, so use it for debugging and tests.
+func WithMisuseGuard() Option {
+	return func(tree *Tree) {
+		tree.guard = new(guard)
+	}
+}
+
+// guard stamps the tree with its current users: -1 for a writer or number of readers.
+type guard struct {
+	state int64
+	op    atomic.Value
+}
+
+func (g *guard) enterWrite(op string) {
+	if !atomic.CompareAndSwapInt64(&g.state, 0, -1) {
+		g.fail(op)
+	}
+	g.op.Store(op)
+}
+
+func (g *guard) exitWrite() {
+	atomic.StoreInt64(&g.state, 0)
+}
+
+func (g *guard) enterRead(op string) {
+	for {
+		s := atomic.LoadInt64(&g.state)
+		if s < 0 {
+			g.fail(op)
+		}
+		if atomic.CompareAndSwapInt64(&g.state, s, s+1) {
+			g.op.Store(op)
+			return
+		}
+	}
+}
+
+func (g *guard) exitRead() {
+	atomic.AddInt64(&g.state, -1)
+}
+
+func (g *guard) fail(op string) {
+	panic(fmt.Sprintf("nradix: concurrent use of tree without locking: %s called while %s is running (%d users)",
+		op, g.op.Load(), atomic.LoadInt64(&g.state)))
+}
@@ -0,0 +1,50 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestMisuseGuard(t *testing.T) {
+	tr := NewTree(WithMisuseGuard())
+	tr.AddCIDR("10.0.0.0/8", 1)
+	if inf, _ := tr.FindCIDR("10.1.1.1"); inf.(int) != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+
+	// lookups during walk are fine, mutation is not
+	err := tr.WalkTree(OptWalkIPv4, func(cidr net.IPNet, value interface{}) (bool, error) {
+		tr.FindCIDR("10.1.1.1")
+		expectMisusePanic(t, "insert called while", func() { tr.AddCIDR("11.0.0.0/8", 2) })
+		return true, nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	// simulate writer running in other goroutine
+	tr.guard.enterWrite("insert")
+	expectMisusePanic(t, "find called while insert", func() { tr.FindCIDR("10.1.1.1") })
+	expectMisusePanic(t, "delete called while insert", func() { tr.DeleteCIDR("10.0.0.0/8") })
+	tr.guard.exitWrite()
+
+	if err = tr.DeleteCIDR("10.0.0.0/8"); err != nil {
+		t.Error(err)
+	}
+}
+
+func expectMisusePanic(t *testing.T, msg string, fn func()) {
+	t.Helper()
+	defer func() {
+		r := recover()
+		if s, ok := r.(string); !ok || !strings.Contains(s, msg) {
+			t.Errorf("Expected panic with %q, got %v", msg, r)
+		}
+	}()
+	fn()
+}
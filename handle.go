@@ -0,0 +1,200 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrBadHandle is returned for the Handle reserved by HandleTree (maximum uint32 value).
+var ErrBadHandle = errors.New("Handle out of range")
+
+// Handle is an index of a value kept by the caller (e.g. in a slice), stored by HandleTree instead of the value.
+type Handle uint32
+
+// hnode is a node of HandleTree, links are indexes into the node arena (0 is no link, root sits at 0)
+// and handle is stored incremented by one (0 is no value), so nodes have no pointers for GC to scan.
+type hnode struct {
+	left, right, parent uint32
+	handle              uint32
+}
+
+// HandleTree implements radix tree for working with IP/mask storing Handle per IP/mask. Values live in a
+// caller managed arena, the tree has no pointers and interfaces inside which makes it nearly free for the GC.
+// Thread safety is not guaranteed unless created with safe set.
+type HandleTree struct {
+	nodes                   []hnode
+	free                    uint32
+	countNodes, countValued int
+	safe                    bool
+	sync.RWMutex
+}
+
+// NewHandleTree creates HandleTree.
+func NewHandleTree(safe bool) *HandleTree {
+	return &HandleTree{nodes: make([]hnode, 1, 200), countNodes: 1, safe: safe}
+}
+
+// GetStats get tree stats count of nodes and valued nodes.
+func (tree *HandleTree) GetStats() (treeNodes, valuetreeNodes int) {
+	return tree.countNodes, tree.countValued
+}
+
+// AddCIDR adds handle associated with IP/mask to the tree. Will return error for invalid CIDR or if handle already exists.
+func (tree *HandleTree) AddCIDR(cidr string, h Handle) error {
+	return tree.insertCIDR(cidr, h, false)
+}
+
+// SetCIDR adds handle associated with IP/mask to the tree. Will return error for invalid CIDR.
+func (tree *HandleTree) SetCIDR(cidr string, h Handle) error {
+	return tree.insertCIDR(cidr, h, true)
+}
+
+func (tree *HandleTree) insertCIDR(cidr string, h Handle, overwrite bool) error {
+	if uint32(h) == ^uint32(0) {
+		return ErrBadHandle
+	}
+	key, bits, err := cidrKey(cidr)
+	if err != nil {
+		return err
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	var n uint32
+	for i := 0; i < bits; i++ {
+		next := tree.nodes[n].child(keyBit(key, i))
+		if next == 0 {
+			next = tree.newnode(n)
+			tree.nodes[n].setChild(keyBit(key, i), next)
+		}
+		n = next
+	}
+	if tree.nodes[n].handle != 0 {
+		if !overwrite {
+			return ErrNodeBusy
+		}
+	} else {
+		tree.countValued++
+	}
+	tree.nodes[n].handle = uint32(h) + 1
+	return nil
+}
+
+// DeleteCIDR removes handle associated with IP/mask from the tree.
+func (tree *HandleTree) DeleteCIDR(cidr string) error {
+	key, bits, err := cidrKey(cidr)
+	if err != nil {
+		return err
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	var n uint32
+	for i := 0; i < bits; i++ {
+		if n = tree.nodes[n].child(keyBit(key, i)); n == 0 {
+			return ErrNotFound
+		}
+	}
+	if tree.nodes[n].handle == 0 {
+		return ErrNotFound
+	}
+	tree.nodes[n].handle = 0
+	tree.countValued--
+
+	// release nodes left without handle and children, but keep the root node
+	for n != 0 && tree.nodes[n].left == 0 && tree.nodes[n].right == 0 && tree.nodes[n].handle == 0 {
+		parent := tree.nodes[n].parent
+		if tree.nodes[parent].left == n {
+			tree.nodes[parent].left = 0
+		} else {
+			tree.nodes[parent].right = 0
+		}
+		tree.nodes[n] = hnode{right: tree.free}
+		tree.free = n
+		tree.countNodes--
+		n = parent
+	}
+	return nil
+}
+
+// FindCIDR traverses tree to proper Node and returns previously saved handle in longest covered IP.
+func (tree *HandleTree) FindCIDR(cidr string) (Handle, bool, error) {
+	key, bits, err := cidrKey(cidr)
+	if err != nil {
+		return 0, false, err
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	var n, found uint32
+	for i := 0; ; i++ {
+		if h := tree.nodes[n].handle; h != 0 {
+			found = h
+		}
+		if i == bits {
+			break
+		}
+		if n = tree.nodes[n].child(keyBit(key, i)); n == 0 {
+			break
+		}
+	}
+	if found == 0 {
+		return 0, false, nil
+	}
+	return Handle(found - 1), true, nil
+}
+
+// FindExactCIDR traverses tree to proper Node and returns previously saved handle for an exact match.
+func (tree *HandleTree) FindExactCIDR(cidr string) (Handle, error) {
+	key, bits, err := cidrKey(cidr)
+	if err != nil {
+		return 0, err
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	var n uint32
+	for i := 0; i < bits; i++ {
+		if n = tree.nodes[n].child(keyBit(key, i)); n == 0 {
+			return 0, ErrNotFound
+		}
+	}
+	if tree.nodes[n].handle == 0 {
+		return 0, ErrNotFound
+	}
+	return Handle(tree.nodes[n].handle - 1), nil
+}
+
+func (tree *HandleTree) newnode(parent uint32) uint32 {
+	tree.countNodes++
+	if n := tree.free; n != 0 {
+		tree.free = tree.nodes[n].right
+		tree.nodes[n] = hnode{parent: parent}
+		return n
+	}
+	tree.nodes = append(tree.nodes, hnode{parent: parent})
+	return uint32(len(tree.nodes) - 1)
+}
+
+func (n *hnode) child(right bool) uint32 {
+	if right {
+		return n.right
+	}
+	return n.left
+}
+
+func (n *hnode) setChild(right bool, c uint32) {
+	if right {
+		n.right = c
+	} else {
+		n.left = c
+	}
+}
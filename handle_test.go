@@ -0,0 +1,84 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"testing"
+)
+
+func TestHandleTree(t *testing.T) {
+	values := []string{"zero", "net10", "net10-1", "dead"}
+	tr := NewHandleTree(false)
+	for cidr, h := range map[string]Handle{
+		"0.0.0.0/0":   0,
+		"10.0.0.0/8":  1,
+		"10.1.0.0/16": 2,
+		"dead::/16":   3,
+	} {
+		if err := tr.AddCIDR(cidr, h); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := tr.AddCIDR("10.0.0.0/8", 2); err != ErrNodeBusy {
+		t.Errorf("Expected ErrNodeBusy, got %v", err)
+	}
+	if err := tr.AddCIDR("11.0.0.0/8", Handle(^uint32(0))); err != ErrBadHandle {
+		t.Errorf("Expected ErrBadHandle, got %v", err)
+	}
+	for ip, exp := range map[string]string{
+		"10.1.2.3":    "net10-1",
+		"10.2.2.3":    "net10",
+		"11.2.2.3":    "zero",
+		"dead::1":     "dead",
+		"10.1.0.0/16": "net10-1",
+	} {
+		h, ok, err := tr.FindCIDR(ip)
+		if err != nil || !ok {
+			t.Errorf("Expected handle for %s, got %v %v", ip, ok, err)
+		} else if values[h] != exp {
+			t.Errorf("Wrong value for %s, expected %s, got %s", ip, exp, values[h])
+		}
+	}
+	// IPv4 default route sits at the root shared with IPv6
+	if h, ok, _ := tr.FindCIDR("beef::1"); !ok || h != 0 {
+		t.Errorf("Expected handle 0 for beef::1, got %v %v", h, ok)
+	}
+	if h, err := tr.FindExactCIDR("10.1.0.0/16"); err != nil || h != 2 {
+		t.Errorf("Wrong exact handle, got %v %v", h, err)
+	}
+	if _, err := tr.FindExactCIDR("10.1.0.0/17"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	if err := tr.SetCIDR("10.0.0.0/8", 0); err != nil {
+		t.Error(err)
+	}
+	if h, _, _ := tr.FindCIDR("10.2.2.3"); h != 0 {
+		t.Errorf("Wrong handle after set, expected 0, got %d", h)
+	}
+
+	nodes, valued := tr.GetStats()
+	if valued != 4 {
+		t.Errorf("Wrong valued nodes, expected 4, got %d", valued)
+	}
+	if err := tr.DeleteCIDR("10.1.0.0/16"); err != nil {
+		t.Error(err)
+	}
+	if err := tr.DeleteCIDR("10.1.0.0/16"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if n, v := tr.GetStats(); n != nodes-8 || v != 3 {
+		t.Errorf("Wrong stats after delete, got %d %d", n, v)
+	}
+	if h, _, _ := tr.FindCIDR("10.1.2.3"); h != 0 {
+		t.Errorf("Wrong handle after delete, expected 0, got %d", h)
+	}
+
+	// freed nodes are reused
+	tr.AddCIDR("10.1.0.0/16", 2)
+	if n, _ := tr.GetStats(); n != nodes || len(tr.nodes) != nodes {
+		t.Errorf("Freed nodes were not reused, %d nodes in %d slots", n, len(tr.nodes))
+	}
+}
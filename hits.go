@@ -0,0 +1,70 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"sync/atomic"
+)
+
+// WithHitCounting makes the tree count lookup hits of every value: each longest match (and every match of
+// FindAllCIDR) counts one hit of the matched IP/mask. Exact lookups are not counted. See HitStats.
+func WithHitCounting() Option {
+	return func(tree *Tree) {
+		tree.countHits = true
+	}
+}
+
+// HitStat is number of lookup hits of the value stored for the IP/mask.
+type HitStat struct {
+	Net   net.IPNet
+	Value interface{}
+	Hits  uint64
+}
+
+// HitStats returns hit counts of all values in the tree in walk order, nil if the tree does not count hits.
+func (tree *Tree) HitStats() []HitStat {
+	if !tree.countHits {
+		return nil
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	var ret []HitStat
+	tree.walkNodes(OptWalkIPAuto, func(cidr net.IPNet, n *node) (bool, error) {
+		var hits uint64
+		if n.meta != nil {
+			hits = atomic.LoadUint64(&n.meta.hits)
+		}
+		ret = append(ret, HitStat{Net: cidr, Value: n.value, Hits: hits})
+		return true, nil
+	})
+	return ret
+}
+
+// ResetHits sets hit counts of all values in the tree to zero.
+func (tree *Tree) ResetHits() {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	tree.walkNodes(OptWalkIPAuto|optWalkExpired, func(cidr net.IPNet, n *node) (bool, error) {
+		if n.meta != nil {
+			atomic.StoreUint64(&n.meta.hits, 0)
+		}
+		return true, nil
+	})
+}
+
+// hit counts lookup hit (and use by NewTreeLRU) of the node, lookups may run concurrently under the read lock.
+func (tree *Tree) hit(n *node) {
+	if tree.countHits && n.meta != nil {
+		atomic.AddUint64(&n.meta.hits, 1)
+	}
+	if tree.lru != nil {
+		tree.lru.use(n)
+	}
+}
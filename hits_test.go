@@ -0,0 +1,55 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestHitStats(t *testing.T) {
+	tr := NewTree(WithHitCounting(), WithLocking(true))
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("2001:db8::/48", 3)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				tr.FindCIDR("10.1.2.3")
+			}
+		}()
+	}
+	wg.Wait()
+	tr.FindCIDR("10.2.0.1")
+	tr.FindCIDR("2001:db8::1")
+	tr.FindCIDR("11.0.0.1")
+	tr.FindExactCIDR("10.0.0.0/8")
+
+	expected := map[string]uint64{"10.0.0.0/8": 1, "10.1.0.0/16": 100, "2001:db8::/48": 1}
+	stats := tr.HitStats()
+	if len(stats) != len(expected) {
+		t.Errorf("Wrong stats count, expected %d, got %v", len(expected), stats)
+	}
+	for _, s := range stats {
+		if s.Hits != expected[s.Net.String()] {
+			t.Errorf("Wrong hits for %s, expected %d, got %d", s.Net.String(), expected[s.Net.String()], s.Hits)
+		}
+	}
+
+	tr.ResetHits()
+	for _, s := range tr.HitStats() {
+		if s.Hits != 0 {
+			t.Errorf("Wrong hits for %s after reset, expected 0, got %d", s.Net.String(), s.Hits)
+		}
+	}
+
+	if stats := NewTree().HitStats(); stats != nil {
+		t.Errorf("Expected no stats without hit counting, got %v", stats)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+)
+
+// WithStrictHostBits sets whether the tree rejects IP/masks with bits set beyond the mask (e.g. 10.0.0.5/24) when
+// values are added, set or deleted, with ErrBadIP. By default such IP/masks are normalized to their network
+// (10.0.0.0/24) in both address families, see WithOnNormalize.
+func WithStrictHostBits(strict bool) Option {
+	return func(tree *Tree) {
+		tree.strict = strict
+	}
+}
+
+// WithOnNormalize makes the tree call fn with every IP/mask normalized to its network when values are added, set
+// or deleted (not for strict tree, which rejects them). Fn is called under the lock of the tree.
+func WithOnNormalize(fn func(cidr string, network net.IPNet)) Option {
+	return func(tree *Tree) {
+		tree.onNormalize = fn
+	}
+}
+
+// NormalizeCIDR returns the network of the IP/mask (address without mask is the host IP/mask) the way the tree
+// stores it, and whether bits beyond the mask were set in the cidr.
+func NormalizeCIDR(cidr string) (network net.IPNet, hostBitsSet bool, err error) {
+	return hostBits([]byte(cidr), isIPv4([]byte(cidr)))
+}
+
+// hostBits normalizes the cidr, parsed as IPv4 one if v4.
+func hostBits(cidr []byte, v4 bool) (net.IPNet, bool, error) {
+	if v4 {
+		ip, mask, err := parsecidr4(cidr)
+		if err != nil {
+			return net.IPNet{}, false, err
+		}
+		network := net.IPNet{IP: make(net.IP, net.IPv4len), Mask: make(net.IPMask, net.IPv4len)}
+		binary.BigEndian.PutUint32(network.IP, ip&mask)
+		binary.BigEndian.PutUint32(network.Mask, mask)
+		return network, ip&^mask != 0, nil
+	}
+	if bytes.IndexByte(cidr, '/') < 0 {
+		ip, mask, err := parsecidr6(cidr)
+		if err != nil {
+			return net.IPNet{}, false, err
+		}
+		return net.IPNet{IP: ip, Mask: mask}, false, nil
+	}
+	ip, network, err := net.ParseCIDR(string(stripZone(cidr)))
+	if err != nil {
+		return net.IPNet{}, false, badIP(cidr, "malformed IPv6 address or mask")
+	}
+	return *network, !ip.Equal(network.IP), nil
+}
@@ -0,0 +1,65 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestNormalizeCIDR(t *testing.T) {
+	for cidr, expected := range map[string]struct {
+		network  string
+		hostBits bool
+	}{
+		"10.0.0.5/24":        {"10.0.0.0/24", true},
+		"10.0.0.0/24":        {"10.0.0.0/24", false},
+		"10.0.0.5":           {"10.0.0.5/32", false},
+		"2001:db8::5/64":     {"2001:db8::/64", true},
+		"2001:db8::/64":      {"2001:db8::/64", false},
+		"2001:db8::5":        {"2001:db8::5/128", false},
+		"::ffff:1.2.3.4/120": {"1.2.3.0/24", true},
+	} {
+		network, hostBits, err := NormalizeCIDR(cidr)
+		if err != nil {
+			t.Error(err)
+		}
+		if network.String() != expected.network || hostBits != expected.hostBits {
+			t.Errorf("Wrong normalization of %s, expected %v %v, got %v %v", cidr, expected.network, expected.hostBits, network.String(), hostBits)
+		}
+	}
+	if _, _, err := NormalizeCIDR("10.0.0.5/33"); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Wrong error, expected %v, got %v", ErrBadIP, err)
+	}
+}
+
+func TestHostBits(t *testing.T) {
+	normalized := map[string]string{}
+	tr := NewTree(WithOnNormalize(func(cidr string, network net.IPNet) {
+		normalized[cidr] = network.String()
+	}))
+	for _, cidr := range []string{"10.0.0.5/24", "10.1.0.0/16", "2001:db8::5/64"} {
+		if err := tr.AddCIDR(cidr, 1); err != nil {
+			t.Error(err)
+		}
+	}
+	if len(normalized) != 2 || normalized["10.0.0.5/24"] != "10.0.0.0/24" || normalized["2001:db8::5/64"] != "2001:db8::/64" {
+		t.Errorf("Wrong normalizations, got %v", normalized)
+	}
+	if inf, err := tr.FindExactCIDR("2001:db8::/64"); err != nil || inf != 1 {
+		t.Errorf("Expected normalized 2001:db8::/64, got %v %v", inf, err)
+	}
+
+	tr = NewTree(WithStrictHostBits(true))
+	for _, cidr := range []string{"10.0.0.5/24", "2001:db8::5/64"} {
+		if err := tr.SetCIDR(cidr, 1); !errors.Is(err, ErrBadIP) {
+			t.Errorf("Wrong error for %s, expected %v, got %v", cidr, ErrBadIP, err)
+		}
+	}
+	if err := NewTree(WithStrictCIDR(), WithStrictHostBits(false)).AddCIDR("10.0.0.5/24", 1); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net"
+)
+
+// ErrBadPrefixLen is returned for prefix length not fitting into the IP/mask it is requested in.
+var ErrBadPrefixLen = errors.New("Bad prefix length")
+
+// FindFreeBlock returns the first (lowest) IP/mask of prefixLen inside within such that no address of it is covered
+// by a value of the tree. Will return ErrBadPrefixLen if prefixLen is shorter than mask of within or longer than
+// the address and ErrNotFound if there is no free IP/mask of that size.
+func (tree *Tree) FindFreeBlock(within string, prefixLen int) (net.IPNet, error) {
+	e, err := tree.parseEntry([]byte(within))
+	if err != nil {
+		return net.IPNet{}, err
+	}
+	key, bits := tree.entryKey(&e)
+	// key bits of the address, prefix lengths are counted from where the address starts in the key
+	size, start := net.IPv6len*8, 0
+	if e.v4 {
+		size, start = net.IPv4len*8, bits-masklen32(e.mk32)
+	}
+	if prefixLen < bits-start || prefixLen > size {
+		return net.IPNet{}, ErrBadPrefixLen
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	f := freeFinder{now: tree.expiryNow(), target: start + prefixLen, maxbits: start + size}
+	b := keyBlock(key, bits)
+	n := tree.root
+	for i := 0; n != nil; i++ {
+		if n.value != nil && !expired(n, f.now) {
+			return net.IPNet{}, ErrNotFound
+		}
+		if i == bits {
+			break
+		}
+		if keyBit(key, i) {
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	if !f.find(n, &b) {
+		return net.IPNet{}, ErrNotFound
+	}
+	return tree.blockNet(b, e.v4), nil
+}
+
+type freeFinder struct {
+	now     int64
+	target  int
+	maxbits int
+}
+
+// find looks for the first free block of target bits in block b of node n (nil if the block has no nodes),
+// the block is turned into the found one.
+func (f *freeFinder) find(n *node, b *block) bool {
+	if n == nil {
+		b.bits = f.target
+		return true
+	}
+	if n.value != nil && !expired(n, f.now) {
+		return false
+	}
+	if b.bits == f.target {
+		return !f.used(n, b.bits)
+	}
+	for _, right := range []bool{false, true} {
+		child := n.left
+		if right {
+			child = n.right
+		}
+		cb := *b
+		setKeyBit(&cb.ip, cb.bits, right)
+		cb.bits++
+		if f.find(child, &cb) {
+			*b = cb
+			return true
+		}
+	}
+	return false
+}
+
+// used tells whether the subtree of node n at depth has values down to maxbits (deeper nodes belong to IPv6
+// when IPv4 takes first 32 bits of the key).
+func (f *freeFinder) used(n *node, depth int) bool {
+	if n == nil || depth > f.maxbits {
+		return false
+	}
+	if n.value != nil && !expired(n, f.now) {
+		return true
+	}
+	return f.used(n.left, depth+1) || f.used(n.right, depth+1)
+}
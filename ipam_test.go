@@ -0,0 +1,44 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"testing"
+)
+
+func TestFindFreeBlock(t *testing.T) {
+	tr := NewTree()
+	for _, cidr := range []string{"10.0.0.0/24", "10.0.1.0/26", "10.0.1.128/25", "10.0.3.0/24", "10.1.0.0/16", "2001:db8::/48"} {
+		if err := tr.AddCIDR(cidr, cidr); err != nil {
+			t.Error(err)
+		}
+	}
+	for _, tc := range []struct {
+		within string
+		size   int
+		free   string
+		err    error
+	}{
+		{"10.0.0.0/16", 24, "10.0.2.0/24", nil},
+		{"10.0.0.0/16", 26, "10.0.1.64/26", nil},
+		{"10.0.0.0/16", 23, "10.0.4.0/23", nil},
+		{"10.0.0.0/15", 16, "", ErrNotFound},
+		{"10.0.0.0/8", 16, "10.2.0.0/16", nil},
+		{"10.0.0.0/8", 8, "", ErrNotFound},
+		{"10.0.1.0/25", 26, "10.0.1.64/26", nil},
+		{"10.1.2.0/24", 28, "", ErrNotFound},
+		{"10.0.0.0/16", 15, "", ErrBadPrefixLen},
+		{"10.0.0.0/16", 33, "", ErrBadPrefixLen},
+		{"192.168.0.0/16", 30, "192.168.0.0/30", nil},
+		{"2001:db8::/32", 48, "2001:db8:1::/48", nil},
+	} {
+		n, err := tr.FindFreeBlock(tc.within, tc.size)
+		if err != tc.err {
+			t.Errorf("Wrong error for /%d in %s, expected %v, got %v", tc.size, tc.within, tc.err, err)
+		} else if err == nil && n.String() != tc.free {
+			t.Errorf("Wrong free block for /%d in %s, expected %s, got %s", tc.size, tc.within, tc.free, n.String())
+		}
+	}
+}
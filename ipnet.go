@@ -0,0 +1,123 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+)
+
+// AddNet adds value associated with the IP/mask to the tree. Will return error for invalid IP/mask or if value already exists.
+func (tree *Tree) AddNet(n net.IPNet, val interface{}) error {
+	return tree.insertNet(n, val, false)
+}
+
+// SetNet adds value associated with the IP/mask to the tree. Will return error for invalid IP/mask.
+func (tree *Tree) SetNet(n net.IPNet, val interface{}) error {
+	return tree.insertNet(n, val, true)
+}
+
+func (tree *Tree) insertNet(n net.IPNet, val interface{}, overwrite bool) error {
+	e, err := net2entry(n)
+	if err != nil {
+		return err
+	}
+	e.value = val
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	return tree.insertEntry(&e, overwrite)
+}
+
+// DeleteNet removes value associated with the IP/mask from the tree.
+func (tree *Tree) DeleteNet(n net.IPNet) error {
+	return tree.deleteNet(n, false)
+}
+
+// DeleteWholeRangeNet removes all values associated with IPs in the entire subnet specified by the IP/mask.
+func (tree *Tree) DeleteWholeRangeNet(n net.IPNet) error {
+	return tree.deleteNet(n, true)
+}
+
+func (tree *Tree) deleteNet(n net.IPNet, wholeRange bool) error {
+	e, err := net2entry(n)
+	if err != nil {
+		return err
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	return tree.deleteEntry(&e, wholeRange)
+}
+
+// FindIP traverses tree to proper Node and returns previously saved information in longest covered IP/mask of the address.
+func (tree *Tree) FindIP(ip net.IP) (interface{}, error) {
+	e, err := ip2entry(ip)
+	if err != nil {
+		return nil, err
+	}
+	return tree.findNetEntry(&e, findBest), nil
+}
+
+// FindIPNet is FindIP returning also the IP/mask the information was saved for, see FindCIDRNet.
+func (tree *Tree) FindIPNet(ip net.IP) (interface{}, net.IPNet, error) {
+	e, err := ip2entry(ip)
+	if err != nil {
+		return nil, net.IPNet{}, err
+	}
+	key, bits := tree.entryKey(&e)
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	n, depth := tree.bestNode(key, bits)
+	if n == nil {
+		return nil, net.IPNet{}, nil
+	}
+	return n.value, tree.blockNet(keyBlock(key, depth), e.v4), nil
+}
+
+// FindNet traverses tree to proper Node and returns previously saved information in longest covered IP/mask.
+func (tree *Tree) FindNet(n net.IPNet) (interface{}, error) {
+	e, err := net2entry(n)
+	if err != nil {
+		return nil, err
+	}
+	return tree.findNetEntry(&e, findBest), nil
+}
+
+// FindExactNet traverses tree to proper Node and returns previously saved information for an exact match.
+func (tree *Tree) FindExactNet(n net.IPNet) (interface{}, error) {
+	e, err := net2entry(n)
+	if err != nil {
+		return nil, err
+	}
+	if value := tree.findNetEntry(&e, findExact); value != nil {
+		return value, nil
+	}
+	return nil, ErrNotFound
+}
+
+// FindAllNet traverses tree to proper Node and returns previously saved information in all covering IP/masks.
+func (tree *Tree) FindAllNet(n net.IPNet) ([]interface{}, error) {
+	e, err := net2entry(n)
+	if err != nil {
+		return nil, err
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	return tree.findAllEntry(&e), nil
+}
+
+func (tree *Tree) findNetEntry(e *prefixEntry, what findWhat) interface{} {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	return tree.findEntry(e, what)
+}
@@ -0,0 +1,94 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func mustNet(cidr string) net.IPNet {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return *n
+}
+
+func TestIPNet(t *testing.T) {
+	tr := NewTree()
+	for cidr, v := range map[string]int{
+		"10.0.0.0/8":          1,
+		"10.1.0.0/16":         2,
+		"::ffff:11.0.0.0/104": 3,
+		"dead::/16":           4,
+		"dead:beef::/32":      5,
+	} {
+		if err := tr.AddNet(mustNet(cidr), v); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := tr.AddNet(mustNet("10.0.0.0/8"), 6); err != ErrNodeBusy {
+		t.Errorf("Wrong error, expected %v, got %v", ErrNodeBusy, err)
+	}
+	if err := tr.AddNet(net.IPNet{}, 6); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Wrong error, expected %v, got %v", ErrBadIP, err)
+	}
+	if err := tr.SetNet(mustNet("10.0.0.0/8"), 7); err != nil {
+		t.Error(err)
+	}
+
+	for ip, expected := range map[string]interface{}{
+		"10.2.0.1":       7,
+		"10.1.0.1":       2,
+		"11.0.0.1":       3,
+		"dead::1":        4,
+		"dead:beef::abc": 5,
+		"beef::1":        nil,
+	} {
+		inf, err := tr.FindIP(net.ParseIP(ip))
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != expected {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, expected, inf)
+		}
+	}
+	if _, err := tr.FindIP(net.IP{1, 2}); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Wrong error, expected %v, got %v", ErrBadIP, err)
+	}
+	inf, n, err := tr.FindIPNet(net.ParseIP("10.1.2.3"))
+	if err != nil || inf != 2 || n.String() != "10.1.0.0/16" {
+		t.Errorf("Wrong match, expected 2 in 10.1.0.0/16, got %v in %v, %v", inf, n.String(), err)
+	}
+
+	if inf, err = tr.FindNet(mustNet("10.1.2.0/24")); err != nil || inf != 2 {
+		t.Errorf("Wrong value, expected 2, got %v %v", inf, err)
+	}
+	if inf, err = tr.FindExactNet(mustNet("10.1.2.0/24")); err != ErrNotFound {
+		t.Errorf("Wrong result, expected %v, got %v %v", ErrNotFound, inf, err)
+	}
+	if inf, err = tr.FindExactNet(mustNet("dead::/16")); err != nil || inf != 4 {
+		t.Errorf("Wrong value, expected 4, got %v %v", inf, err)
+	}
+	all, err := tr.FindAllNet(mustNet("10.1.2.0/24"))
+	if err != nil || len(all) != 2 || all[0] != 7 || all[1] != 2 {
+		t.Errorf("Wrong values, expected [7 2], got %v %v", all, err)
+	}
+
+	if err = tr.DeleteNet(mustNet("10.1.0.0/16")); err != nil {
+		t.Error(err)
+	}
+	if err = tr.DeleteWholeRangeNet(mustNet("dead::/16")); err != nil {
+		t.Error(err)
+	}
+	if inf, _ = tr.FindIP(net.ParseIP("dead:beef::1")); inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+	if inf, _ = tr.FindIP(net.ParseIP("10.1.0.1")); inf != 7 {
+		t.Errorf("Wrong value, expected 7, got %v", inf)
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"net"
+)
+
+// ErrBadRange is returned for IP range with addresses of different families or ending before it starts.
+var ErrBadRange = errors.New("Bad IP range")
+
+// AddRange adds value associated with every address of the inclusive range startIP-endIP, the range is split
+// into the minimal set of IP/masks. Will return error for invalid range (nothing is added then) or if value
+// already exists for some of the IP/masks (IP/masks ordered before it in address order are added).
+func (tree *Tree) AddRange(startIP, endIP string, val interface{}) error {
+	entries, err := parseRange(startIP, endIP)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		entries[i].value = val
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	return tree.insertEntries(entries, false)
+}
+
+// RangeCIDRs returns the minimal set of IP/masks covering exactly the inclusive range startIP-endIP, in address order.
+func RangeCIDRs(startIP, endIP string) ([]net.IPNet, error) {
+	entries, err := parseRange(startIP, endIP)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]net.IPNet, len(entries))
+	for i := range entries {
+		key, bits := entries[i].key()
+		ret[i] = keyBlock(key, bits).ipnet(entries[i].v4)
+	}
+	return ret, nil
+}
+
+func parseRange(startIP, endIP string) ([]prefixEntry, error) {
+	start := net.ParseIP(startIP)
+	if start == nil {
+		return nil, badIP([]byte(startIP), "malformed IP address")
+	}
+	end := net.ParseIP(endIP)
+	if end == nil {
+		return nil, badIP([]byte(endIP), "malformed IP address")
+	}
+	return rangeEntries(start, end)
+}
+
+// rangeEntries splits the inclusive range into largest aligned blocks, address is kept as 128 bit hi:lo number.
+func rangeEntries(start, end net.IP) ([]prefixEntry, error) {
+	maxbits := net.IPv6len * 8
+	s4, e4 := start.To4(), end.To4()
+	if (s4 == nil) != (e4 == nil) {
+		return nil, ErrBadRange
+	}
+	var shi, slo, ehi, elo uint64
+	if s4 != nil {
+		maxbits = net.IPv4len * 8
+		slo, elo = uint64(binary.BigEndian.Uint32(s4)), uint64(binary.BigEndian.Uint32(e4))
+	} else {
+		shi, slo = binary.BigEndian.Uint64(start[:8]), binary.BigEndian.Uint64(start[8:])
+		ehi, elo = binary.BigEndian.Uint64(end[:8]), binary.BigEndian.Uint64(end[8:])
+	}
+	if shi > ehi || shi == ehi && slo > elo {
+		return nil, ErrBadRange
+	}
+
+	var ret []prefixEntry
+	for {
+		// block size is limited by alignment of the start and by the remaining length of the range
+		size := trailingZeros128(shi, slo)
+		if size > maxbits {
+			size = maxbits
+		}
+		rhi, rlo := ehi-shi, elo-slo
+		if elo < slo {
+			rhi--
+		}
+		for size > 0 && !fits128(rhi, rlo, size) {
+			size--
+		}
+		if s4 != nil {
+			ret = append(ret, prefixEntry{v4: true, ip32: uint32(slo), mk32: uint32(0xffffffff) << size})
+		} else {
+			ip := make(net.IP, net.IPv6len)
+			binary.BigEndian.PutUint64(ip[:8], shi)
+			binary.BigEndian.PutUint64(ip[8:], slo)
+			ret = append(ret, prefixEntry{ip: ip, mask: net.CIDRMask(maxbits-size, maxbits)})
+		}
+
+		// stop after the block ending the range, otherwise start the next block right after this one
+		lastHi, lastLo := shi, slo
+		if size >= 64 {
+			lastHi |= uint64(1)<<(size-64) - 1
+			lastLo = ^uint64(0)
+		} else {
+			lastLo |= uint64(1)<<size - 1
+		}
+		if lastHi == ehi && lastLo == elo {
+			return ret, nil
+		}
+		slo = lastLo + 1
+		shi = lastHi
+		if slo == 0 {
+			shi++
+		}
+	}
+}
+
+// trailingZeros128 returns number of trailing zero bits of hi:lo, 128 for zero.
+func trailingZeros128(hi, lo uint64) int {
+	if lo != 0 {
+		return bits.TrailingZeros64(lo)
+	}
+	return 64 + bits.TrailingZeros64(hi)
+}
+
+// fits128 tells whether block of 2^size addresses fits into range with rhi:rlo+1 addresses.
+func fits128(rhi, rlo uint64, size int) bool {
+	// compare rhi:rlo with 2^size-1, shifts by 64 give zero so size 128 works too
+	if size >= 64 {
+		h := uint64(1)<<(size-64) - 1
+		return rhi > h || rhi == h && rlo == ^uint64(0)
+	}
+	return rhi > 0 || rlo >= uint64(1)<<size-1
+}
+
+// ExcludeCIDR punches the hole into the value of outer IP/mask: the value is removed from outer and added to the
+// IP/masks covering the rest of outer around the hole (e.g. 10.0.0.0/8 minus 10.1.0.0/16). IP/masks already having
+// value keep it. Will return ErrNotFound if outer has no value and ErrBadRange if the hole is not inside outer.
+func (tree *Tree) ExcludeCIDR(outer, hole string) error {
+	okey, obits, err := tree.cidrKey(outer)
+	if err != nil {
+		return err
+	}
+	hkey, hbits, err := tree.cidrKey(hole)
+	if err != nil {
+		return err
+	}
+	if hbits < obits || commonBits(okey, hkey, obits) != obits {
+		return ErrBadRange
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	val, err := tree.findExactCIDRb([]byte(outer))
+	if err != nil {
+		return err
+	}
+	if err = tree.delete(okey[:], net.CIDRMask(obits, net.IPv6len*8), false); err != nil {
+		return err
+	}
+	for depth := obits; depth < hbits; depth++ {
+		sibling := keyBlock(hkey, depth+1)
+		setKeyBit(&sibling.ip, depth, !keyBit(hkey, depth))
+		err = tree.insert(sibling.ip[:], net.CIDRMask(depth+1, net.IPv6len*8), val, false)
+		if err != nil && err != ErrNodeBusy {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRangeCIDRs(t *testing.T) {
+	for _, tc := range []struct {
+		start, end string
+		expected   string
+	}{
+		{"10.0.0.0", "10.0.0.255", "10.0.0.0/24"},
+		{"10.0.0.1", "10.0.0.6", "10.0.0.1/32,10.0.0.2/31,10.0.0.4/31,10.0.0.6/32"},
+		{"192.168.0.0", "192.168.2.127", "192.168.0.0/23,192.168.2.0/25"},
+		{"1.2.3.4", "1.2.3.4", "1.2.3.4/32"},
+		{"0.0.0.0", "255.255.255.255", "0.0.0.0/0"},
+		{"255.255.255.254", "255.255.255.255", "255.255.255.254/31"},
+		{"2001:db8::", "2001:db8::ffff", "2001:db8::/112"},
+		{"2001:db8::ffff:ffff:ffff:ffff", "2001:db8:0:1::1", "2001:db8::ffff:ffff:ffff:ffff/128,2001:db8:0:1::/127"},
+		{"::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "::/0"},
+		{"8000::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "8000::/1"},
+	} {
+		nets, err := RangeCIDRs(tc.start, tc.end)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		var got []string
+		for _, n := range nets {
+			got = append(got, n.String())
+		}
+		if strings.Join(got, ",") != tc.expected {
+			t.Errorf("Wrong CIDRs for %s-%s, expected %s, got %v", tc.start, tc.end, tc.expected, got)
+		}
+	}
+
+	if _, err := RangeCIDRs("10.0.0.2", "10.0.0.1"); err != ErrBadRange {
+		t.Errorf("Expected ErrBadRange, got %v", err)
+	}
+	if _, err := RangeCIDRs("10.0.0.1", "2001:db8::1"); err != ErrBadRange {
+		t.Errorf("Expected ErrBadRange, got %v", err)
+	}
+	if _, err := RangeCIDRs("10.0.0.1", "bad"); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Expected ErrBadIP, got %v", err)
+	}
+}
+
+func TestAddRange(t *testing.T) {
+	tr := NewTree()
+	if err := tr.AddRange("10.0.0.1", "10.0.0.6", 1); err != nil {
+		t.Error(err)
+	}
+	if _, values, _, _ := tr.GetStats(); values != 4 {
+		t.Errorf("Wrong valued count, expected 4, got %d", values)
+	}
+	for ip, expected := range map[string]interface{}{"10.0.0.0": nil, "10.0.0.1": 1, "10.0.0.5": 1, "10.0.0.6": 1, "10.0.0.7": nil} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != expected {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, expected, inf)
+		}
+	}
+	if err := tr.AddRange("10.0.0.4", "10.0.0.5", 2); err != ErrNodeBusy {
+		t.Errorf("Expected ErrNodeBusy, got %v", err)
+	}
+}
+
+func TestExcludeCIDR(t *testing.T) {
+	tr := NewTree()
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.0.0.0/16", 2)
+	tr.AddCIDR("10.1.2.0/24", 3)
+	if err := tr.ExcludeCIDR("10.0.0.0/8", "10.1.0.0/16"); err != nil {
+		t.Error(err)
+	}
+	for ip, expected := range map[string]interface{}{
+		"10.0.0.1":       2,
+		"10.1.0.1":       nil,
+		"10.1.2.1":       3,
+		"10.2.0.1":       1,
+		"10.128.0.1":     1,
+		"10.255.255.255": 1,
+		"11.0.0.1":       nil,
+	} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != expected {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, expected, inf)
+		}
+	}
+	if _, err := tr.FindExactCIDR("10.0.0.0/8"); err != ErrNotFound {
+		t.Errorf("Expected outer value to be removed, got %v", err)
+	}
+	// 10.0.0.0/16 kept its value, /15 /14 /13 /12 /11 /10 /9 were added
+	if _, values, _, _ := tr.GetStats(); values != 9 {
+		t.Errorf("Wrong valued count, expected 9, got %d", values)
+	}
+
+	if err := tr.ExcludeCIDR("10.0.0.0/8", "10.1.0.0/16"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := tr.ExcludeCIDR("10.2.0.0/15", "11.0.0.0/16"); err != ErrBadRange {
+		t.Errorf("Expected ErrBadRange, got %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+)
+
+// IPSet is a set of IPv4 and IPv6 addresses backed by the tree. It is kept as the smallest set of disjoint
+// IP/masks: adding splits nothing and drops IP/masks inside the added one, adjacent IP/masks are merged
+// (two /25s become the /24) and removing splits IP/masks around the removed one. IPv4 and IPv6 addresses never
+// collide (the tree is WithIPv4Mapped). IPSet is not safe for concurrent use.
+type IPSet struct {
+	tree *Tree
+}
+
+// ipsetMember is the value of IP/masks of the set.
+const ipsetMember = true
+
+// NewIPSet creates empty IPSet.
+func NewIPSet() *IPSet {
+	return &IPSet{tree: NewTree(WithIPv4Mapped(), WithOnlineAggregation(nil))}
+}
+
+// Add adds all addresses of the cidr to the set.
+func (s *IPSet) Add(cidr string) error {
+	key, bits, err := s.tree.cidrKey(cidr)
+	if err != nil {
+		return err
+	}
+	if n, _ := s.tree.bestNode(key, bits); n != nil {
+		return nil
+	}
+	if err = s.tree.DeleteWholeRangeCIDR(cidr); err != nil && err != ErrNotFound {
+		return err
+	}
+	return s.tree.SetCIDR(cidr, ipsetMember)
+}
+
+// AddRange adds all addresses of the inclusive range startIP-endIP to the set.
+func (s *IPSet) AddRange(startIP, endIP string) error {
+	nets, err := RangeCIDRs(startIP, endIP)
+	if err != nil {
+		return err
+	}
+	for _, n := range nets {
+		if err = s.Add(n.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove removes all addresses of the cidr from the set.
+func (s *IPSet) Remove(cidr string) error {
+	key, bits, err := s.tree.cidrKey(cidr)
+	if err != nil {
+		return err
+	}
+	if n, depth := s.tree.bestNode(key, bits); n != nil && depth < bits {
+		outer := s.tree.blockNet(keyBlock(key, depth), s.tree.isIPv4([]byte(cidr)))
+		return s.tree.ExcludeCIDR(outer.String(), cidr)
+	}
+	if err = s.tree.DeleteWholeRangeCIDR(cidr); err != nil && err != ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+// Contains tells whether all addresses of the cidr are in the set.
+func (s *IPSet) Contains(cidr string) bool {
+	contained, _ := s.tree.ContainsCIDR(cidr)
+	return contained
+}
+
+// Len returns number of IP/masks of the set.
+func (s *IPSet) Len() int {
+	return s.tree.countValuedNodes
+}
+
+// Prefixes returns IP/masks of the set in address order of the tree keys (IPv4 as IPv4-mapped).
+func (s *IPSet) Prefixes() []net.IPNet {
+	ret := make([]net.IPNet, 0, s.Len())
+	s.tree.WalkTree(OptWalkIPAuto, func(cidr net.IPNet, value interface{}) (bool, error) {
+		ret = append(ret, cidr)
+		return true, nil
+	})
+	return ret
+}
+
+// Union returns new set of addresses in the set or other set.
+func (s *IPSet) Union(other *IPSet) *IPSet {
+	u := &IPSet{tree: s.tree.Clone()}
+	for _, n := range other.Prefixes() {
+		u.Add(n.String())
+	}
+	return u
+}
+
+// Intersect returns new set of addresses in both the set and other set.
+func (s *IPSet) Intersect(other *IPSet) *IPSet {
+	return &IPSet{tree: s.tree.Intersect(other.tree)}
+}
+
+// Subtract returns new set of addresses in the set and not in other set.
+func (s *IPSet) Subtract(other *IPSet) *IPSet {
+	return &IPSet{tree: s.tree.Subtract(other.tree)}
+}
+
+// Complement returns new set of addresses of the universe cidr (e.g. 10.0.0.0/8 or ::/0) not in the set.
+func (s *IPSet) Complement(universe string) (*IPSet, error) {
+	u := NewIPSet()
+	if err := u.Add(universe); err != nil {
+		return nil, err
+	}
+	return u.Subtract(s), nil
+}
@@ -0,0 +1,97 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"strings"
+	"testing"
+)
+
+func setPrefixes(s *IPSet) string {
+	var ret []string
+	for _, n := range s.Prefixes() {
+		ret = append(ret, n.String())
+	}
+	return "[" + strings.Join(ret, " ") + "]"
+}
+
+func TestIPSet(t *testing.T) {
+	s := NewIPSet()
+	for _, cidr := range []string{"10.0.0.0/25", "10.0.0.128/25", "10.0.0.7", "192.168.0.0/16", "2001:db8::/32", "102:300::/24"} {
+		if err := s.Add(cidr); err != nil {
+			t.Error(err)
+		}
+	}
+	if p := setPrefixes(s); p != "[10.0.0.0/24 192.168.0.0/16 102:300::/24 2001:db8::/32]" {
+		t.Errorf("Wrong set, expected [10.0.0.0/24 192.168.0.0/16 102:300::/24 2001:db8::/32], got %v", p)
+	}
+	if err := s.Add("192.0.0.0/8"); err != nil {
+		t.Error(err)
+	}
+	if s.Len() != 4 || !s.Contains("192.1.2.3") || !s.Contains("10.0.0.0/24") || s.Contains("10.0.0.0/23") || s.Contains("1.2.3.4") {
+		t.Errorf("Wrong set %v", setPrefixes(s))
+	}
+
+	if err := s.Remove("10.0.0.64/26"); err != nil {
+		t.Error(err)
+	}
+	if p := setPrefixes(s); p != "[10.0.0.0/26 10.0.0.128/25 192.0.0.0/8 102:300::/24 2001:db8::/32]" {
+		t.Errorf("Wrong set after remove, got %v", p)
+	}
+	if err := s.Remove("10.0.0.0/8"); err != nil {
+		t.Error(err)
+	}
+	if err := s.Remove("172.16.0.0/12"); err != nil {
+		t.Error(err)
+	}
+	if err := s.Add("bad"); err == nil {
+		t.Errorf("Should have gotten error for bad cidr")
+	}
+	if err := s.AddRange("10.0.0.1", "10.0.0.6"); err != nil {
+		t.Error(err)
+	}
+	if p := setPrefixes(s); p != "[10.0.0.1/32 10.0.0.2/31 10.0.0.4/31 10.0.0.6/32 192.0.0.0/8 102:300::/24 2001:db8::/32]" {
+		t.Errorf("Wrong set after range, got %v", p)
+	}
+	if err := s.tree.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIPSetOperations(t *testing.T) {
+	a, b := NewIPSet(), NewIPSet()
+	a.Add("10.0.0.0/24")
+	a.Add("2001:db8::/33")
+	b.Add("10.0.0.128/25")
+	b.Add("10.0.1.0/24")
+	b.Add("2001:db8:8000::/33")
+
+	for name, tc := range map[string]struct {
+		s        *IPSet
+		expected string
+	}{
+		"union":     {a.Union(b), "[10.0.0.0/23 2001:db8::/32]"},
+		"intersect": {a.Intersect(b), "[10.0.0.128/25]"},
+		"subtract":  {a.Subtract(b), "[10.0.0.0/25 2001:db8::/33]"},
+	} {
+		if p := setPrefixes(tc.s); p != tc.expected {
+			t.Errorf("Wrong %s, expected %v, got %v", name, tc.expected, p)
+		}
+	}
+	if p := setPrefixes(a); p != "[10.0.0.0/24 2001:db8::/33]" {
+		t.Errorf("Set changed by operations, got %v", p)
+	}
+
+	c, err := a.Complement("10.0.0.0/22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := setPrefixes(c); p != "[10.0.1.0/24 10.0.2.0/23]" {
+		t.Errorf("Wrong complement, expected [10.0.1.0/24 10.0.2.0/23], got %v", p)
+	}
+	if _, err = a.Complement("bad"); err == nil {
+		t.Errorf("Should have gotten error for bad universe")
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+//go:build go1.23
+
+package nradix
+
+import (
+	"errors"
+	"iter"
+	"net"
+)
+
+// errStopIteration ends the walk when the loop over All breaks.
+var errStopIteration = errors.New("iteration stopped")
+
+// All returns iterator over all IP/masks with values in walk order, to be used as
+//
+//	for cidr, value := range tree.All(nradix.OptWalkIPAuto) { ... }
+//
+// The tree is read locked (if safe) while the loop runs, the loop body must not modify the tree.
+func (tree *Tree) All(opt OptWalk) iter.Seq2[net.IPNet, interface{}] {
+	return func(yield func(net.IPNet, interface{}) bool) {
+		tree.WalkTree(opt&^OptWalkCollectErrors, func(cidr net.IPNet, value interface{}) (bool, error) {
+			if !yield(cidr, value) {
+				return false, errStopIteration
+			}
+			return true, nil
+		})
+	}
+}
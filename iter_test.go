@@ -0,0 +1,44 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+//go:build go1.23
+
+package nradix
+
+import (
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	tr := NewTree()
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("2001:db8::/48", 3)
+	tr.AddCIDR("192.168.0.0/24", 4)
+
+	var got []string
+	for cidr, value := range tr.All(OptWalkIPAuto) {
+		got = append(got, cidr.String())
+		if value.(int) == 3 {
+			break
+		}
+	}
+	expected := []string{"10.0.0.0/8", "10.1.0.0/16", "2001:db8::/48"}
+	if len(got) != len(expected) {
+		t.Fatalf("Wrong iteration, expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Wrong iteration, expected %v, got %v", expected, got)
+		}
+	}
+
+	var count int
+	for range tr.All(OptWalkIPAuto) {
+		count++
+	}
+	if count != 4 {
+		t.Errorf("Wrong iteration count, expected 4, got %d", count)
+	}
+}
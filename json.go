@@ -0,0 +1,94 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+)
+
+// WithJSONValueDecoder sets function decoding values in UnmarshalJSON, by default values are decoded
+// into interface{} (maps, slices, float64, string, bool) by encoding/json.
+func WithJSONValueDecoder(fn func(data []byte) (interface{}, error)) Option {
+	return func(tree *Tree) {
+		tree.jsonDecode = fn
+	}
+}
+
+// MarshalJSON implements json.Marshaler, the tree is written as an object of "cidr": value pairs in walk order.
+func (tree *Tree) MarshalJSON() ([]byte, error) {
+	if tree.root == nil {
+		return []byte("{}"), nil
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	err := tree.walkNodes(OptWalkIPAuto, func(cidr net.IPNet, n *node) (bool, error) {
+		key, err := json.Marshal(cidr.String())
+		if err != nil {
+			return false, err
+		}
+		value, err := json.Marshal(n.value)
+		if err != nil {
+			return false, err
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, "cidr": value pairs of the object are set into the tree
+// (a zero Tree is initialized first), null values are skipped.
+func (tree *Tree) UnmarshalJSON(data []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	if tree.root == nil {
+		tree.countNodes++
+		tree.root = tree.newnode()
+	}
+	entries := make([]prefixEntry, 0, len(m))
+	for cidr, raw := range m {
+		if string(raw) == "null" {
+			continue
+		}
+		if err := tree.checkStrict([]byte(cidr)); err != nil {
+			return err
+		}
+		e, err := tree.parseEntry([]byte(cidr))
+		if err != nil {
+			return err
+		}
+		if tree.jsonDecode != nil {
+			e.value, err = tree.jsonDecode(raw)
+		} else {
+			err = json.Unmarshal(raw, &e.value)
+		}
+		if err != nil {
+			return err
+		}
+		entries = append(entries, e)
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	return tree.insertEntries(entries, true)
+}
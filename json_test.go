@@ -0,0 +1,75 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+)
+
+type jsonPolicy struct {
+	Action string
+}
+
+func (p *jsonPolicy) MarshalJSON() ([]byte, error) {
+	return json.Marshal("policy:" + p.Action)
+}
+
+func TestJSON(t *testing.T) {
+	tr := NewTree()
+	tr.AddCIDR("10.0.0.0/8", "ten")
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("2620:10f:d000::/48", map[string]interface{}{"a": true})
+	data, err := json.Marshal(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"10.0.0.0/8":"ten","10.1.0.0/16":2,"2620:10f:d000::/48":{"a":true}}` {
+		t.Errorf("Wrong JSON: %s", data)
+	}
+
+	var loaded Tree
+	if err = json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if inf, _ := loaded.FindCIDR("10.1.1.1"); inf.(float64) != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+	if inf, _ := loaded.FindCIDR("2620:10f:d000::1"); !inf.(map[string]interface{})["a"].(bool) {
+		t.Errorf("Wrong value, got %v", inf)
+	}
+
+	// values implementing json.Marshaler round trip with the decoder option
+	tr = NewTree()
+	tr.AddCIDR("192.168.0.0/16", &jsonPolicy{"deny"})
+	data, _ = json.Marshal(tr)
+	loaded2 := NewTree(WithJSONValueDecoder(func(data []byte) (interface{}, error) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, err
+		}
+		return &jsonPolicy{s[len("policy:"):]}, nil
+	}))
+	if err = json.Unmarshal(data, loaded2); err != nil {
+		t.Fatal(err)
+	}
+	if inf, _ := loaded2.FindCIDR("192.168.1.1"); inf.(*jsonPolicy).Action != "deny" {
+		t.Errorf("Wrong value, expected deny policy, got %v", inf)
+	}
+
+	if err = json.Unmarshal([]byte(`{"1.2.3.x":1}`), loaded2); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Expected ErrBadIP, got %v", err)
+	}
+	var cidrs []string
+	loaded2.WalkTree(OptWalkIPv4, func(cidr net.IPNet, value interface{}) (bool, error) {
+		cidrs = append(cidrs, cidr.String())
+		return true, nil
+	})
+	if len(cidrs) != 1 {
+		t.Errorf("Wrong content after failed unmarshal: %v", cidrs)
+	}
+}
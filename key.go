@@ -0,0 +1,78 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+)
+
+// block is an address block of the tree keyspace: first bits of ip, IPv4 takes first 32 bits.
+type block struct {
+	ip   [net.IPv6len]byte
+	bits int
+}
+
+// keyBlock returns the block of the first bits of the key.
+func keyBlock(key [16]byte, bits int) block {
+	b := block{bits: bits}
+	for i := 0; i < bits; i++ {
+		setKeyBit(&b.ip, i, keyBit(key, i))
+	}
+	return b
+}
+
+// ipnet returns the block as net.IPNet of IPv4 (first 32 bits of the key) or IPv6.
+func (b block) ipnet(v4 bool) net.IPNet {
+	if v4 {
+		return net.IPNet{IP: net.IP(append([]byte(nil), b.ip[:net.IPv4len]...)), Mask: net.CIDRMask(b.bits, net.IPv4len*8)}
+	}
+	return net.IPNet{IP: net.IP(append([]byte(nil), b.ip[:]...)), Mask: net.CIDRMask(b.bits, net.IPv6len*8)}
+}
+
+// cidrKey parses the cidr into the tree key, IPv4 takes first 32 bits of the key.
+func cidrKey(cidr string) (key [16]byte, bits int, err error) {
+	e, err := parseEntry([]byte(cidr))
+	if err != nil {
+		return key, 0, err
+	}
+	key, bits = e.key()
+	return key, bits, nil
+}
+
+// bestNode returns the deepest node with value on the path of the key (nil if there is none) and its depth,
+// the node is counted as a hit.
+func (tree *Tree) bestNode(key [16]byte, bits int) (best *node, depth int) {
+	now := tree.expiryNow()
+	n := tree.root
+	for i := 0; n != nil; i++ {
+		if n.value != nil && !expired(n, now) {
+			best, depth = n, i
+		}
+		if i == bits {
+			break
+		}
+		if keyBit(key, i) {
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	if best != nil {
+		tree.hit(best)
+	}
+	return best, depth
+}
+
+func keyBit(key [16]byte, i int) bool {
+	return key[i/8]&(startbyte>>(i%8)) != 0
+}
+
+func setKeyBit(key *[net.IPv6len]byte, i int, set bool) {
+	if set {
+		key[i/8] |= startbyte >> (i % 8)
+	} else {
+		key[i/8] &^= startbyte >> (i % 8)
+	}
+}
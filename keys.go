@@ -0,0 +1,118 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net"
+)
+
+// ErrBadKey is returned for raw key shorter than its length in bits or longer than 128 bits.
+var ErrBadKey = errors.New("Bad key")
+
+// maxKeyBits is the longest raw key, the depth of the tree for IPv6.
+const maxKeyBits = net.IPv6len * 8
+
+// Raw keys are the first bits of key (most significant bit of key[0] first) used as the path in the tree, the CIDR
+// methods are the same with IP/mask turned into a key. Keep raw keys (like MAC prefixes or MPLS labels) in a tree
+// of their own, in a tree with IP/masks they would share the keyspace with them.
+
+// keyMask returns mask of the first bits of key.
+func keyMask(key []byte, bits int) (net.IPMask, error) {
+	if bits < 0 || bits > len(key)*8 || bits > maxKeyBits {
+		return nil, ErrBadKey
+	}
+	mask := make(net.IPMask, len(key))
+	for i := 0; i < bits; i++ {
+		mask[i/8] |= startbyte >> (i % 8)
+	}
+	return mask, nil
+}
+
+// AddKey adds value associated with the first bits of key. Will return error for invalid key or if value
+// already exists.
+func (tree *Tree) AddKey(key []byte, bits int, val interface{}) error {
+	mask, err := keyMask(key, bits)
+	if err != nil {
+		return err
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	return tree.insert(key, mask, val, false)
+}
+
+// SetKey sets value associated with the first bits of key. Will return error for invalid key.
+func (tree *Tree) SetKey(key []byte, bits int, val interface{}) error {
+	mask, err := keyMask(key, bits)
+	if err != nil {
+		return err
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	return tree.insert(key, mask, val, true)
+}
+
+// DeleteKey removes value associated with the first bits of key. Will return error for invalid key or
+// ErrNotFound if there is no such value.
+func (tree *Tree) DeleteKey(key []byte, bits int) error {
+	mask, err := keyMask(key, bits)
+	if err != nil {
+		return err
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	return tree.delete(key, mask, false)
+}
+
+// FindKey returns value associated with the longest key being prefix of the first bits of key.
+func (tree *Tree) FindKey(key []byte, bits int) (interface{}, error) {
+	mask, err := keyMask(key, bits)
+	if err != nil {
+		return nil, err
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	return tree.find(key, mask, findBest), nil
+}
+
+// FindExactKey returns value associated with exactly the first bits of key. Will return ErrNotFound if there is none.
+func (tree *Tree) FindExactKey(key []byte, bits int) (interface{}, error) {
+	mask, err := keyMask(key, bits)
+	if err != nil {
+		return nil, err
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	if value := tree.find(key, mask, findExact); value != nil {
+		return value, nil
+	}
+	return nil, ErrNotFound
+}
+
+// WalkKeysFunc is the type of function for caller of WalkKeys function, see WalkTreeFunc.
+type WalkKeysFunc func(key []byte, bits int, value interface{}) (bool, error)
+
+// WalkKeys walks keys with values in order of the keys (shorter key first), key holds the bits rounded up to bytes.
+func (tree *Tree) WalkKeys(wkfunc WalkKeysFunc) error {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	return tree.walkNodes(OptWalkIPv6, func(cidr net.IPNet, n *node) (bool, error) {
+		bits, _ := cidr.Mask.Size()
+		key := append([]byte(nil), cidr.IP[:(bits+7)/8]...)
+		return wkfunc(key, bits, n.value)
+	})
+}
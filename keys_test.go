@@ -0,0 +1,68 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestKeys(t *testing.T) {
+	tr := NewTree()
+	// MPLS labels are 20 bits
+	if err := tr.AddKey([]byte{0x00, 0x01, 0x00}, 20, "label 16"); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddKey([]byte{0x00, 0x00}, 12, "labels 0-255"); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddKey([]byte{0x00, 0x01, 0x00}, 20, "again"); err != ErrNodeBusy {
+		t.Errorf("Wrong error, expected %v, got %v", ErrNodeBusy, err)
+	}
+	if err := tr.AddKey([]byte{0x00}, 9, "too long"); err != ErrBadKey {
+		t.Errorf("Wrong error, expected %v, got %v", ErrBadKey, err)
+	}
+	if err := tr.AddKey(make([]byte, 17), 129, "too long"); err != ErrBadKey {
+		t.Errorf("Wrong error, expected %v, got %v", ErrBadKey, err)
+	}
+
+	for _, tc := range []struct {
+		key      []byte
+		bits     int
+		expected interface{}
+	}{
+		{[]byte{0x00, 0x01, 0x00}, 20, "label 16"},
+		{[]byte{0x00, 0x01, 0x10}, 20, "labels 0-255"},
+		{[]byte{0x00, 0x10, 0x00}, 20, nil},
+		{[]byte{0x00, 0x01}, 16, "labels 0-255"},
+	} {
+		inf, err := tr.FindKey(tc.key, tc.bits)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != tc.expected {
+			t.Errorf("Wrong value for %x/%d, expected %v, got %v", tc.key, tc.bits, tc.expected, inf)
+		}
+	}
+	if _, err := tr.FindExactKey([]byte{0x00, 0x01, 0x10}, 20); err != ErrNotFound {
+		t.Errorf("Wrong error, expected %v, got %v", ErrNotFound, err)
+	}
+
+	var walked []string
+	tr.WalkKeys(func(key []byte, bits int, value interface{}) (bool, error) {
+		walked = append(walked, fmt.Sprintf("%x/%d", key, bits))
+		return true, nil
+	})
+	if fmt.Sprint(walked) != "[0000/12 000100/20]" {
+		t.Errorf("Wrong walk, expected [0000/12 000100/20], got %v", walked)
+	}
+
+	if err := tr.DeleteKey([]byte{0x00, 0x01, 0x00}, 20); err != nil {
+		t.Error(err)
+	}
+	if inf, _ := tr.FindKey([]byte{0x00, 0x01, 0x00}, 20); inf != "labels 0-255" {
+		t.Errorf("Wrong value, expected labels 0-255, got %v", inf)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// Format is a text format of prefixes read by LoadFrom.
+type Format int
+
+const (
+	// FormatCIDR is one CIDR per line.
+	FormatCIDR Format = iota
+	// FormatCSV is CSV (RFC 4180) records of CIDR followed by value fields, like "10.0.0.0/8,office".
+	FormatCSV
+	// FormatFields is CIDR followed by value fields separated by whitespace, like "10.0.0.0/8 office".
+	FormatFields
+)
+
+// LoadFS adds content of all files of fsys matching the pattern (see fs.Glob) to the tree, works with embed.FS.
+// Every line of a file holds a CIDR optionally followed by whitespace and value text, empty lines and lines
+// starting with '#' are skipped. Value stored is the value text or, when missing, the name of the file.
+// Will return error (with file name and line number) for invalid CIDR or if value already exists.
+func (tree *Tree) LoadFS(fsys fs.FS, pattern string) error {
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return err
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	for _, name := range names {
+		if err = tree.loadFile(fsys, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tree *Tree) loadFile(fsys fs.FS, name string) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 || text[0] == '#' {
+			continue
+		}
+		cidr, value := text, []byte(nil)
+		if p := bytes.IndexAny(text, " \t"); p > 0 {
+			cidr, value = text[:p], bytes.TrimSpace(text[p+1:])
+		}
+		var val interface{} = name
+		if len(value) > 0 {
+			val = string(value)
+		}
+		if err = tree.addCIDRb(cidr, val); err != nil {
+			return fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// LoadFrom streams prefixes in the format from r to the tree. Empty lines and lines starting with '#' are skipped.
+// Value stored is valueParser(fields of the line) where fields[0] is the CIDR, when valueParser is nil it is
+// the second field or true if the line has only the CIDR. Will return error (with line number) for bad format,
+// invalid CIDR, error of valueParser or if value already exists, prefixes of the lines before it are kept.
+func (tree *Tree) LoadFrom(r io.Reader, format Format, valueParser func(fields []string) (interface{}, error)) error {
+	if valueParser == nil {
+		valueParser = defaultValueParser
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	if format == FormatCSV {
+		return tree.loadCSV(r, valueParser)
+	}
+	if format != FormatCIDR && format != FormatFields {
+		return fmt.Errorf("unknown format %d", format)
+	}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || text[0] == '#' {
+			continue
+		}
+		fields := []string{text}
+		if format == FormatFields {
+			fields = strings.Fields(text)
+		}
+		if err := tree.loadFields(fields, valueParser); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func (tree *Tree) loadCSV(r io.Reader, valueParser func(fields []string) (interface{}, error)) error {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	for {
+		fields, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fields[0] = strings.TrimSpace(fields[0])
+		if err = tree.loadFields(fields, valueParser); err != nil {
+			line, _ := cr.FieldPos(0)
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
+
+func (tree *Tree) loadFields(fields []string, valueParser func(fields []string) (interface{}, error)) error {
+	val, err := valueParser(fields)
+	if err != nil {
+		return err
+	}
+	return tree.addCIDRb([]byte(fields[0]), val)
+}
+
+func defaultValueParser(fields []string) (interface{}, error) {
+	if len(fields) > 1 {
+		return fields[1], nil
+	}
+	return true, nil
+}
@@ -0,0 +1,102 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"policy/allow.txt": {Data: []byte("# allowed networks\n10.0.0.0/8\n\n192.168.0.0/16 office\n")},
+		"policy/deny.txt":  {Data: []byte("10.66.0.0/16\tblocked\ndead::/16\n")},
+		"other/skip.txt":   {Data: []byte("1.1.1.1\n")},
+	}
+	tr := NewTree()
+	if err := tr.LoadFS(fsys, "policy/*.txt"); err != nil {
+		t.Fatal(err)
+	}
+	for cidr, exp := range map[string]string{
+		"10.1.1.1":    "policy/allow.txt",
+		"192.168.1.1": "office",
+		"10.66.1.1":   "blocked",
+		"dead::1":     "policy/deny.txt",
+	} {
+		inf, err := tr.FindCIDR(cidr)
+		if err != nil {
+			t.Error(err)
+		} else if inf != exp {
+			t.Errorf("Wrong value for %s, expected %q, got %v", cidr, exp, inf)
+		}
+	}
+	if inf, _ := tr.FindCIDR("1.1.1.1"); inf != nil {
+		t.Errorf("Value from not matching file found: %v", inf)
+	}
+
+	fsys["policy/bad.txt"] = &fstest.MapFile{Data: []byte("1.2.3.4/24\n1.2.3.x\n")}
+	err := NewTree().LoadFS(fsys, "policy/bad.txt")
+	if !errors.Is(err, ErrBadIP) {
+		t.Errorf("Expected ErrBadIP, got %v", err)
+	} else if err.Error() != `policy/bad.txt:2: Bad IP address or mask "1.2.3.x": unexpected character 'x'` {
+		t.Errorf("Wrong error location: %v", err)
+	}
+}
+
+func TestLoadFrom(t *testing.T) {
+	for format, data := range map[Format]string{
+		FormatCIDR:   "# list\n10.0.0.0/8\n\n  dead::/16  \n",
+		FormatCSV:    "# list\n10.0.0.0/8\n\ndead::/16\n",
+		FormatFields: "# list\n10.0.0.0/8\n\ndead::/16\n",
+	} {
+		tr := NewTree()
+		if err := tr.LoadFrom(strings.NewReader(data), format, nil); err != nil {
+			t.Fatal(err)
+		}
+		for _, cidr := range []string{"10.1.1.1", "dead::1"} {
+			inf, err := tr.FindCIDR(cidr)
+			if err != nil {
+				t.Error(err)
+			} else if inf != true {
+				t.Errorf("Wrong value for %s in format %d, expected true, got %v", cidr, format, inf)
+			}
+		}
+	}
+
+	tr := NewTree()
+	err := tr.LoadFrom(strings.NewReader("10.0.0.0/8, office\n\"192.168.0.0/16\",\"lab, 2nd floor\"\n"), FormatCSV, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inf, _ := tr.FindCIDR("10.1.1.1"); inf != "office" {
+		t.Errorf("Wrong value, expected office, got %v", inf)
+	}
+	if inf, _ := tr.FindCIDR("192.168.1.1"); inf != "lab, 2nd floor" {
+		t.Errorf("Wrong value, expected \"lab, 2nd floor\", got %v", inf)
+	}
+
+	tr = NewTree()
+	asn := func(fields []string) (interface{}, error) {
+		if len(fields) != 2 {
+			return nil, errors.New("expected CIDR and AS number")
+		}
+		return strconv.Atoi(fields[1])
+	}
+	err = tr.LoadFrom(strings.NewReader("1.0.0.0/24 13335\n8.8.8.0/24\t15169\n8.8.4.0/24\n"), FormatFields, asn)
+	if err == nil || err.Error() != "line 3: expected CIDR and AS number" {
+		t.Errorf("Wrong error, expected line 3, got %v", err)
+	}
+	if inf, _ := tr.FindCIDR("8.8.8.8"); inf != 15169 {
+		t.Errorf("Wrong value, expected 15169, got %v", inf)
+	}
+
+	err = NewTree().LoadFrom(strings.NewReader("10.0.0.0/8,a\n10.0.0.0/8,b\n"), FormatCSV, nil)
+	if !errors.Is(err, ErrNodeBusy) || !strings.HasPrefix(err.Error(), "line 2: ") {
+		t.Errorf("Expected ErrNodeBusy on line 2, got %v", err)
+	}
+}
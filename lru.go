@@ -0,0 +1,110 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"container/list"
+	"net"
+	"sync"
+)
+
+// NewTreeLRU creates tree keeping at most maxValued (at least one) values: adding value to the full tree evicts the
+// value of the least recently used IP/mask. Values are used by longest match lookups (every match counts, like hits
+// of WithHitCounting) and by being added or set. See WithOnEvict.
+func NewTreeLRU(maxValued int, opts ...Option) *Tree {
+	return NewTree(append([]Option{withLRU(maxValued)}, opts...)...)
+}
+
+func withLRU(maxValued int) Option {
+	if maxValued < 1 {
+		maxValued = 1
+	}
+	return func(tree *Tree) {
+		tree.lru = &lruList{max: maxValued}
+	}
+}
+
+// WithOnEvict sets function called for every value evicted from the tree created by NewTreeLRU.
+// It is called under the lock of the tree, right after the value is removed, and must not use the tree.
+func WithOnEvict(fn func(cidr net.IPNet, value interface{})) Option {
+	return func(tree *Tree) {
+		tree.onEvict = fn
+	}
+}
+
+// lruList orders valued nodes from the most to the least recently used, it has own lock as lookups holding only
+// read lock of the tree use nodes.
+type lruList struct {
+	max   int
+	order list.List
+	elems map[*node]*list.Element
+	sync.Mutex
+}
+
+func (l *lruList) use(n *node) {
+	l.Lock()
+	defer l.Unlock()
+	if e, ok := l.elems[n]; ok {
+		l.order.MoveToFront(e)
+		return
+	}
+	if l.elems == nil {
+		l.elems = make(map[*node]*list.Element)
+	}
+	l.elems[n] = l.order.PushFront(n)
+}
+
+func (l *lruList) drop(n *node) {
+	l.Lock()
+	defer l.Unlock()
+	if e, ok := l.elems[n]; ok {
+		l.order.Remove(e)
+		delete(l.elems, n)
+	}
+}
+
+// moved replaces node src copied to dst (by Compact).
+func (l *lruList) moved(src, dst *node) {
+	l.Lock()
+	defer l.Unlock()
+	if e, ok := l.elems[src]; ok {
+		e.Value = dst
+		delete(l.elems, src)
+		l.elems[dst] = e
+	}
+}
+
+func (l *lruList) reset() {
+	l.Lock()
+	defer l.Unlock()
+	l.order.Init()
+	l.elems = nil
+}
+
+// evict removes least recently used values while the tree has more values than allowed.
+func (tree *Tree) evict() {
+	l := tree.lru
+	if l.order.Len() != tree.countValuedNodes {
+		// content was copied into the tree (Clone, UnmarshalBinary), start over in walk order
+		l.reset()
+		tree.walkNodes(OptWalkIPAuto|optWalkExpired, func(cidr net.IPNet, n *node) (bool, error) {
+			l.use(n)
+			return true, nil
+		})
+	}
+	for tree.countValuedNodes > l.max && l.order.Len() > 0 {
+		n := l.order.Back().Value.(*node)
+		l.drop(n)
+		value := n.value
+		cidr := walkpath2net(tree.walkOpt(OptWalkIPAuto), nodeWalkpath(n))
+		e, err := net2entry(cidr)
+		if err != nil || tree.deleteEntry(&e, false) != nil {
+			continue
+		}
+		if tree.onEvict != nil {
+			tree.onEvict(cidr, value)
+		}
+	}
+}
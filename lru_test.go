@@ -0,0 +1,66 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"testing"
+)
+
+func TestNewTreeLRU(t *testing.T) {
+	var evicted []string
+	tr := NewTreeLRU(3, WithOnEvict(func(cidr net.IPNet, value interface{}) {
+		evicted = append(evicted, cidr.String())
+	}))
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("2001:db8::/48", 3)
+	if inf, _ := tr.FindCIDR("10.2.0.1"); inf != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+
+	// 10.1.0.0/16 is the least recently used now
+	tr.AddCIDR("192.168.0.0/16", 4)
+	if len(evicted) != 1 || evicted[0] != "10.1.0.0/16" {
+		t.Errorf("Wrong eviction, expected [10.1.0.0/16], got %v", evicted)
+	}
+	if _, err := tr.FindExactCIDR("10.1.0.0/16"); err != ErrNotFound {
+		t.Errorf("Wrong error, expected %v, got %v", ErrNotFound, err)
+	}
+	tr.SetCIDR("2001:db8::/48", 5)
+	tr.AddCIDR("172.16.0.0/12", 6)
+	if len(evicted) != 2 || evicted[1] != "10.0.0.0/8" {
+		t.Errorf("Wrong eviction, expected 10.0.0.0/8, got %v", evicted)
+	}
+	if _, valued, _, _ := tr.GetStats(); valued != 3 {
+		t.Errorf("Wrong number of values, expected 3, got %d", valued)
+	}
+
+	// deleted values are not evicted
+	tr.DeleteCIDR("192.168.0.0/16")
+	tr.AddCIDR("10.0.0.0/8", 7)
+	if len(evicted) != 2 {
+		t.Errorf("Wrong eviction, expected none, got %v", evicted[2:])
+	}
+	tr.AddCIDR("10.1.0.0/16", 8)
+	if len(evicted) != 3 || evicted[2] != "2001:db8::/48" {
+		t.Errorf("Wrong eviction, expected 2001:db8::/48, got %v", evicted)
+	}
+
+	// copies keep the bound
+	c := tr.Clone()
+	c.AddCIDR("1.0.0.0/8", 9)
+	if _, valued, _, _ := c.GetStats(); valued != 3 {
+		t.Errorf("Wrong number of values of clone, expected 3, got %d", valued)
+	}
+	c.Compact()
+	c.AddCIDR("2.0.0.0/8", 10)
+	if _, valued, _, _ := c.GetStats(); valued != 3 {
+		t.Errorf("Wrong number of values after compaction, expected 3, got %d", valued)
+	}
+	if inf, _ := c.FindCIDR("2.0.0.1"); inf != 10 {
+		t.Errorf("Wrong value, expected 10, got %v", inf)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"encoding/hex"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ErrBadMAC is returned for invalid MAC address or prefix.
+var ErrBadMAC = errors.New("Bad MAC address or prefix")
+
+// parseMACPrefix parses MAC prefix: hex bytes separated by ':' or '-' (like OUI "00:1a:2b"), optionally followed
+// by "/bits" (like MA-S "00:1a:2b:3c:40:00/36"), without it the prefix is as long as the bytes given.
+func parseMACPrefix(prefix string) ([]byte, int, error) {
+	s, bitsStr, masked := strings.Cut(prefix, "/")
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ':' || r == '-' })
+	if len(fields) == 0 || len(fields) > net.IPv6len {
+		return nil, 0, ErrBadMAC
+	}
+	key := make([]byte, len(fields))
+	for i, f := range fields {
+		if len(f) != 2 {
+			return nil, 0, ErrBadMAC
+		}
+		if _, err := hex.Decode(key[i:i+1], []byte(f)); err != nil {
+			return nil, 0, ErrBadMAC
+		}
+	}
+	bits := len(key) * 8
+	if masked {
+		var err error
+		if bits, err = strconv.Atoi(bitsStr); err != nil || bits < 0 || bits > len(key)*8 {
+			return nil, 0, ErrBadMAC
+		}
+	}
+	return key, bits, nil
+}
+
+// AddMAC adds value associated with MAC prefix (OUI like "00:1a:2b", full address or "address/bits"), keep MAC
+// prefixes in a tree of their own. Will return error for invalid prefix or if value already exists.
+func (tree *Tree) AddMAC(prefix string, val interface{}) error {
+	key, bits, err := parseMACPrefix(prefix)
+	if err != nil {
+		return err
+	}
+	return tree.AddKey(key, bits, val)
+}
+
+// DeleteMAC removes value associated with MAC prefix. Will return error for invalid prefix or ErrNotFound
+// if there is no such value.
+func (tree *Tree) DeleteMAC(prefix string) error {
+	key, bits, err := parseMACPrefix(prefix)
+	if err != nil {
+		return err
+	}
+	return tree.DeleteKey(key, bits)
+}
+
+// FindMAC returns value associated with the longest MAC prefix of the address (in any format of net.ParseMAC).
+func (tree *Tree) FindMAC(mac string) (interface{}, error) {
+	addr, err := net.ParseMAC(mac)
+	if err != nil {
+		return nil, ErrBadMAC
+	}
+	return tree.FindKey(addr, len(addr)*8)
+}
@@ -0,0 +1,53 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"testing"
+)
+
+func TestMAC(t *testing.T) {
+	tr := NewTree()
+	for prefix, vendor := range map[string]string{
+		"00:1a:2b":             "Ayecom",
+		"00-1A-2B-3C-40-00/36": "Small vendor",
+		"f4:f5:d8":             "Google",
+		"f4:f5:d8:00:00:01":    "Router",
+	} {
+		if err := tr.AddMAC(prefix, vendor); err != nil {
+			t.Error(err)
+		}
+	}
+	for _, prefix := range []string{"00:1a:2g", "00:1a:2b/25", "001a2b", ""} {
+		if err := tr.AddMAC(prefix, "bad"); err != ErrBadMAC {
+			t.Errorf("Wrong error for %q, expected %v, got %v", prefix, ErrBadMAC, err)
+		}
+	}
+	for mac, expected := range map[string]interface{}{
+		"00:1a:2b:00:00:01": "Ayecom",
+		"00:1a:2b:3c:4f:ff": "Small vendor",
+		"00:1a:2b:3c:50:00": "Ayecom",
+		"f4f5.d800.0001":    "Router",
+		"f4:f5:d8:00:00:02": "Google",
+		"00:00:00:00:00:01": nil,
+	} {
+		inf, err := tr.FindMAC(mac)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != expected {
+			t.Errorf("Wrong value for %s, expected %v, got %v", mac, expected, inf)
+		}
+	}
+	if _, err := tr.FindMAC("00:1a"); err != ErrBadMAC {
+		t.Errorf("Wrong error, expected %v, got %v", ErrBadMAC, err)
+	}
+	if err := tr.DeleteMAC("00:1a:2b"); err != nil {
+		t.Error(err)
+	}
+	if inf, _ := tr.FindMAC("00:1a:2b:00:00:01"); inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+}
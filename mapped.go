@@ -0,0 +1,114 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// Address families share one keyspace of the tree. By default IPv4 address takes the first 32 bits of the key, so
+// IPv4 and IPv6 prefixes with the same leading bits are the same node (1.2.3.0/24 is 102:300::/24) and walks report
+// prefixes of up to 32 bits as IPv4 (with OptWalkIPAuto) whichever family they were added as.
+//
+// WithIPv4Mapped keeps IPv4 prefixes in the IPv4-mapped IPv6 block ::ffff:0:0/96 instead (1.2.3.0/24 is
+// ::ffff:1.2.3.0/120), so prefixes of different families never collide and walks report the family by position:
+// prefixes inside ::ffff:0:0/96 are IPv4 (walked with OptWalkIPv4), all others are IPv6 (walked with OptWalkIPv6).
+// IPv6 prefixes covering the block (like ::/0) cover IPv4 addresses too.
+func WithIPv4Mapped() Option {
+	return func(tree *Tree) {
+		tree.mapped4 = true
+	}
+}
+
+// WithUnmapIPv4 makes the tree take IPv4-mapped IPv6 addresses given as strings or netip.Prefix (::ffff:1.2.3.4,
+// with mask of at least 96 bits if any) as IPv4 ones, so dual-stack lookups of mapped addresses hit IPv4 prefixes.
+// Without it such addresses are IPv6 ones (except with WithIPv4Mapped, where both are the same key anyway).
+func WithUnmapIPv4() Option {
+	return func(tree *Tree) {
+		tree.unmap4 = true
+	}
+}
+
+// mappedBits is the length of the IPv4-mapped block, IPv4 prefixes of WithIPv4Mapped tree are that much longer.
+const mappedBits = 96
+
+// optWalkMapped4 makes walkpath2net report the IPv4-mapped block as IPv4 (set by walks of WithIPv4Mapped tree).
+const optWalkMapped4 = OptWalk(0x40000000)
+
+// walkOpt returns walk options with the address family semantics of the tree.
+func (tree *Tree) walkOpt(opt OptWalk) OptWalk {
+	if tree.mapped4 {
+		return opt | optWalkMapped4
+	}
+	return opt
+}
+
+// mapped6 returns IPv4 key and mask as key and mask of the IPv4-mapped block.
+func mapped6(key, mask uint32) (net.IP, net.IPMask) {
+	ip := make(net.IP, net.IPv6len)
+	ip[10], ip[11] = 0xff, 0xff
+	binary.BigEndian.PutUint32(ip[12:], key&mask)
+	return ip, net.CIDRMask(mappedBits+masklen32(mask), net.IPv6len*8)
+}
+
+// isMappedPath tells whether walkpath is inside the IPv4-mapped block.
+func isMappedPath(walkpath []byte) bool {
+	if len(walkpath) < mappedBits {
+		return false
+	}
+	for i, b := range walkpath[:mappedBits] {
+		if (b != 0) != (i >= mappedBits-16) {
+			return false
+		}
+	}
+	return true
+}
+
+// entryKey returns the entry as the key of the tree.
+func (tree *Tree) entryKey(e *prefixEntry) (key [16]byte, bits int) {
+	if tree.mapped4 && e.v4 {
+		ip, mask := mapped6(e.ip32, e.mk32)
+		copy(key[:], ip)
+		bits, _ = mask.Size()
+		return key, bits
+	}
+	return e.key()
+}
+
+// cidrKey parses the cidr into the key of the tree.
+func (tree *Tree) cidrKey(cidr string) (key [16]byte, bits int, err error) {
+	e, err := tree.parseEntry([]byte(cidr))
+	if err != nil {
+		return key, 0, err
+	}
+	key, bits = tree.entryKey(&e)
+	return key, bits, nil
+}
+
+// blockNet returns the block of the key of the tree as net.IPNet, IPv4 (if v4) unless the block is shorter than
+// the IPv4 prefix.
+func (tree *Tree) blockNet(b block, v4 bool) net.IPNet {
+	if tree.mapped4 && v4 {
+		if b.bits < mappedBits {
+			return b.ipnet(false)
+		}
+		v := block{bits: b.bits - mappedBits}
+		copy(v.ip[:], b.ip[12:])
+		return v.ipnet(true)
+	}
+	return b.ipnet(v4)
+}
+
+// subtreeOpt returns options walking the subtree of the cidr.
+func (tree *Tree) subtreeOpt(cidr string) OptWalk {
+	switch {
+	case tree.mapped4:
+		return OptWalkIPAuto
+	case tree.isIPv4([]byte(cidr)):
+		return OptWalkIPv4
+	}
+	return OptWalkIPv6
+}
@@ -0,0 +1,108 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"testing"
+)
+
+func TestSharedKeyspace(t *testing.T) {
+	tr := NewTree()
+	if err := tr.AddCIDR("1.2.3.0/24", 1); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("102:300::/24", 2); err != ErrNodeBusy {
+		t.Errorf("Wrong error, expected %v, got %v", ErrNodeBusy, err)
+	}
+}
+
+func TestWithIPv4Mapped(t *testing.T) {
+	tr := NewTree(WithIPv4Mapped())
+	if err := tr.AddCIDR("1.2.3.0/24", 1); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("102:300::/24", 2); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("2001:db8::/32", 3); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("::ffff:5.6.0.0/112", 4); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("::/0", 5); err != nil {
+		t.Error(err)
+	}
+	for cidr, expected := range map[string]interface{}{
+		"1.2.3.4":         1,
+		"102:304::":       2,
+		"::ffff:1.2.3.4":  1,
+		"5.6.7.8":         4,
+		"2001:db8::1":     3,
+		"9.9.9.9":         5,
+		"102:300::/24":    2,
+		"::ffff:9.9.9.9":  5,
+		"2001:db9::1/128": 5,
+	} {
+		inf, err := tr.FindCIDR(cidr)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != expected {
+			t.Errorf("Wrong value for %s, expected %v, got %v", cidr, expected, inf)
+		}
+	}
+
+	_, n, err := tr.FindCIDRNet("5.6.7.8")
+	if err != nil {
+		t.Error(err)
+	}
+	if n.String() != "5.6.0.0/16" {
+		t.Errorf("Wrong net, expected 5.6.0.0/16, got %v", n.String())
+	}
+	_, n, err = tr.FindCIDRNet("9.9.9.9")
+	if err != nil {
+		t.Error(err)
+	}
+	if n.String() != "::/0" {
+		t.Errorf("Wrong net, expected ::/0, got %v", n.String())
+	}
+
+	for opt, expected := range map[OptWalk][]string{
+		OptWalkIPAuto: {"::/0", "1.2.3.0/24", "5.6.0.0/16", "102:300::/24", "2001:db8::/32"},
+		OptWalkIPv4:   {"1.2.3.0/24", "5.6.0.0/16"},
+		OptWalkIPv6:   {"::/0", "102:300::/24", "2001:db8::/32"},
+	} {
+		var walked []string
+		tr.WalkTree(opt, func(cidr net.IPNet, value interface{}) (bool, error) {
+			if cidr.IP != nil {
+				walked = append(walked, cidr.String())
+			}
+			return true, nil
+		})
+		if len(walked) != len(expected) {
+			t.Errorf("Wrong walk, expected %v, got %v", expected, walked)
+			continue
+		}
+		for i := range expected {
+			if walked[i] != expected[i] {
+				t.Errorf("Wrong walk, expected %v, got %v", expected, walked)
+				break
+			}
+		}
+	}
+
+	if err := tr.DeleteCIDR("1.2.3.0/24"); err != nil {
+		t.Error(err)
+	}
+	inf, err := tr.FindCIDR("102:304::")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+}
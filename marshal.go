@@ -0,0 +1,179 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrBadFormat is returned when reading data which is not a tree written by Marshal.
+var ErrBadFormat = errors.New("Bad tree data format")
+
+const (
+	marshalMagic   = "NRDX"
+	marshalVersion = 2 // version 1 wrote flags 4, 8 and 16, its data is rejected
+
+	flagValue = 1
+	flagLeft  = 2
+	flagRight = 4
+)
+
+// Marshal writes the tree in compact binary format: every node (in depth first order) takes a byte of flags
+// followed by its value encoded by encodeValue, so loading it with UnmarshalTree needs no CIDR parsing.
+// Expiration of values is not written, expired values are skipped. Trees of zones (see WithZones) are not written.
+func (tree *Tree) Marshal(w io.Writer, encodeValue func(value interface{}) ([]byte, error)) error {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	bw := bufio.NewWriter(w)
+	bw.WriteString(marshalMagic)
+	bw.WriteByte(marshalVersion)
+	var buf [binary.MaxVarintLen64]byte
+	bw.Write(buf[:binary.PutUvarint(buf[:], uint64(tree.countNodes))])
+	m := marshaler{bw: bw, encodeValue: encodeValue, buf: buf[:], now: tree.expiryNow()}
+	if m.now != 0 {
+		// expiration is not written, expired values and subtrees left with no value are skipped
+		m.live = make(map[*node]bool)
+		m.markLive(tree.root)
+	}
+	if err := m.node(tree.root); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+type marshaler struct {
+	bw          *bufio.Writer
+	encodeValue func(value interface{}) ([]byte, error)
+	buf         []byte
+	now         int64
+	live        map[*node]bool // subtrees having not expired value, nil if no value can be expired
+}
+
+// markLive marks subtrees of n having not expired value, returns whether n is such.
+func (m *marshaler) markLive(n *node) bool {
+	live := n.value != nil && !expired(n, m.now)
+	for _, child := range []*node{n.left, n.right} {
+		if child != nil && m.markLive(child) {
+			live = true
+		}
+	}
+	if live {
+		m.live[n] = true
+	}
+	return live
+}
+
+func (m *marshaler) keep(n *node) bool {
+	return n != nil && (m.live == nil || m.live[n])
+}
+
+func (m *marshaler) node(n *node) error {
+	var flags byte
+	value := n.value != nil && !expired(n, m.now)
+	if value {
+		flags |= flagValue
+	}
+	if m.keep(n.left) {
+		flags |= flagLeft
+	}
+	if m.keep(n.right) {
+		flags |= flagRight
+	}
+	if err := m.bw.WriteByte(flags); err != nil {
+		return err
+	}
+	if value {
+		data, err := m.encodeValue(n.value)
+		if err != nil {
+			return err
+		}
+		m.bw.Write(m.buf[:binary.PutUvarint(m.buf, uint64(len(data)))])
+		if _, err = m.bw.Write(data); err != nil {
+			return err
+		}
+	}
+	if flags&flagLeft != 0 {
+		if err := m.node(n.left); err != nil {
+			return err
+		}
+	}
+	if flags&flagRight != 0 {
+		if err := m.node(n.right); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarshalTree creates Tree (configured by opts) from data written by Marshal, values are decoded by decodeValue.
+func UnmarshalTree(r io.Reader, decodeValue func(data []byte) (interface{}, error), opts ...Option) (*Tree, error) {
+	br := bufio.NewReader(r)
+	head := make([]byte, len(marshalMagic)+1)
+	if _, err := io.ReadFull(br, head); err != nil || string(head[:len(marshalMagic)]) != marshalMagic || head[len(marshalMagic)] != marshalVersion {
+		return nil, ErrBadFormat
+	}
+	count, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, ErrBadFormat
+	}
+	tree := NewTree(opts...)
+	if count > 1<<20 {
+		count = 1 << 20
+	}
+	tree.reserve(int(count))
+	if err = tree.unmarshalNode(br, tree.root, 0, decodeValue); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+func (tree *Tree) unmarshalNode(br *bufio.Reader, n *node, depth int, decodeValue func(data []byte) (interface{}, error)) error {
+	flags, err := br.ReadByte()
+	if err != nil || flags&^(flagValue|flagLeft|flagRight) != 0 {
+		return ErrBadFormat
+	}
+	if flags&flagValue != 0 {
+		size, err := binary.ReadUvarint(br)
+		if err != nil || size > 1<<30 {
+			return ErrBadFormat
+		}
+		// the buffer grows with data actually read, not with the size claimed by the input
+		data, err := io.ReadAll(io.LimitReader(br, int64(size)))
+		if err != nil || uint64(len(data)) != size {
+			return ErrBadFormat
+		}
+		if n.value, err = decodeValue(data); err != nil {
+			return err
+		}
+		if n.value != nil {
+			tree.countValuedNodes++
+		}
+	}
+	if flags&(flagLeft|flagRight) != 0 && depth >= 128 {
+		return ErrBadFormat
+	}
+	if flags&flagLeft != 0 {
+		n.left = tree.newnode()
+		tree.countNodes++
+		n.left.parent = n
+		if err = tree.unmarshalNode(br, n.left, depth+1, decodeValue); err != nil {
+			return err
+		}
+	}
+	if flags&flagRight != 0 {
+		n.right = tree.newnode()
+		tree.countNodes++
+		n.right.parent = n
+		if err = tree.unmarshalNode(br, n.right, depth+1, decodeValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestMarshal(t *testing.T) {
+	tr := NewTree()
+	cidrs := map[string]string{
+		"0.0.0.0/0":                "default",
+		"10.0.0.0/8":               "ten",
+		"10.1.0.0/16":              "",
+		"dead::/16":                "dead",
+		"2620:10f:d000:100::5/128": "host",
+	}
+	for cidr, v := range cidrs {
+		tr.AddCIDR(cidr, v)
+	}
+	encode := func(value interface{}) ([]byte, error) { return []byte(value.(string)), nil }
+	decode := func(data []byte) (interface{}, error) { return string(data), nil }
+
+	var buf bytes.Buffer
+	if err := tr.Marshal(&buf, encode); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	loaded, err := UnmarshalTree(bytes.NewReader(data), decode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n1, v1, _, _ := tr.GetStats()
+	n2, v2, _, _ := loaded.GetStats()
+	if n1 != n2 || v1 != v2 {
+		t.Errorf("Wrong stats, expected %d/%d, got %d/%d", n1, v1, n2, v2)
+	}
+	found := map[string]string{}
+	loaded.WalkTree(OptWalkIPAuto, func(cidr net.IPNet, value interface{}) (bool, error) {
+		found[cidr.String()] = value.(string)
+		return true, nil
+	})
+	if len(found) != len(cidrs) {
+		t.Errorf("Wrong loaded values, got %v", found)
+	}
+	if inf, _ := loaded.FindCIDR("2620:10f:d000:100::5"); inf != "host" {
+		t.Errorf("Wrong value, expected host, got %v", inf)
+	}
+
+	if _, err = UnmarshalTree(bytes.NewReader(data[:len(data)-3]), decode); err != ErrBadFormat {
+		t.Errorf("Expected ErrBadFormat for truncated data, got %v", err)
+	}
+	if _, err = UnmarshalTree(bytes.NewReader([]byte("NOPE")), decode); err != ErrBadFormat {
+		t.Errorf("Expected ErrBadFormat, got %v", err)
+	}
+}
+
+func TestMarshalFormat(t *testing.T) {
+	tr := NewTree()
+	tr.AddCIDR("0.0.0.0/0", "default")
+	tr.AddCIDR("128.0.0.0/1", "high")
+	var buf bytes.Buffer
+	if err := tr.Marshal(&buf, func(value interface{}) ([]byte, error) { return []byte(value.(string)), nil }); err != nil {
+		t.Fatal(err)
+	}
+	// magic, version, node count, then the root: value and right child flags
+	data := buf.Bytes()
+	if data[4] != marshalVersion || data[6] != flagValue|flagRight || flagValue != 1 || flagLeft != 2 || flagRight != 4 {
+		t.Errorf("Wrong format, got % x", data)
+	}
+
+	// value size claimed by the input is not allocated up front
+	bad := []byte(marshalMagic + "\x02\x01\x01\xff\xff\xff\xff\x03")
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := UnmarshalTree(bytes.NewReader(bad), func(data []byte) (interface{}, error) { return data, nil }); err != ErrBadFormat {
+		t.Errorf("Expected ErrBadFormat, got %v", err)
+	}
+	runtime.ReadMemStats(&after)
+	if after.TotalAlloc-before.TotalAlloc > 1<<20 {
+		t.Errorf("Expected small allocation for truncated value, got %d bytes", after.TotalAlloc-before.TotalAlloc)
+	}
+}
+
+func TestMarshalExpired(t *testing.T) {
+	tr := NewTree()
+	clock := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr.clock = func() time.Time { return clock }
+	tr.AddCIDR("10.0.0.0/8", "ten")
+	tr.AddCIDRWithTTL("10.1.0.0/16", "gone", time.Minute)
+	tr.AddCIDRWithTTL("10.1.2.0/24", "kept", time.Hour)
+	tr.AddCIDRWithTTL("192.168.0.0/16", "gone", time.Minute)
+	clock = clock.Add(10 * time.Minute)
+
+	var buf bytes.Buffer
+	if err := tr.Marshal(&buf, func(value interface{}) ([]byte, error) { return []byte(value.(string)), nil }); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := UnmarshalTree(&buf, func(data []byte) (interface{}, error) { return string(data), nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for cidr, expected := range map[string]interface{}{"10.1.0.0/16": nil, "10.1.2.0/24": "kept", "192.168.0.0/16": nil} {
+		if inf, _ := loaded.FindExactCIDR(cidr); inf != expected {
+			t.Errorf("Wrong loaded value for %s, expected %v, got %v", cidr, expected, inf)
+		}
+	}
+	if _, values, _, _ := loaded.GetStats(); values != 2 {
+		t.Errorf("Wrong loaded value count, expected 2, got %d", values)
+	}
+	if err = loaded.Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"unsafe"
+)
+
+// Merge adds all values of other tree to the tree. For IP/mask having value in both trees the value becomes
+// conflict(value of the tree, value of other tree), or the value of other tree if conflict is nil.
+// Will return error if the IP/mask of other tree can't be added, values merged before it are kept.
+// IP/masks are converted if only one of the trees is WithIPv4Mapped.
+func (tree *Tree) Merge(other *Tree, conflict func(a, b interface{}) interface{}) error {
+	if other == tree {
+		return nil
+	}
+	defer tree.lockPair(other, true)()
+	return other.walkNodes(OptWalkIPAuto, func(cidr net.IPNet, n *node) (bool, error) {
+		e, err := net2entry(cidr)
+		if err != nil {
+			return false, err
+		}
+		e.value = n.value
+		if conflict != nil {
+			if found := tree.findEntry(&e, findExact); found != nil {
+				e.value = conflict(found, n.value)
+			}
+		}
+		return true, tree.insertEntry(&e, true)
+	})
+}
+
+// lockPair locks the tree (for writing if write, otherwise for reading) and other tree for reading, in the order of
+// their addresses, so operations running on the same two trees in both directions don't deadlock.
+// Returns function unlocking both.
+func (tree *Tree) lockPair(other *Tree, write bool) func() {
+	lockTree := func() {
+		if !tree.safe {
+			return
+		}
+		if write {
+			tree.Lock()
+		} else {
+			tree.RLock()
+		}
+	}
+	lockOther := func() {
+		if other.safe {
+			other.RLock()
+		}
+	}
+	if uintptr(unsafe.Pointer(tree)) < uintptr(unsafe.Pointer(other)) {
+		lockTree()
+		lockOther()
+	} else {
+		lockOther()
+		lockTree()
+	}
+	return func() {
+		if other.safe {
+			other.RUnlock()
+		}
+		if !tree.safe {
+			return
+		}
+		if write {
+			tree.Unlock()
+		} else {
+			tree.RUnlock()
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	a := NewTree()
+	a.AddCIDR("10.0.0.0/8", 1)
+	a.AddCIDR("2001:db8::/48", 2)
+
+	b := NewTree()
+	b.AddCIDR("10.0.0.0/8", 10)
+	b.AddCIDR("10.1.0.0/16", 20)
+	b.AddCIDR("2001:db8:1::/48", 30)
+
+	err := a.Merge(b, func(x, y interface{}) interface{} { return x.(int) + y.(int) })
+	if err != nil {
+		t.Error(err)
+	}
+	for cidr, expected := range map[string]int{"10.0.0.0/8": 11, "10.1.0.0/16": 20, "2001:db8::/48": 2, "2001:db8:1::/48": 30} {
+		inf, err := a.FindExactCIDR(cidr)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != expected {
+			t.Errorf("Wrong value for %s, expected %d, got %v", cidr, expected, inf)
+		}
+	}
+	if _, valued, _, _ := a.GetStats(); valued != 4 {
+		t.Errorf("Wrong valued count, expected 4, got %v", valued)
+	}
+	if _, valued, _, _ := b.GetStats(); valued != 3 {
+		t.Errorf("Other tree changed, expected 3 values, got %v", valued)
+	}
+
+	if err = a.Merge(b, nil); err != nil {
+		t.Error(err)
+	}
+	if inf, _ := a.FindExactCIDR("10.0.0.0/8"); inf != 10 {
+		t.Errorf("Wrong value, expected 10, got %v", inf)
+	}
+}
+
+func TestMergeBothWays(t *testing.T) {
+	a := NewTree(WithLocking(true))
+	b := NewTree(WithLocking(true))
+	a.AddCIDR("10.0.0.0/8", 1)
+	b.AddCIDR("192.168.0.0/16", 2)
+
+	done := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 100; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				a.Merge(b, nil)
+			}()
+			go func() {
+				defer wg.Done()
+				b.Merge(a, nil)
+			}()
+		}
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Merges of two trees in both directions deadlocked")
+	}
+	for _, tr := range []*Tree{a, b} {
+		if _, valued, _, _ := tr.GetStats(); valued != 2 {
+			t.Errorf("Wrong valued count, expected 2, got %v", valued)
+		}
+	}
+}
+
+func TestMergeMapped(t *testing.T) {
+	a := NewTree(WithIPv4Mapped())
+	a.AddCIDR("10.0.0.0/8", 1)
+	b := NewTree()
+	b.AddCIDR("192.168.0.0/16", 2)
+	b.AddCIDR("2001:db8::/48", 3)
+
+	if err := a.Merge(b, nil); err != nil {
+		t.Fatal(err)
+	}
+	for cidr, expected := range map[string]int{"10.1.1.1": 1, "192.168.1.1": 2, "::ffff:192.168.1.1": 2, "2001:db8::1": 3} {
+		if inf, _ := a.FindCIDR(cidr); inf != expected {
+			t.Errorf("Wrong value for %s, expected %d, got %v", cidr, expected, inf)
+		}
+	}
+	if err := b.Merge(a, nil); err != nil {
+		t.Fatal(err)
+	}
+	if inf, _ := b.FindExactCIDR("10.0.0.0/8"); inf != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+}
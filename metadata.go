@@ -0,0 +1,135 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// Metadata is maintained by the tree (created with WithMetadata) for every stored value.
+type Metadata struct {
+	Created time.Time // when value was added for the IP/mask
+	Updated time.Time // when value was last added or set
+	Source  string    // source given to the last add or set, empty if none
+}
+
+// WithMetadata makes the tree keep Metadata for every value, see FindCIDRMeta and WalkTreeMeta.
+func WithMetadata() Option {
+	return func(tree *Tree) {
+		tree.metaNow = time.Now
+	}
+}
+
+// nodeMeta is kept for nodes with value when the tree maintains Metadata, counts hits or the value expires.
+type nodeMeta struct {
+	hits uint64 // updated atomically by lookups, kept first for 64-bit alignment
+	Metadata
+	expires int64 // unix nanoseconds, zero if the value does not expire
+}
+
+func (tree *Tree) touchMeta(n *node) {
+	if n.value == nil {
+		n.meta = nil
+		return
+	}
+	now := tree.metaNow()
+	if n.meta == nil {
+		n.meta = &nodeMeta{Metadata: Metadata{Created: now}}
+	}
+	n.meta.Updated = now
+	n.meta.Source = tree.metaSource
+}
+
+func copyMeta(meta *nodeMeta) *nodeMeta {
+	if meta == nil {
+		return nil
+	}
+	m := *meta
+	m.hits = atomic.LoadUint64(&meta.hits)
+	return &m
+}
+
+// AddCIDRWithSource is AddCIDR recording source in the Metadata of the value.
+func (tree *Tree) AddCIDRWithSource(cidr string, val interface{}, source string) error {
+	if zt, addr, zoned := tree.zoneTree(cidr, true); zoned {
+		return zt.AddCIDRWithSource(addr, val, source)
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	tree.metaSource = source
+	defer func() { tree.metaSource = "" }()
+	return tree.addCIDRb([]byte(cidr), val)
+}
+
+// SetCIDRWithSource is SetCIDR recording source in the Metadata of the value.
+func (tree *Tree) SetCIDRWithSource(cidr string, val interface{}, source string) error {
+	if zt, addr, zoned := tree.zoneTree(cidr, true); zoned {
+		return zt.SetCIDRWithSource(addr, val, source)
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	tree.metaSource = source
+	defer func() { tree.metaSource = "" }()
+	return tree.setCIDRb([]byte(cidr), val)
+}
+
+// FindCIDRMeta is FindCIDR also returning Metadata of the found value (zero Metadata if there is none).
+func (tree *Tree) FindCIDRMeta(cidr string) (interface{}, Metadata, error) {
+	if zt, addr, zoned := tree.zoneTree(cidr, false); zoned {
+		if zt != nil {
+			if val, meta, err := zt.FindCIDRMeta(addr); err != nil || val != nil {
+				return val, meta, err
+			}
+		}
+		cidr = addr
+	}
+	key, bits, err := tree.cidrKey(cidr)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	if tree.guard != nil {
+		tree.guard.enterRead("FindCIDRMeta")
+		defer tree.guard.exitRead()
+	}
+	n, _ := tree.bestNode(key, bits)
+	if n == nil {
+		return nil, Metadata{}, nil
+	}
+	if n.meta == nil {
+		return n.value, Metadata{}, nil
+	}
+	return n.value, n.meta.Metadata, nil
+}
+
+// WalkTreeMetaFunc is the type of function for caller of WalkTreeMeta function, see WalkTreeFunc.
+type WalkTreeMetaFunc func(cidr net.IPNet, value interface{}, meta Metadata) (bool, error)
+
+// WalkTreeMeta is WalkTree also passing Metadata of each value (zero Metadata if there is none).
+func (tree *Tree) WalkTreeMeta(opt OptWalk, wtfunc WalkTreeMetaFunc) error {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	if tree.guard != nil {
+		tree.guard.enterRead("WalkTreeMeta")
+		defer tree.guard.exitRead()
+	}
+	return tree.walkNodes(opt, func(cidr net.IPNet, n *node) (bool, error) {
+		if n.meta == nil {
+			return wtfunc(cidr, n.value, Metadata{})
+		}
+		return wtfunc(cidr, n.value, n.meta.Metadata)
+	})
+}
@@ -0,0 +1,63 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMetadata(t *testing.T) {
+	tr := NewTree(WithMetadata())
+	clock := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr.metaNow = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+
+	tr.AddCIDRWithSource("10.0.0.0/8", 1, "feed-a")
+	tr.AddCIDR("10.1.0.0/16", 2)
+	created := clock
+	tr.SetCIDRWithSource("10.0.0.0/8", 3, "feed-b")
+
+	inf, meta, err := tr.FindCIDRMeta("10.2.0.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 3 || meta.Source != "feed-b" || !meta.Created.Equal(created.Add(-time.Second)) || !meta.Updated.Equal(clock) {
+		t.Errorf("Wrong value or metadata: %v %+v", inf, meta)
+	}
+	inf, meta, _ = tr.FindCIDRMeta("10.1.0.1")
+	if inf.(int) != 2 || meta.Source != "" || !meta.Created.Equal(created) {
+		t.Errorf("Wrong value or metadata: %v %+v", inf, meta)
+	}
+	if inf, meta, _ = tr.FindCIDRMeta("11.0.0.1"); inf != nil || !meta.Created.IsZero() {
+		t.Errorf("Expected no value and metadata, got %v %+v", inf, meta)
+	}
+
+	sources := map[string]string{}
+	tr.WalkTreeMeta(OptWalkIPv4, func(cidr net.IPNet, value interface{}, meta Metadata) (bool, error) {
+		sources[cidr.String()] = meta.Source
+		return true, nil
+	})
+	if len(sources) != 2 || sources["10.0.0.0/8"] != "feed-b" || sources["10.1.0.0/16"] != "" {
+		t.Errorf("Wrong metadata in walk: %v", sources)
+	}
+
+	// metadata is dropped with the value
+	tr.DeleteCIDR("10.0.0.0/8")
+	tr.AddCIDR("10.0.0.0/8", 4)
+	if _, meta, _ = tr.FindCIDRMeta("10.2.0.1"); !meta.Created.Equal(clock) || meta.Source != "" {
+		t.Errorf("Expected fresh metadata, got %+v", meta)
+	}
+
+	// tree without metadata
+	tr = NewTree()
+	tr.AddCIDRWithSource("10.0.0.0/8", 1, "feed-a")
+	if inf, meta, _ = tr.FindCIDRMeta("10.2.0.1"); inf.(int) != 1 || meta.Source != "" {
+		t.Errorf("Expected value without metadata, got %v %+v", inf, meta)
+	}
+}
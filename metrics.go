@@ -0,0 +1,28 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"time"
+)
+
+// Metrics receives duration of every lookup ("find"), insert ("insert", "bulk insert" for a batch) and delete
+// ("delete") of the tree, e.g. to feed Prometheus histograms (their counts give the rates). Node, value and free
+// node counts are reported by Stats. Observe is called under the lock of the tree, it must be fast and safe
+// for concurrent use.
+type Metrics interface {
+	Observe(op string, d time.Duration)
+}
+
+// WithMetrics makes the tree report durations of its operations to m.
+func WithMetrics(m Metrics) Option {
+	return func(tree *Tree) {
+		tree.metrics = m
+	}
+}
+
+func (tree *Tree) observe(op string, start time.Time) {
+	tree.metrics.Observe(op, time.Since(start))
+}
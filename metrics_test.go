@@ -0,0 +1,42 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type countingMetrics struct {
+	sync.Mutex
+	ops map[string]int
+}
+
+func (m *countingMetrics) Observe(op string, d time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+	if d < 0 {
+		panic("negative duration")
+	}
+	m.ops[op]++
+}
+
+func TestMetrics(t *testing.T) {
+	m := &countingMetrics{ops: make(map[string]int)}
+	tr := NewTree(WithMetrics(m), WithIPv4Mapped())
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("2001:db8::/32", 2)
+	tr.FindCIDR("10.1.1.1")
+	tr.FindCIDR("2001:db8::1")
+	tr.FindCIDR("8.8.8.8")
+	tr.DeleteCIDR("10.0.0.0/8")
+	tr.BulkAdd([]PrefixValue{{"1.0.0.0/8", 1}, {"2.0.0.0/8", 2}})
+	for op, expected := range map[string]int{"insert": 2, "find": 3, "delete": 1, "bulk insert": 1} {
+		if m.ops[op] != expected {
+			t.Errorf("Wrong number of %s, expected %d, got %d", op, expected, m.ops[op])
+		}
+	}
+}
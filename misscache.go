@@ -0,0 +1,108 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+)
+
+// WithMissCache makes the tree remember up to size addresses whose lookups matched nothing, so repeated lookups of
+// such addresses skip tree traversal. Remembered addresses are forgotten when a covering prefix gets inserted.
+func WithMissCache(size int) Option {
+	return func(tree *Tree) {
+		if size > 0 {
+			tree.misses = &missCache{size: size, keys: make(map[missKey]struct{}, size)}
+		}
+	}
+}
+
+// missKey is an address as it is laid out in the tree: IPv4 takes first 32 bits of the key.
+type missKey struct {
+	ip [net.IPv6len]byte
+	v4 bool
+}
+
+// missCache has own lock, lookups update it while holding only read lock of the tree.
+type missCache struct {
+	size int
+	keys map[missKey]struct{}
+	sync.Mutex
+}
+
+func missKey32(key uint32) (k missKey) {
+	binary.BigEndian.PutUint32(k.ip[:], key)
+	k.v4 = true
+	return k
+}
+
+func missKey128(key net.IP) (k missKey) {
+	copy(k.ip[:], key)
+	return k
+}
+
+func (c *missCache) has(k missKey) bool {
+	c.Lock()
+	defer c.Unlock()
+	_, ok := c.keys[k]
+	return ok
+}
+
+func (c *missCache) add(k missKey) {
+	c.Lock()
+	defer c.Unlock()
+	if len(c.keys) >= c.size {
+		// drop any remembered address to make room
+		for old := range c.keys {
+			delete(c.keys, old)
+			break
+		}
+	}
+	c.keys[k] = struct{}{}
+}
+
+// invalidate forgets all addresses whose lookups would pass the node of the prefix (first bits of ip).
+func (c *missCache) invalidate(ip []byte, bits int) {
+	c.Lock()
+	defer c.Unlock()
+	for k := range c.keys {
+		if k.v4 && bits > 32 {
+			continue
+		}
+		if bitsEqual(k.ip[:], ip, bits) {
+			delete(c.keys, k)
+		}
+	}
+}
+
+func (c *missCache) invalidate32(key, mask uint32) {
+	var ip [4]byte
+	binary.BigEndian.PutUint32(ip[:], key)
+	c.invalidate(ip[:], masklen32(mask))
+}
+
+// bitsEqual compares first bits of a and b.
+func bitsEqual(a, b []byte, bits int) bool {
+	for i := 0; bits > 0; i++ {
+		if bits < 8 {
+			m := byte(0xff) << (8 - bits)
+			return a[i]&m == b[i]&m
+		}
+		if a[i] != b[i] {
+			return false
+		}
+		bits -= 8
+	}
+	return true
+}
+
+func masklen32(mask uint32) int {
+	var n int
+	for bit := startbit; bit&mask != 0; bit >>= 1 {
+		n++
+	}
+	return n
+}
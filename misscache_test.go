@@ -0,0 +1,60 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"testing"
+)
+
+func TestMissCache(t *testing.T) {
+	tr := NewTree(WithMissCache(2))
+	tr.AddCIDR("10.0.0.0/8", 1)
+
+	for _, ip := range []string{"11.1.1.1", "dead::1", "12.1.1.1"} {
+		if inf, err := tr.FindCIDR(ip); err != nil || inf != nil {
+			t.Errorf("Expected miss for %s, got %v %v", ip, inf, err)
+		}
+	}
+	if len(tr.misses.keys) != 2 {
+		t.Errorf("Miss cache is not bounded, got %d entries", len(tr.misses.keys))
+	}
+	if inf, _ := tr.FindCIDR("10.1.1.1"); inf.(int) != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+	if len(tr.misses.keys) != 2 {
+		t.Errorf("Hit was cached as a miss")
+	}
+
+	// insert into covering region forgets the miss
+	tr.FindCIDR("11.1.1.1")
+	if !tr.misses.has(missKey32(0x0b010101)) {
+		t.Fatal("Miss was not cached")
+	}
+	tr.AddCIDR("11.0.0.0/8", 2)
+	if tr.misses.has(missKey32(0x0b010101)) {
+		t.Error("Miss was not invalidated by insert")
+	}
+	if inf, _ := tr.FindCIDR("11.1.1.1"); inf == nil || inf.(int) != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+
+	tr.FindCIDR("dead::1")
+	if !tr.misses.has(missKey128(ip6("dead::1"))) {
+		t.Fatal("IPv6 miss was not cached")
+	}
+	tr.AddCIDR("dead:beef::/32", 3)
+	if !tr.misses.has(missKey128(ip6("dead::1"))) {
+		t.Error("IPv6 miss was invalidated by insert outside its region")
+	}
+	tr.AddCIDR("dead::/16", 4)
+	if inf, _ := tr.FindCIDR("dead::1"); inf == nil || inf.(int) != 4 {
+		t.Errorf("Wrong value, expected 4, got %v", inf)
+	}
+}
+
+func ip6(s string) []byte {
+	ip, _, _ := parsecidr6([]byte(s))
+	return ip
+}
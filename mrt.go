@@ -0,0 +1,165 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// ErrBadMRT is returned for truncated or malformed MRT record.
+var ErrBadMRT = errors.New("Bad MRT record")
+
+// MRT (RFC 6396) record types and subtypes read by LoadMRT.
+const (
+	mrtTableDumpV2   = 13
+	mrtRIBIPv4       = 2
+	mrtRIBIPv6       = 4
+	bgpAttrASPath    = 2
+	bgpAttrExtLength = 0x10
+	bgpASSequence    = 2
+)
+
+// BGPRoute is a route to the prefix from BGP table dump.
+type BGPRoute struct {
+	Peer   int      // index of the peer in the peer index table of the dump
+	Path   []uint32 // AS path, members of AS sets included in the order of the dump
+	Origin uint32   // last AS of the path, 0 if the path ends with AS set
+}
+
+// LoadMRT reads MRT TABLE_DUMP_V2 file (like RIPE RIS or RouteViews RIB dumps) and adds IPv4 and IPv6 unicast
+// prefixes to the tree, value stored for the prefix is value(prefix, routes of all peers). Prefixes for which
+// value returns nil are skipped, records of other types are ignored. Will return ErrBadMRT for malformed record
+// or error if value already exists, prefixes of the records before it are kept.
+func (tree *Tree) LoadMRT(r io.Reader, value func(prefix net.IPNet, routes []BGPRoute) interface{}) error {
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	var header [12]byte
+	var body bytes.Buffer
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return ErrBadMRT
+		}
+		typ, subtype := binary.BigEndian.Uint16(header[4:]), binary.BigEndian.Uint16(header[6:])
+		length := int64(binary.BigEndian.Uint32(header[8:]))
+		if typ != mrtTableDumpV2 || subtype != mrtRIBIPv4 && subtype != mrtRIBIPv6 {
+			if n, _ := io.CopyN(io.Discard, r, length); n != length {
+				return ErrBadMRT
+			}
+			continue
+		}
+		// the buffer grows with data actually read, not with the length claimed by the header
+		body.Reset()
+		if n, _ := io.CopyN(&body, r, length); n != length {
+			return ErrBadMRT
+		}
+		prefix, routes, err := parseRIB(body.Bytes(), subtype == mrtRIBIPv4)
+		if err != nil {
+			return err
+		}
+		val := value(prefix, routes)
+		if val == nil {
+			continue
+		}
+		e, err := net2entry(prefix)
+		if err != nil {
+			return err
+		}
+		e.value = val
+		if err = tree.insertEntry(&e, false); err != nil {
+			return fmt.Errorf("%s: %w", prefix.String(), err)
+		}
+	}
+}
+
+// parseRIB parses body of RIB_IPV4_UNICAST or RIB_IPV6_UNICAST record.
+func parseRIB(b []byte, v4 bool) (net.IPNet, []BGPRoute, error) {
+	size := net.IPv6len
+	if v4 {
+		size = net.IPv4len
+	}
+	if len(b) < 5 {
+		return net.IPNet{}, nil, ErrBadMRT
+	}
+	bits := int(b[4])
+	n := (bits + 7) / 8
+	if bits > size*8 || len(b) < 5+n+2 {
+		return net.IPNet{}, nil, ErrBadMRT
+	}
+	prefix := net.IPNet{IP: make(net.IP, size), Mask: net.CIDRMask(bits, size*8)}
+	copy(prefix.IP, b[5:5+n])
+	prefix.IP = prefix.IP.Mask(prefix.Mask)
+	count := int(binary.BigEndian.Uint16(b[5+n:]))
+	b = b[5+n+2:]
+
+	routes := make([]BGPRoute, 0, count)
+	for i := 0; i < count; i++ {
+		if len(b) < 8 {
+			return net.IPNet{}, nil, ErrBadMRT
+		}
+		route := BGPRoute{Peer: int(binary.BigEndian.Uint16(b))}
+		attrLen := int(binary.BigEndian.Uint16(b[6:]))
+		if len(b) < 8+attrLen {
+			return net.IPNet{}, nil, ErrBadMRT
+		}
+		var err error
+		if route.Path, route.Origin, err = parseASPath(b[8 : 8+attrLen]); err != nil {
+			return net.IPNet{}, nil, err
+		}
+		routes = append(routes, route)
+		b = b[8+attrLen:]
+	}
+	return prefix, routes, nil
+}
+
+// parseASPath finds AS_PATH among BGP path attributes and returns its ASes (4 bytes each in TABLE_DUMP_V2)
+// and the origin AS.
+func parseASPath(b []byte) (path []uint32, origin uint32, err error) {
+	for len(b) > 0 {
+		if len(b) < 3 {
+			return nil, 0, ErrBadMRT
+		}
+		flags, typ := b[0], b[1]
+		hdr, length := 3, int(b[2])
+		if flags&bgpAttrExtLength != 0 {
+			if len(b) < 4 {
+				return nil, 0, ErrBadMRT
+			}
+			hdr, length = 4, int(binary.BigEndian.Uint16(b[2:]))
+		}
+		if len(b) < hdr+length {
+			return nil, 0, ErrBadMRT
+		}
+		attr := b[hdr : hdr+length]
+		b = b[hdr+length:]
+		if typ != bgpAttrASPath {
+			continue
+		}
+		for len(attr) > 0 {
+			if len(attr) < 2 || len(attr) < 2+4*int(attr[1]) {
+				return nil, 0, ErrBadMRT
+			}
+			origin = 0
+			for j := 0; j < int(attr[1]); j++ {
+				path = append(path, binary.BigEndian.Uint32(attr[2+4*j:]))
+			}
+			if attr[0] == bgpASSequence && attr[1] > 0 {
+				origin = path[len(path)-1]
+			}
+			attr = attr[2+4*int(attr[1]):]
+		}
+		return path, origin, nil
+	}
+	return nil, 0, nil
+}
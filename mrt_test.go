@@ -0,0 +1,104 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"runtime"
+	"testing"
+)
+
+func mrtRecord(subtype uint16, body []byte) []byte {
+	b := make([]byte, 12, 12+len(body))
+	binary.BigEndian.PutUint16(b[4:], mrtTableDumpV2)
+	binary.BigEndian.PutUint16(b[6:], subtype)
+	binary.BigEndian.PutUint32(b[8:], uint32(len(body)))
+	return append(b, body...)
+}
+
+// ribBody builds RIB record of the prefix with one entry per path, AS set is appended to the path if given.
+func ribBody(prefix []byte, bits int, set []uint32, paths ...[]uint32) []byte {
+	b := []byte{0, 0, 0, 1, byte(bits)}
+	b = append(b, prefix[:(bits+7)/8]...)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(paths)))
+	for peer, path := range paths {
+		origin := []byte{0x40, 1, 1, 0}
+		seg := []byte{bgpASSequence, byte(len(path))}
+		for _, as := range path {
+			seg = binary.BigEndian.AppendUint32(seg, as)
+		}
+		if set != nil {
+			seg = append(seg, 1, byte(len(set)))
+			for _, as := range set {
+				seg = binary.BigEndian.AppendUint32(seg, as)
+			}
+		}
+		attrs := append(origin, 0x50, bgpAttrASPath)
+		attrs = binary.BigEndian.AppendUint16(attrs, uint16(len(seg)))
+		attrs = append(attrs, seg...)
+		b = binary.BigEndian.AppendUint16(b, uint16(peer))
+		b = append(b, 0, 0, 0, 0)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(attrs)))
+		b = append(b, attrs...)
+	}
+	return b
+}
+
+func TestLoadMRT(t *testing.T) {
+	var dump []byte
+	dump = append(dump, mrtRecord(1, []byte{1, 2, 3, 4, 0, 0, 0, 0})...)
+	dump = append(dump, mrtRecord(mrtRIBIPv4, ribBody([]byte{1, 1, 1, 0}, 24, nil, []uint32{3356, 13335}, []uint32{174, 13335}))...)
+	dump = append(dump, mrtRecord(mrtRIBIPv4, ribBody([]byte{10, 0, 0, 0}, 8, []uint32{65001, 65002}, []uint32{3356}))...)
+	dump = append(dump, mrtRecord(mrtRIBIPv6, ribBody(net.ParseIP("2001:db8::"), 32, nil, []uint32{6939, 64496}))...)
+
+	var paths [][]uint32
+	tr := NewTree()
+	err := tr.LoadMRT(bytes.NewReader(dump), func(prefix net.IPNet, routes []BGPRoute) interface{} {
+		for _, r := range routes {
+			paths = append(paths, r.Path)
+		}
+		return routes[0].Origin
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ip, expected := range map[string]uint32{"1.1.1.1": 13335, "10.1.2.3": 0, "2001:db8::1": 64496} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != expected {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, expected, inf)
+		}
+	}
+	if len(paths) != 4 || len(paths[1]) != 2 || paths[1][0] != 174 || len(paths[2]) != 3 {
+		t.Errorf("Wrong paths: %v", paths)
+	}
+
+	err = NewTree().LoadMRT(bytes.NewReader(dump[:len(dump)-3]), func(prefix net.IPNet, routes []BGPRoute) interface{} {
+		return true
+	})
+	if err != ErrBadMRT {
+		t.Errorf("Wrong error, expected %v, got %v", ErrBadMRT, err)
+	}
+
+	// record length claimed by the header is not allocated up front
+	huge := mrtRecord(mrtRIBIPv4, []byte{0, 0, 0, 1})
+	binary.BigEndian.PutUint32(huge[8:], 0xffffffff)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	err = NewTree().LoadMRT(bytes.NewReader(huge), func(prefix net.IPNet, routes []BGPRoute) interface{} {
+		return true
+	})
+	runtime.ReadMemStats(&after)
+	if err != ErrBadMRT {
+		t.Errorf("Wrong error, expected %v, got %v", ErrBadMRT, err)
+	}
+	if after.TotalAlloc-before.TotalAlloc > 1<<20 {
+		t.Errorf("Expected small allocation for truncated record, got %d bytes", after.TotalAlloc-before.TotalAlloc)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+)
+
+// Next returns the nearest IP/mask with value after the cidr in address order (the order of WalkTree: by address,
+// the covering IP/mask before IP/masks inside it) together with its value. IP/masks inside the cidr come after it.
+// Will return ErrNotFound if there is none.
+func (tree *Tree) Next(cidr string) (net.IPNet, interface{}, error) {
+	return tree.neighbor(cidr, true)
+}
+
+// Prev returns the nearest IP/mask with value before the cidr in address order (see Next) together with its value.
+// IP/masks covering the cidr come before it. Will return ErrNotFound if there is none.
+func (tree *Tree) Prev(cidr string) (net.IPNet, interface{}, error) {
+	return tree.neighbor(cidr, false)
+}
+
+func (tree *Tree) neighbor(cidr string, next bool) (net.IPNet, interface{}, error) {
+	key, bits, err := tree.cidrKey(cidr)
+	if err != nil {
+		return net.IPNet{}, nil, err
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	now := tree.expiryNow()
+
+	// nodes on the path of the cidr, the last one is the cidr itself or the deepest existing node covering it
+	path := make([]*node, 1, bits+1)
+	path[0] = tree.root
+	for d := 0; d < bits; d++ {
+		n := path[d].left
+		if keyBit(key, d) {
+			n = path[d].right
+		}
+		if n == nil {
+			break
+		}
+		path = append(path, n)
+	}
+	d := len(path) - 1
+	var found *node
+	if next {
+		if d == bits {
+			found = firstValued(path[d].left, now)
+			if found == nil {
+				found = firstValued(path[d].right, now)
+			}
+			d--
+		}
+		for ; found == nil && d >= 0; d-- {
+			if !keyBit(key, d) {
+				found = firstValued(path[d].right, now)
+			}
+		}
+	} else {
+		if d == bits {
+			d--
+		}
+		for ; found == nil && d >= 0; d-- {
+			if keyBit(key, d) {
+				found = lastValued(path[d].left, now)
+			}
+			if found == nil && path[d].value != nil && !expired(path[d], now) {
+				found = path[d]
+			}
+		}
+	}
+	if found == nil {
+		return net.IPNet{}, nil, ErrNotFound
+	}
+	return walkpath2net(tree.walkOpt(OptWalkIPAuto), nodeWalkpath(found)), found.value, nil
+}
+
+// firstValued returns the first node with value of the subtree in walk order.
+func firstValued(n *node, now int64) *node {
+	if n == nil || n.value != nil && !expired(n, now) {
+		return n
+	}
+	if found := firstValued(n.left, now); found != nil {
+		return found
+	}
+	return firstValued(n.right, now)
+}
+
+// lastValued returns the last node with value of the subtree in walk order.
+func lastValued(n *node, now int64) *node {
+	if n == nil {
+		return nil
+	}
+	if found := lastValued(n.right, now); found != nil {
+		return found
+	}
+	if found := lastValued(n.left, now); found != nil {
+		return found
+	}
+	if n.value != nil && !expired(n, now) {
+		return n
+	}
+	return nil
+}
+
+// nodeWalkpath returns path of the node from the root (0=left, 1=right).
+func nodeWalkpath(n *node) []byte {
+	var walkpath []byte
+	for ; n.parent != nil; n = n.parent {
+		if n.parent.right == n {
+			walkpath = append(walkpath, 1)
+		} else {
+			walkpath = append(walkpath, 0)
+		}
+	}
+	for i, j := 0, len(walkpath)-1; i < j; i, j = i+1, j-1 {
+		walkpath[i], walkpath[j] = walkpath[j], walkpath[i]
+	}
+	return walkpath
+}
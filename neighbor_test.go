@@ -0,0 +1,47 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"testing"
+)
+
+func TestNextPrev(t *testing.T) {
+	tr := NewTree()
+	for _, cidr := range []string{"10.0.0.0/8", "10.0.0.0/24", "10.0.2.0/24", "10.1.0.0/16", "192.168.0.0/16", "2001:db8::/48"} {
+		if err := tr.AddCIDR(cidr, cidr); err != nil {
+			t.Error(err)
+		}
+	}
+	for _, tc := range []struct {
+		cidr, next, prev string
+	}{
+		{"10.0.0.0/8", "10.0.0.0/24", ""},
+		{"10.0.0.5", "10.0.2.0/24", "10.0.0.0/24"},
+		{"10.0.1.0/24", "10.0.2.0/24", "10.0.0.0/24"},
+		{"10.0.2.0/24", "10.1.0.0/16", "10.0.0.0/24"},
+		{"10.0.3.0", "10.1.0.0/16", "10.0.2.0/24"},
+		{"10.2.0.0/16", "2001:db8::/48", "10.1.0.0/16"},
+		{"1.1.1.1", "10.0.0.0/8", ""},
+		{"192.168.1.1", "", "192.168.0.0/16"},
+		{"2001:db8::1", "192.168.0.0/16", "2001:db8::/48"},
+		{"2001:db9::", "192.168.0.0/16", "2001:db8::/48"},
+	} {
+		n, v, err := tr.Next(tc.cidr)
+		switch {
+		case tc.next == "" && err != ErrNotFound:
+			t.Errorf("Wrong next of %s, expected ErrNotFound, got %v, %v", tc.cidr, n.String(), err)
+		case tc.next != "" && (err != nil || n.String() != tc.next || v != tc.next):
+			t.Errorf("Wrong next of %s, expected %s, got %v, %v, %v", tc.cidr, tc.next, n.String(), v, err)
+		}
+		p, v, err := tr.Prev(tc.cidr)
+		switch {
+		case tc.prev == "" && err != ErrNotFound:
+			t.Errorf("Wrong prev of %s, expected ErrNotFound, got %v, %v", tc.cidr, p.String(), err)
+		case tc.prev != "" && (err != nil || p.String() != tc.prev || v != tc.prev):
+			t.Errorf("Wrong prev of %s, expected %s, got %v, %v, %v", tc.cidr, tc.prev, p.String(), v, err)
+		}
+	}
+}
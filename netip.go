@@ -0,0 +1,159 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+// prefixKey is netip.Prefix converted to the tree key without allocations.
+type prefixKey struct {
+	v4         bool
+	ip32, mk32 uint32
+	ip, mask   [16]byte
+}
+
+// unmapPrefix returns IPv4-mapped IPv6 prefix (of at least 96 bits) as IPv4 one if the tree is WithUnmapIPv4.
+func (tree *Tree) unmapPrefix(p netip.Prefix) netip.Prefix {
+	if tree.unmap4 && p.Addr().Is4In6() && p.Bits() >= 96 {
+		return netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+	}
+	return p
+}
+
+func (tree *Tree) netipKey(p netip.Prefix) (k prefixKey, err error) {
+	if !p.IsValid() {
+		return k, ErrBadIP
+	}
+	p = tree.unmapPrefix(p)
+	bits := p.Bits()
+	if p.Addr().Is4() {
+		b := p.Addr().As4()
+		k.v4 = true
+		k.mk32 = 0xffffffff << (32 - bits)
+		k.ip32 = binary.BigEndian.Uint32(b[:]) & k.mk32
+		return k, nil
+	}
+	k.ip = p.Addr().As16()
+	for i := range k.mask {
+		switch {
+		case bits >= 8:
+			k.mask[i] = 0xff
+			bits -= 8
+		case bits > 0:
+			k.mask[i] = 0xff << (8 - bits)
+			bits = 0
+		}
+		k.ip[i] &= k.mask[i]
+	}
+	return k, nil
+}
+
+// AddPrefix adds value associated with the prefix to the tree. Will return error for invalid prefix or if value already exists.
+func (tree *Tree) AddPrefix(p netip.Prefix, val interface{}) error {
+	return tree.insertPrefix(p, val, false)
+}
+
+// SetPrefix adds value associated with the prefix to the tree. Will return error for invalid prefix.
+func (tree *Tree) SetPrefix(p netip.Prefix, val interface{}) error {
+	return tree.insertPrefix(p, val, true)
+}
+
+func (tree *Tree) insertPrefix(p netip.Prefix, val interface{}, overwrite bool) error {
+	k, err := tree.netipKey(p)
+	if err != nil {
+		return err
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	if k.v4 {
+		return tree.insert32(k.ip32, k.mk32, val, overwrite)
+	}
+	return tree.insert(k.ip[:], k.mask[:], val, overwrite)
+}
+
+// DeletePrefix removes value associated with the prefix from the tree.
+func (tree *Tree) DeletePrefix(p netip.Prefix) error {
+	return tree.deletePrefix(p, false)
+}
+
+// DeleteWholeRangePrefix removes all values associated with IPs in the entire subnet specified by the prefix.
+func (tree *Tree) DeleteWholeRangePrefix(p netip.Prefix) error {
+	return tree.deletePrefix(p, true)
+}
+
+func (tree *Tree) deletePrefix(p netip.Prefix, wholeRange bool) error {
+	k, err := tree.netipKey(p)
+	if err != nil {
+		return err
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	if k.v4 {
+		return tree.delete32(k.ip32, k.mk32, wholeRange)
+	}
+	return tree.delete(k.ip[:], k.mask[:], wholeRange)
+}
+
+// FindAddr traverses tree to proper Node and returns previously saved information in longest covered prefix of the address.
+func (tree *Tree) FindAddr(a netip.Addr) (interface{}, error) {
+	if !a.IsValid() {
+		return nil, ErrBadIP
+	}
+	return tree.FindPrefix(netip.PrefixFrom(a, a.BitLen()))
+}
+
+// FindPrefix traverses tree to proper Node and returns previously saved information in longest covered prefix.
+func (tree *Tree) FindPrefix(p netip.Prefix) (interface{}, error) {
+	return tree.findPrefix(p, findBest)
+}
+
+// FindExactPrefix traverses tree to proper Node and returns previously saved information for an exact match.
+func (tree *Tree) FindExactPrefix(p netip.Prefix) (interface{}, error) {
+	value, err := tree.findPrefix(p, findExact)
+	if err != nil {
+		return nil, err
+	}
+	if value != nil {
+		return value, nil
+	}
+	return nil, ErrNotFound
+}
+
+// FindAllPrefix traverses tree to proper Node and returns previously saved information in all covering prefixes.
+func (tree *Tree) FindAllPrefix(p netip.Prefix) ([]interface{}, error) {
+	k, err := tree.netipKey(p)
+	if err != nil {
+		return nil, err
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	if k.v4 {
+		return tree.findAll32(k.ip32, k.mk32), nil
+	}
+	return tree.findAll(k.ip[:], k.mask[:]), nil
+}
+
+func (tree *Tree) findPrefix(p netip.Prefix, what findWhat) (interface{}, error) {
+	k, err := tree.netipKey(p)
+	if err != nil {
+		return nil, err
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	if k.v4 {
+		return tree.find32(k.ip32, k.mk32, what), nil
+	}
+	return tree.find(k.ip[:], k.mask[:], what), nil
+}
@@ -0,0 +1,80 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestNetip(t *testing.T) {
+	tr := NewTree()
+	for p, v := range map[string]int{
+		"10.0.0.0/8":      1,
+		"10.1.2.3/16":     2,
+		"dead::/16":       3,
+		"dead:beef::1/32": 4,
+	} {
+		if err := tr.AddPrefix(netip.MustParsePrefix(p), v); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := tr.AddPrefix(netip.MustParsePrefix("10.0.0.0/8"), 5); err != ErrNodeBusy {
+		t.Errorf("Expected ErrNodeBusy, got %v", err)
+	}
+	if err := tr.AddPrefix(netip.Prefix{}, 5); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Expected ErrBadIP, got %v", err)
+	}
+
+	for a, exp := range map[string]int{
+		"10.2.0.1":       1,
+		"10.1.0.1":       2,
+		"dead::1":        3,
+		"dead:beef::abc": 4,
+	} {
+		inf, err := tr.FindAddr(netip.MustParseAddr(a))
+		if err != nil {
+			t.Error(err)
+		} else if inf == nil || inf.(int) != exp {
+			t.Errorf("Wrong value for %s, expected %d, got %v", a, exp, inf)
+		}
+	}
+	if inf, _ := tr.FindAddr(netip.MustParseAddr("11.0.0.1")); inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+	if _, err := tr.FindAddr(netip.Addr{}); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Expected ErrBadIP, got %v", err)
+	}
+
+	if inf, err := tr.FindExactPrefix(netip.MustParsePrefix("10.1.0.0/16")); err != nil || inf.(int) != 2 {
+		t.Errorf("Wrong exact value, expected 2, got %v %v", inf, err)
+	}
+	if _, err := tr.FindExactPrefix(netip.MustParsePrefix("10.1.0.0/17")); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if all, _ := tr.FindAllPrefix(netip.MustParsePrefix("dead:beef:1::/48")); len(all) != 2 {
+		t.Errorf("Expected 2 values, got %v", all)
+	}
+
+	if err := tr.SetPrefix(netip.MustParsePrefix("10.0.0.0/8"), 6); err != nil {
+		t.Error(err)
+	}
+	if inf, _ := tr.FindPrefix(netip.MustParsePrefix("10.2.0.0/24")); inf.(int) != 6 {
+		t.Errorf("Wrong value, expected 6, got %v", inf)
+	}
+	if err := tr.DeletePrefix(netip.MustParsePrefix("10.1.0.0/16")); err != nil {
+		t.Error(err)
+	}
+	if inf, _ := tr.FindAddr(netip.MustParseAddr("10.1.0.1")); inf.(int) != 6 {
+		t.Errorf("Wrong value after delete, expected 6, got %v", inf)
+	}
+	if err := tr.DeleteWholeRangePrefix(netip.MustParsePrefix("dead::/16")); err != nil {
+		t.Error(err)
+	}
+	if inf, _ := tr.FindAddr(netip.MustParseAddr("dead:beef::1")); inf != nil {
+		t.Errorf("Wrong value after delete, expected nil, got %v", inf)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// ErrStaleRef is returned by NodeRef methods once the tree was changed after the NodeRef was obtained.
+var ErrStaleRef = errors.New("Stale node reference")
+
+// NodeRef is an opaque reference to a node of the tree for custom traversals. It stays valid until the next
+// insert or delete on the tree, after that its methods return ErrStaleRef.
+type NodeRef struct {
+	tree *Tree
+	n    *node
+	gen  uint64
+	b    block
+	v4   bool
+}
+
+// NodeRef returns reference to the node located exactly at the IP/mask (it may have no value).
+// Will return ErrNotFound if the tree has no such node.
+func (tree *Tree) NodeRef(cidr string) (NodeRef, error) {
+	key, bits, err := tree.cidrKey(cidr)
+	if err != nil {
+		return NodeRef{}, err
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	n := tree.root
+	for i := 0; i < bits && n != nil; i++ {
+		if keyBit(key, i) {
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	if n == nil {
+		return NodeRef{}, ErrNotFound
+	}
+	return tree.ref(n, key, bits, tree.isIPv4([]byte(cidr))), nil
+}
+
+// LookupRef returns reference to the node with the value FindCIDR would return for the cidr.
+// Will return ErrNotFound if there is no such value.
+func (tree *Tree) LookupRef(cidr string) (NodeRef, error) {
+	key, bits, err := tree.cidrKey(cidr)
+	if err != nil {
+		return NodeRef{}, err
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	n, depth := tree.bestNode(key, bits)
+	if n == nil {
+		return NodeRef{}, ErrNotFound
+	}
+	return tree.ref(n, key, depth, tree.isIPv4([]byte(cidr))), nil
+}
+
+func (tree *Tree) ref(n *node, key [16]byte, bits int, v4 bool) NodeRef {
+	return NodeRef{tree: tree, n: n, gen: tree.generation, b: keyBlock(key, bits), v4: v4}
+}
+
+// Valid tells whether the reference can still be used.
+func (r NodeRef) Valid() bool {
+	if r.tree == nil {
+		return false
+	}
+	if r.tree.safe {
+		r.tree.RLock()
+		defer r.tree.RUnlock()
+	}
+	return r.gen == r.tree.generation
+}
+
+func (r NodeRef) check() error {
+	if r.tree == nil || r.gen != r.tree.generation {
+		return ErrStaleRef
+	}
+	return nil
+}
+
+// Prefix returns the IP/mask of the node.
+func (r NodeRef) Prefix() (net.IPNet, error) {
+	if r.tree == nil {
+		return net.IPNet{}, ErrStaleRef
+	}
+	if r.tree.safe {
+		r.tree.RLock()
+		defer r.tree.RUnlock()
+	}
+	if err := r.check(); err != nil {
+		return net.IPNet{}, err
+	}
+	return r.tree.blockNet(r.b, r.v4), nil
+}
+
+// Value returns value of the node, nil if the node has no value.
+func (r NodeRef) Value() (interface{}, error) {
+	if r.tree == nil {
+		return nil, ErrStaleRef
+	}
+	if r.tree.safe {
+		r.tree.RLock()
+		defer r.tree.RUnlock()
+	}
+	if err := r.check(); err != nil {
+		return nil, err
+	}
+	return r.n.value, nil
+}
+
+// SetValue sets value of the node like SetCIDR of its IP/mask would, nil removes the value but keeps the node. It does
+// not invalidate references, unless the tree merges nodes (see WithOnlineAggregation) or evicts values (NewTreeLRU).
+func (r NodeRef) SetValue(val interface{}) error {
+	if r.tree == nil {
+		return ErrStaleRef
+	}
+	tree := r.tree
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	if err := r.check(); err != nil {
+		return err
+	}
+	if tree.lru != nil {
+		defer tree.evict()
+	}
+	if tree.metrics != nil {
+		defer tree.observe("insert", time.Now())
+	}
+	if tree.guard != nil {
+		tree.guard.enterWrite("SetValue")
+		defer tree.guard.exitWrite()
+	}
+	if tree.aggregateEqual != nil {
+		tree.generation++
+	} else {
+		tree.version++
+	}
+	if tree.misses != nil {
+		tree.misses.invalidate(r.b.ip[:], r.b.bits)
+	}
+	switch {
+	case r.n.value == nil && val != nil:
+		tree.countValuedNodes++
+	case r.n.value != nil && val == nil:
+		tree.countValuedNodes--
+	}
+	old := r.n.value
+	r.n.value = val
+	tree.changed(r.n, old, val)
+	tree.inserted(r.n)
+	return nil
+}
+
+// Parent returns reference to the parent node, ErrNotFound is returned for the root node.
+func (r NodeRef) Parent() (NodeRef, error) {
+	if r.tree == nil {
+		return NodeRef{}, ErrStaleRef
+	}
+	if r.tree.safe {
+		r.tree.RLock()
+		defer r.tree.RUnlock()
+	}
+	if err := r.check(); err != nil {
+		return NodeRef{}, err
+	}
+	if r.n.parent == nil {
+		return NodeRef{}, ErrNotFound
+	}
+	p := r
+	p.n = r.n.parent
+	p.b.bits--
+	setKeyBit(&p.b.ip, p.b.bits, false)
+	return p, nil
+}
+
+// Children returns references to existing child nodes, the left (bit 0) one first.
+// Nodes of IPv4 /32 have no children, deeper nodes belong to IPv6 (unless the tree is WithIPv4Mapped).
+func (r NodeRef) Children() ([]NodeRef, error) {
+	if r.tree == nil {
+		return nil, ErrStaleRef
+	}
+	if r.tree.safe {
+		r.tree.RLock()
+		defer r.tree.RUnlock()
+	}
+	if err := r.check(); err != nil {
+		return nil, err
+	}
+	if r.v4 && !r.tree.mapped4 && r.b.bits >= net.IPv4len*8 || r.b.bits >= net.IPv6len*8 {
+		return nil, nil
+	}
+	var ret []NodeRef
+	for _, c := range []*node{r.n.left, r.n.right} {
+		if c == nil {
+			continue
+		}
+		cr := r
+		cr.n = c
+		cr.b.bits++
+		setKeyBit(&cr.b.ip, r.b.bits, c == r.n.right)
+		ret = append(ret, cr)
+	}
+	return ret, nil
+}
@@ -0,0 +1,129 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNodeRef(t *testing.T) {
+	tr := NewTree()
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.0.0.0/16", 2)
+	tr.AddCIDR("10.128.0.0/16", 3)
+
+	r, err := tr.LookupRef("10.0.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := r.Prefix(); p.String() != "10.0.0.0/16" {
+		t.Errorf("Wrong prefix, expected 10.0.0.0/16, got %s", p.String())
+	}
+	if v, _ := r.Value(); v.(int) != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", v)
+	}
+
+	// walk up to the /8
+	for i := 0; i < 8; i++ {
+		if r, err = r.Parent(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p, _ := r.Prefix(); p.String() != "10.0.0.0/8" {
+		t.Errorf("Wrong parent prefix, expected 10.0.0.0/8, got %s", p.String())
+	}
+	if v, _ := r.Value(); v.(int) != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", v)
+	}
+	children, err := r.Children()
+	if err != nil || len(children) != 2 {
+		t.Fatalf("Expected 2 children, got %d %v", len(children), err)
+	}
+	if p, _ := children[1].Prefix(); p.String() != "10.128.0.0/9" {
+		t.Errorf("Wrong child prefix, expected 10.128.0.0/9, got %s", p.String())
+	}
+
+	if err = children[0].SetValue(5); err != nil {
+		t.Error(err)
+	}
+	if inf, _ := tr.FindExactCIDR("10.0.0.0/9"); inf.(int) != 5 {
+		t.Errorf("Wrong value after SetValue, expected 5, got %v", inf)
+	}
+	if _, values, _, _ := tr.GetStats(); values != 4 {
+		t.Errorf("Wrong valued nodes, expected 4, got %d", values)
+	}
+
+	root, err := tr.NodeRef("0.0.0.0/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = root.Parent(); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for parent of root, got %v", err)
+	}
+	if _, err = tr.NodeRef("11.0.0.0/8"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	// mutation invalidates references
+	tr.AddCIDR("11.0.0.0/8", 6)
+	if r.Valid() {
+		t.Error("Expected reference to be invalid after insert")
+	}
+	if _, err = r.Value(); err != ErrStaleRef {
+		t.Errorf("Expected ErrStaleRef, got %v", err)
+	}
+	if _, err = (NodeRef{}).Prefix(); err != ErrStaleRef {
+		t.Errorf("Expected ErrStaleRef for zero reference, got %v", err)
+	}
+}
+
+func TestNodeRefSetValueAccounting(t *testing.T) {
+	// stale TTL of the old value does not expire the new one
+	tr := NewTree()
+	clock := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr.clock = func() time.Time { return clock }
+	tr.AddCIDRWithTTL("10.0.0.0/8", 1, time.Minute)
+	ref, err := tr.NodeRef("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ref.SetValue(2); err != nil {
+		t.Error(err)
+	}
+	clock = clock.Add(2 * time.Minute)
+	if inf, _ := tr.FindCIDR("10.1.1.1"); inf != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+
+	// hits of value set through the reference are counted
+	tr = NewTree(WithHitCounting())
+	tr.AddCIDR("10.1.0.0/16", 1)
+	if ref, err = tr.NodeRef("10.0.0.0/8"); err != nil {
+		t.Fatal(err)
+	}
+	ref.SetValue(2)
+	tr.FindCIDR("10.2.0.1")
+	for _, hs := range tr.HitStats() {
+		if hs.Net.String() == "10.0.0.0/8" && hs.Hits != 1 {
+			t.Errorf("Wrong hits, expected 1, got %d", hs.Hits)
+		}
+	}
+
+	// value set through the reference counts against the LRU limit
+	tr = NewTreeLRU(2)
+	tr.AddCIDR("10.1.0.0/16", 1)
+	tr.AddCIDR("10.2.0.0/16", 2)
+	if ref, err = tr.NodeRef("10.0.0.0/8"); err != nil {
+		t.Fatal(err)
+	}
+	ref.SetValue(3)
+	if _, valued, _, _ := tr.GetStats(); valued != 2 {
+		t.Errorf("Wrong number of values, expected 2, got %d", valued)
+	}
+	if _, err = tr.FindExactCIDR("10.1.0.0/16"); err != ErrNotFound {
+		t.Errorf("Wrong error, expected %v, got %v", ErrNotFound, err)
+	}
+}
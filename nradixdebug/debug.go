@@ -0,0 +1,95 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+// Package nradixdebug exposes internals of nradix.Tree for diagnosing memory growth in production: Publish
+// publishes node, value and free node counts with expvar (served at /debug/vars) and Handler renders the shape
+// of the tree, meant to be mounted next to net/http/pprof handlers, e.g. at /debug/nradix.
+package nradixdebug
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gilwo/nradix"
+)
+
+// Publish publishes counts of the tree as expvar variable of the name, they are read (under the lock of the tree,
+// without walking it) by every request of the variable. Bytes of metadata are not included, they are shown by
+// Handler. Like expvar.Publish it panics if the name is already published.
+func Publish(name string, tree *nradix.Tree) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		st := tree.Counts()
+		return map[string]int{
+			"nodes":      st.Nodes,
+			"values":     st.ValuedNodes,
+			"allocated":  st.AllocNodes,
+			"free":       st.FreeNodes,
+			"arenaBytes": int(st.NodeBytes),
+		}
+	}))
+}
+
+// Handler returns http.Handler rendering statistics of the tree as text: counts, histogram of values by prefix
+// length and the largest subtrees. Query parameters depth (default 16) and top (default 10) select the prefix
+// length of the subtrees and how many of them are shown.
+func Handler(tree *nradix.Tree) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		depth, err := intParam(r, "depth", 16)
+		if err != nil || depth < 0 || depth > 128 {
+			http.Error(w, "bad depth", http.StatusBadRequest)
+			return
+		}
+		top, err := intParam(r, "top", 10)
+		if err != nil || top < 0 {
+			http.Error(w, "bad top", http.StatusBadRequest)
+			return
+		}
+		st := tree.Stats()
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+		fmt.Fprintf(tw, "nodes\t%d\n", st.Nodes)
+		fmt.Fprintf(tw, "values\t%d\n", st.ValuedNodes)
+		fmt.Fprintf(tw, "allocated\t%d\n", st.AllocNodes)
+		fmt.Fprintf(tw, "free\t%d\n", st.FreeNodes)
+		fmt.Fprintf(tw, "bytes\t%d\n", st.NodeBytes+st.MetaBytes)
+		fmt.Fprintf(tw, "max depth\t%d\n", st.MaxDepth)
+		fmt.Fprintf(tw, "leaves/one child/two children\t%d/%d/%d\n", st.Leaves, st.OneChild, st.TwoChildren)
+
+		tw.Flush()
+
+		fmt.Fprintf(w, "\nvalues by prefix length\n")
+		most := 0
+		for _, count := range st.DepthHistogram {
+			if count > most {
+				most = count
+			}
+		}
+		for length, count := range st.DepthHistogram {
+			if count > 0 {
+				fmt.Fprintf(tw, "/%d\t%d\t%s\n", length, count, strings.Repeat("#", (count*50+most-1)/most))
+			}
+		}
+
+		tw.Flush()
+
+		fmt.Fprintf(w, "\nlargest subtrees at /%d\n", depth)
+		fmt.Fprintf(tw, "prefix\tnodes\tvalues\n")
+		for _, sub := range tree.LargestSubtrees(depth, top) {
+			fmt.Fprintf(tw, "%s\t%d\t%d\n", sub.Net.String(), sub.Nodes, sub.ValuedNodes)
+		}
+		tw.Flush()
+	})
+}
+
+func intParam(r *http.Request, name string, def int) (int, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+	return strconv.Atoi(s)
+}
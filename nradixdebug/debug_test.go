@@ -0,0 +1,91 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradixdebug
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gilwo/nradix"
+)
+
+func TestPublish(t *testing.T) {
+	tr := nradix.NewTree()
+	Publish("nradix_test", tr)
+	tr.AddCIDR("10.0.0.0/8", 1)
+
+	var counts map[string]int
+	if err := json.Unmarshal([]byte(expvar.Get("nradix_test").String()), &counts); err != nil {
+		t.Fatal(err)
+	}
+	nodes, values, alloc, free := tr.GetStats()
+	if counts["nodes"] != nodes || counts["values"] != values || counts["allocated"] != alloc || counts["free"] != free {
+		t.Errorf("Wrong counts, expected %d %d %d %d, got %v", nodes, values, alloc, free, counts)
+	}
+	if values != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", values)
+	}
+	if st := tr.Counts(); counts["arenaBytes"] != int(st.NodeBytes) {
+		t.Errorf("Wrong arena bytes, expected %d, got %d", st.NodeBytes, counts["arenaBytes"])
+	}
+}
+
+func TestPublishConcurrentWrites(t *testing.T) {
+	tr := nradix.NewTree(nradix.WithLocking(true))
+	Publish("nradix_test_locked", tr)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			tr.AddCIDR(fmt.Sprintf("10.%d.0.0/16", i), i)
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		var counts map[string]int
+		if err := json.Unmarshal([]byte(expvar.Get("nradix_test_locked").String()), &counts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+}
+
+func TestHandler(t *testing.T) {
+	tr := nradix.NewTree()
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("192.168.0.0/16", 3)
+	tr.AddCIDR("192.168.1.0/24", 4)
+	h := Handler(tr)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/nradix?depth=8&top=1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Wrong status, expected 200, got %v", rec.Code)
+	}
+	body := rec.Body.String()
+	lines := map[string]bool{}
+	for _, line := range strings.Split(body, "\n") {
+		lines[strings.Join(strings.Fields(line), " ")] = true
+	}
+	for _, expected := range []string{"values 4", "/16 2 " + strings.Repeat("#", 50), "largest subtrees at /8", "192.0.0.0/8 17 2"} {
+		if !lines[expected] {
+			t.Errorf("Wrong output, expected line %q in:\n%s", expected, body)
+		}
+	}
+	if strings.Contains(body, "10.0.0.0/8") {
+		t.Errorf("Wrong output, expected only the largest subtree in:\n%s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/nradix?depth=x", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Wrong status, expected 400, got %v", rec.Code)
+	}
+}
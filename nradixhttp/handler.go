@@ -0,0 +1,201 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+// Package nradixhttp serves lookups and administration of nradix.Tree over HTTP:
+//
+//	GET    /lookup?ip=ADDR    longest match of the address: {"prefix": "10.0.0.0/8", "value": ...}
+//	PUT    /prefix?cidr=CIDR  sets value of the IP/mask to the JSON of the request body
+//	DELETE /prefix?cidr=CIDR  removes value of the IP/mask
+//	GET    /dump              all IP/masks with values: [{"prefix": ..., "value": ...}, ...]
+//
+// Lookups answer the default route of the tree (nradix.WithDefaultRoute) as the /0 prefix of the address family.
+// Lookups matching nothing and deletes of IP/masks without value answer 404, bad addresses 400, request bodies
+// larger than the limit (see WithMaxBodySize) 413.
+package nradixhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gilwo/nradix"
+)
+
+// Handler is http.Handler of the tree, the tree should be created with locking (nradix.WithLocking)
+// as requests are served concurrently.
+type Handler struct {
+	tree    *nradix.Tree
+	encode  func(value interface{}) (json.RawMessage, error)
+	decode  func(data json.RawMessage) (interface{}, error)
+	maxBody int64
+	mux     *http.ServeMux
+}
+
+// Option configures optional behaviour of the Handler.
+type Option func(*Handler)
+
+// WithValueCodec sets functions encoding values into JSON of responses and decoding them from JSON of requests,
+// by default values are encoding/json values (decoded into interface{}).
+func WithValueCodec(encode func(value interface{}) (json.RawMessage, error), decode func(data json.RawMessage) (interface{}, error)) Option {
+	return func(h *Handler) {
+		h.encode, h.decode = encode, decode
+	}
+}
+
+// WithMaxBodySize sets the limit of request body size in bytes, 1 MiB by default.
+func WithMaxBodySize(n int64) Option {
+	return func(h *Handler) {
+		h.maxBody = n
+	}
+}
+
+// NewHandler creates Handler of the tree.
+func NewHandler(tree *nradix.Tree, opts ...Option) *Handler {
+	h := &Handler{
+		tree: tree,
+		encode: func(value interface{}) (json.RawMessage, error) {
+			return json.Marshal(value)
+		},
+		decode: func(data json.RawMessage) (interface{}, error) {
+			var value interface{}
+			err := json.Unmarshal(data, &value)
+			return value, err
+		},
+		maxBody: 1 << 20,
+		mux:     http.NewServeMux(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.mux.HandleFunc("/lookup", h.lookup)
+	h.mux.HandleFunc("/prefix", h.prefix)
+	h.mux.HandleFunc("/dump", h.dump)
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+type entry struct {
+	Prefix string          `json:"prefix"`
+	Value  json.RawMessage `json:"value"`
+}
+
+func (h *Handler) lookup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ip := r.URL.Query().Get("ip")
+	value, prefix, err := h.tree.FindCIDRNet(ip)
+	if err == nil && value == nil {
+		// FindCIDRNet does not see the default route
+		value = h.tree.DefaultRoute()
+		prefix = defaultPrefix(ip)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if value == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	h.writeJSON(w, prefix, value)
+}
+
+// defaultPrefix returns the default route IP/mask of the address family of the ip (IPv4-mapped being IPv4).
+func defaultPrefix(ip string) net.IPNet {
+	if i := strings.IndexByte(ip, '/'); i >= 0 {
+		ip = ip[:i]
+	}
+	if a, err := netip.ParseAddr(ip); err == nil && a.Unmap().Is4() {
+		return net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 8*net.IPv4len)}
+	}
+	return net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 8*net.IPv6len)}
+}
+
+func (h *Handler) prefix(w http.ResponseWriter, r *http.Request) {
+	cidr := r.URL.Query().Get("cidr")
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxBody))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		value, err := h.decode(data)
+		if err != nil || value == nil {
+			http.Error(w, "bad value", http.StatusBadRequest)
+			return
+		}
+		if err = h.tree.SetCIDR(cidr, value); err != nil {
+			writeError(w, err)
+			return
+		}
+	case http.MethodDelete:
+		if err := h.tree.DeleteCIDR(cidr); err != nil {
+			writeError(w, err)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) dump(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	entries := []entry{}
+	err := h.tree.WalkTree(nradix.OptWalkIPAuto, func(cidr net.IPNet, value interface{}) (bool, error) {
+		data, err := h.encode(value)
+		if err != nil {
+			return false, err
+		}
+		entries = append(entries, entry{Prefix: cidr.String(), Value: data})
+		return true, nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, prefix net.IPNet, value interface{}) {
+	data, err := h.encode(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry{Prefix: prefix.String(), Value: data})
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, nradix.ErrBadIP):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case err == nradix.ErrNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
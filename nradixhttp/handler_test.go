@@ -0,0 +1,99 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradixhttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gilwo/nradix"
+)
+
+func TestHandler(t *testing.T) {
+	tree := nradix.NewTree(nradix.WithLocking(true))
+	srv := httptest.NewServer(NewHandler(tree))
+	defer srv.Close()
+
+	do := func(method, path, body string) (int, string) {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, strings.TrimSpace(string(data))
+	}
+
+	for _, tc := range []struct {
+		method, path, body string
+		code               int
+		resp               string
+	}{
+		{"PUT", "/prefix?cidr=10.0.0.0/8", `{"owner":"a"}`, 204, ""},
+		{"PUT", "/prefix?cidr=10.1.0.0/16", `"b"`, 204, ""},
+		{"PUT", "/prefix?cidr=2001:db8::/48", `3`, 204, ""},
+		{"PUT", "/prefix?cidr=10.2.0.0/16", `{bad`, 400, "bad value"},
+		{"PUT", "/prefix?cidr=10.2.0.300/16", `1`, 400, ""},
+		{"GET", "/lookup?ip=10.1.2.3", "", 200, `{"prefix":"10.1.0.0/16","value":"b"}`},
+		{"GET", "/lookup?ip=10.2.2.3", "", 200, `{"prefix":"10.0.0.0/8","value":{"owner":"a"}}`},
+		{"GET", "/lookup?ip=2001:db8::1", "", 200, `{"prefix":"2001:db8::/48","value":3}`},
+		{"GET", "/lookup?ip=11.0.0.1", "", 404, "not found"},
+		{"GET", "/lookup?ip=bad", "", 400, ""},
+		{"DELETE", "/prefix?cidr=10.1.0.0/16", "", 204, ""},
+		{"DELETE", "/prefix?cidr=10.1.0.0/16", "", 404, ""},
+		{"POST", "/prefix?cidr=10.1.0.0/16", "", 405, ""},
+		{"GET", "/dump", "", 200, `[{"prefix":"10.0.0.0/8","value":{"owner":"a"}},{"prefix":"2001:db8::/48","value":3}]`},
+	} {
+		code, resp := do(tc.method, tc.path, tc.body)
+		if code != tc.code {
+			t.Errorf("Wrong status of %s %s, expected %d, got %d (%s)", tc.method, tc.path, tc.code, code, resp)
+		}
+		if tc.resp != "" && resp != tc.resp {
+			t.Errorf("Wrong response of %s %s, expected %s, got %s", tc.method, tc.path, tc.resp, resp)
+		}
+	}
+}
+
+func TestHandlerDefaultRouteAndBodyLimit(t *testing.T) {
+	tree := nradix.NewTree(nradix.WithLocking(true), nradix.WithDefaultRoute("default"), nradix.WithUnmapIPv4())
+	tree.AddCIDR("10.0.0.0/8", "ten")
+	h := NewHandler(tree, WithMaxBodySize(16))
+
+	for ip, expected := range map[string]string{
+		"10.1.1.1":        `{"prefix":"10.0.0.0/8","value":"ten"}`,
+		"11.1.1.1":        `{"prefix":"0.0.0.0/0","value":"default"}`,
+		"2001:db8::1":     `{"prefix":"::/0","value":"default"}`,
+		"::ffff:11.1.1.1": `{"prefix":"0.0.0.0/0","value":"default"}`,
+		"::ffff:10.1.1.1": `{"prefix":"10.0.0.0/8","value":"ten"}`,
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/lookup?ip="+ip, nil))
+		if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != expected {
+			t.Errorf("Wrong lookup of %s, expected %s, got %d %s", ip, expected, rec.Code, rec.Body.String())
+		}
+		if inf, _ := tree.FindCIDR(ip); !strings.Contains(expected, `"`+inf.(string)+`"`) {
+			t.Errorf("Lookup of %s does not match FindCIDR %v", ip, inf)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/prefix?cidr=12.0.0.0/8", strings.NewReader(`"`+strings.Repeat("x", 32)+`"`)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Wrong status, expected 413, got %d", rec.Code)
+	}
+	if inf, _ := tree.FindExactCIDR("12.0.0.0/8"); inf != nil {
+		t.Errorf("Expected nothing set, got %v", inf)
+	}
+}
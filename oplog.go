@@ -0,0 +1,113 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+)
+
+// ErrBadOpLog is returned for truncated or malformed record of the mutation log.
+var ErrBadOpLog = errors.New("Bad mutation log record")
+
+// EmitOps writes every change of values of the tree (see OnChange) to w as a record of the mutation log: a byte of
+// Op, the IP/mask (uvarint length and text) and, unless the value is deleted, the value (uvarint length and bytes
+// encoded by the codec of WithBinaryValueCodec, gob by default). Applying the log to a copy of the tree by ApplyOps
+// makes it mirror the tree. Expiration and the default route are not logged. The returned stop function ends
+// logging and returns the first error of writing the log, changes are not logged after it.
+func (tree *Tree) EmitOps(w io.Writer) (stop func() error) {
+	encode := tree.binaryEncode
+	if encode == nil {
+		encode = gobEncodeValue
+	}
+	var werr error
+	var record []byte
+	var buf [binary.MaxVarintLen64]byte
+	cancel := tree.OnChange(func(op Op, cidr net.IPNet, old, new interface{}) {
+		if werr != nil {
+			return
+		}
+		record = append(record[:0], byte(op))
+		s := cidr.String()
+		record = append(record, buf[:binary.PutUvarint(buf[:], uint64(len(s)))]...)
+		record = append(record, s...)
+		if op != OpDelete {
+			data, err := encode(new)
+			if err != nil {
+				werr = err
+				return
+			}
+			record = append(record, buf[:binary.PutUvarint(buf[:], uint64(len(data)))]...)
+			record = append(record, data...)
+		}
+		_, werr = w.Write(record)
+	})
+	return func() error {
+		cancel()
+		return werr
+	}
+}
+
+// ApplyOps applies records of the mutation log written by EmitOps from r until its end: added and set values are
+// set, deleted ones are removed (IP/masks having no value are skipped). Values are decoded by the codec of
+// WithBinaryValueCodec (gob by default). Will return ErrBadOpLog for malformed record, records before it are applied.
+func (tree *Tree) ApplyOps(r io.Reader) error {
+	decode := tree.binaryDecode
+	if decode == nil {
+		decode = gobDecodeValue
+	}
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		op := Op(b)
+		if op != OpAdd && op != OpSet && op != OpDelete {
+			return ErrBadOpLog
+		}
+		cidr, err := readOpField(br)
+		if err != nil {
+			return err
+		}
+		if op == OpDelete {
+			err = tree.DeleteCIDR(string(cidr))
+			if err != nil && err != ErrNotFound {
+				return err
+			}
+			continue
+		}
+		data, err := readOpField(br)
+		if err != nil {
+			return err
+		}
+		val, err := decode(data)
+		if err != nil {
+			return err
+		}
+		if err = tree.SetCIDR(string(cidr), val); err != nil {
+			return err
+		}
+	}
+}
+
+// readOpField reads field of the record prefixed by its uvarint length.
+func readOpField(br *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(br)
+	if err != nil || n > 1<<30 {
+		return nil, ErrBadOpLog
+	}
+	data := make([]byte, n)
+	if _, err = io.ReadFull(br, data); err != nil {
+		return nil, ErrBadOpLog
+	}
+	return data, nil
+}
@@ -0,0 +1,60 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestOpLog(t *testing.T) {
+	src := NewTree()
+	src.AddCIDR("10.0.0.0/8", 1)
+	mirror := src.Clone()
+
+	var log bytes.Buffer
+	stop := src.EmitOps(&log)
+	src.AddCIDR("10.1.0.0/16", 2)
+	src.SetCIDR("10.0.0.0/8", "one")
+	src.AddCIDR("2001:db8::/48", 3)
+	src.AddCIDR("192.168.0.0/16", 4)
+	src.AddCIDR("192.168.1.0/24", 5)
+	src.DeleteWholeRangeCIDR("192.168.0.0/16")
+	src.DeleteCIDR("10.1.0.0/16")
+	if err := stop(); err != nil {
+		t.Error(err)
+	}
+	src.AddCIDR("172.16.0.0/12", 6) // not logged
+
+	if err := mirror.ApplyOps(bytes.NewReader(log.Bytes())); err != nil {
+		t.Error(err)
+	}
+	src.DeleteCIDR("172.16.0.0/12")
+	if src.Checksum(nil) != mirror.Checksum(nil) {
+		t.Error("Wrong mirror, expected checksum of the source tree")
+	}
+	if inf, _ := mirror.FindCIDR("10.1.2.3"); inf != "one" {
+		t.Errorf("Wrong value, expected one, got %v", inf)
+	}
+
+	// records before a malformed one are applied
+	data := append(append([]byte(nil), log.Bytes()...), byte(OpSet), 5, '1')
+	if err := NewTree().ApplyOps(bytes.NewReader(data)); err != ErrBadOpLog {
+		t.Errorf("Wrong error, expected %v, got %v", ErrBadOpLog, err)
+	}
+	if err := NewTree().ApplyOps(bytes.NewReader([]byte{9})); err != ErrBadOpLog {
+		t.Errorf("Wrong error, expected %v, got %v", ErrBadOpLog, err)
+	}
+
+	// encoding errors stop the log
+	failing := errors.New("fail")
+	tr := NewTree(WithBinaryValueCodec(func(value interface{}) ([]byte, error) { return nil, failing }, nil))
+	stop = tr.EmitOps(&log)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	if err := stop(); err != failing {
+		t.Errorf("Wrong error, expected %v, got %v", failing, err)
+	}
+}
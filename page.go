@@ -0,0 +1,104 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrBadToken is returned by WalkPage for malformed resume token.
+var ErrBadToken = errors.New("Bad resume token")
+
+// ResumeToken marks where WalkPage stopped, it is an opaque string safe to hand out over APIs. Empty token is
+// the start of the tree.
+type ResumeToken string
+
+// WalkPage returns up to limit (all if not positive) IP/masks with values following the start token in the order
+// of WalkTree (with OptWalkIPAuto), and the token to resume from, empty when the walk is complete. The lock is held
+// only for the page, so huge trees can be dumped page by page; IP/masks changed between pages are seen only if
+// they follow the token.
+func (tree *Tree) WalkPage(start ResumeToken, limit int) ([]NetValue, ResumeToken, error) {
+	key, bits, resume, err := parseToken(start)
+	if err != nil {
+		return nil, "", err
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	opt := tree.walkOpt(OptWalkIPAuto)
+	now := tree.expiryNow()
+	var ret []NetValue
+	var last []byte
+	more := false
+	walkpath := make([]byte, 0, 128)
+
+	// visit walks the subtree of n, after tells that the subtree follows the token (otherwise n is on its path)
+	var visit func(n *node, after bool) bool
+	visit = func(n *node, after bool) bool {
+		d := len(walkpath)
+		if after && n.value != nil && !expired(n, now) {
+			if limit > 0 && len(ret) == limit {
+				more = true
+				return false
+			}
+			ret = append(ret, NetValue{Net: walkpath2net(opt, walkpath), Value: n.value})
+			last = append(last[:0], walkpath...)
+		}
+		for bit, c := range []*node{n.left, n.right} {
+			if c == nil {
+				continue
+			}
+			childAfter := after || d >= bits
+			if !childAfter && keyBit(key, d) != (bit == 1) {
+				if bit == 0 {
+					// branch left of the token path was walked already
+					continue
+				}
+				childAfter = true
+			}
+			walkpath = append(walkpath[:d], byte(bit))
+			if !visit(c, childAfter) {
+				return false
+			}
+		}
+		return true
+	}
+	visit(tree.root, !resume)
+	if !more {
+		return ret, "", nil
+	}
+	return ret, makeToken(last), nil
+}
+
+// makeToken encodes walkpath as "bits:hex key".
+func makeToken(walkpath []byte) ResumeToken {
+	var key [16]byte
+	for i, b := range walkpath {
+		setKeyBit(&key, i, b != 0)
+	}
+	return ResumeToken(strconv.Itoa(len(walkpath)) + ":" + hex.EncodeToString(key[:(len(walkpath)+7)/8]))
+}
+
+func parseToken(t ResumeToken) (key [16]byte, bits int, ok bool, err error) {
+	if t == "" {
+		return key, 0, false, nil
+	}
+	s, h, found := strings.Cut(string(t), ":")
+	bits, err = strconv.Atoi(s)
+	if !found || err != nil || bits < 0 || bits > len(key)*8 {
+		return key, 0, false, fmt.Errorf("%w: %q", ErrBadToken, t)
+	}
+	b, err := hex.DecodeString(h)
+	if err != nil || len(b) != (bits+7)/8 {
+		return key, 0, false, fmt.Errorf("%w: %q", ErrBadToken, t)
+	}
+	copy(key[:], b)
+	return key, bits, true, nil
+}
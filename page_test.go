@@ -0,0 +1,79 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestWalkPage(t *testing.T) {
+	tr := NewTree()
+	FillRandom(tr, 1000)
+	tr.AddCIDR("0.0.0.0/0", "root")
+	var expected []string
+	tr.WalkTree(OptWalkIPAuto, func(cidr net.IPNet, value interface{}) (bool, error) {
+		expected = append(expected, cidr.String())
+		return true, nil
+	})
+
+	for _, limit := range []int{1, 7, 100, 1001, 5000} {
+		var walked []string
+		var token ResumeToken
+		pages := 0
+		for {
+			page, next, err := tr.WalkPage(token, limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page) > limit {
+				t.Fatalf("Wrong page size, expected at most %d, got %d", limit, len(page))
+			}
+			for _, nv := range page {
+				walked = append(walked, nv.Net.String())
+			}
+			pages++
+			if next == "" {
+				break
+			}
+			token = next
+		}
+		if len(walked) != len(expected) {
+			t.Fatalf("Wrong number of walked with limit %d, expected %d, got %d", limit, len(expected), len(walked))
+		}
+		for i := range expected {
+			if walked[i] != expected[i] {
+				t.Fatalf("Wrong walk with limit %d at %d, expected %s, got %s", limit, i, expected[i], walked[i])
+			}
+		}
+		if want := (len(expected) + limit - 1) / limit; pages != want {
+			t.Errorf("Wrong number of pages with limit %d, expected %d, got %d", limit, want, pages)
+		}
+	}
+
+	// changes between pages: removed token and IP/masks added after it
+	page, token, _ := tr.WalkPage("", 10)
+	tr.DeleteCIDR(page[9].Net.String())
+	tr.AddCIDR("255.255.255.255/32", "last")
+	var rest []NetValue
+	for token != "" {
+		var p []NetValue
+		p, token, _ = tr.WalkPage(token, 100)
+		rest = append(rest, p...)
+	}
+	if len(rest) != len(expected)-9 || rest[len(rest)-1].Value != "last" {
+		t.Errorf("Wrong walk after changes, expected %d IP/masks ending with 255.255.255.255/32, got %d", len(expected)-9, len(rest))
+	}
+
+	for _, bad := range []ResumeToken{"x", "8:", "8:0a0b", "200:00", "-1:"} {
+		if _, _, err := tr.WalkPage(bad, 1); !errors.Is(err, ErrBadToken) {
+			t.Errorf("Wrong error for %q, expected %v, got %v", bad, ErrBadToken, err)
+		}
+	}
+	if page, token, err := NewTree().WalkPage("", 10); len(page) != 0 || token != "" || err != nil {
+		t.Errorf("Wrong page of empty tree, got %v %q %v", page, token, err)
+	}
+}
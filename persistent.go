@@ -0,0 +1,220 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+// PersistentTree is an immutable radix tree: changes return a new tree sharing all nodes off the changed path
+// with the old one, so every version stays valid and can be used from many goroutines without locking.
+// The zero PersistentTree is an empty tree. IPv4 takes the first 32 bits of the key like in Tree.
+type PersistentTree struct {
+	root  *pnode
+	count int
+}
+
+type pnode struct {
+	left, right *pnode
+	value       interface{}
+}
+
+// Len returns number of values of the tree.
+func (t *PersistentTree) Len() int {
+	return t.count
+}
+
+// AddCIDR returns the tree with value associated with IP/mask added. Will return error for invalid CIDR
+// or if value already exists.
+func (t *PersistentTree) AddCIDR(cidr string, val interface{}) (*PersistentTree, error) {
+	return t.set(cidr, val, false)
+}
+
+// SetCIDR returns the tree with value associated with IP/mask set. Will return error for invalid CIDR.
+func (t *PersistentTree) SetCIDR(cidr string, val interface{}) (*PersistentTree, error) {
+	return t.set(cidr, val, true)
+}
+
+func (t *PersistentTree) set(cidr string, val interface{}, overwrite bool) (*PersistentTree, error) {
+	key, bits, err := cidrKey(cidr)
+	if err != nil {
+		return t, err
+	}
+	if val == nil {
+		return t.delete(key, bits, overwrite)
+	}
+	path := t.path(key, bits)
+	n := path[len(path)-1]
+	count := t.count
+	switch {
+	case len(path) <= bits || n.value == nil:
+		count++
+	case !overwrite:
+		return t, ErrNodeBusy
+	}
+	leaf := &pnode{value: val}
+	if len(path) > bits {
+		leaf.left, leaf.right = n.left, n.right
+		path = path[:bits]
+	}
+	// nodes missing below the path
+	for d := bits - 1; d >= len(path); d-- {
+		parent := new(pnode)
+		if keyBit(key, d) {
+			parent.right = leaf
+		} else {
+			parent.left = leaf
+		}
+		leaf = parent
+	}
+	return &PersistentTree{root: copyPath(path, key, leaf), count: count}, nil
+}
+
+// DeleteCIDR returns the tree with value associated with IP/mask removed. Will return error for invalid CIDR
+// or ErrNotFound if there is no such value.
+func (t *PersistentTree) DeleteCIDR(cidr string) (*PersistentTree, error) {
+	key, bits, err := cidrKey(cidr)
+	if err != nil {
+		return t, err
+	}
+	return t.delete(key, bits, false)
+}
+
+// delete removes the value, missing value is not an error if quiet.
+func (t *PersistentTree) delete(key [16]byte, bits int, quiet bool) (*PersistentTree, error) {
+	path := t.path(key, bits)
+	n := path[len(path)-1]
+	if len(path) <= bits || n.value == nil {
+		if quiet {
+			return t, nil
+		}
+		return t, ErrNotFound
+	}
+	var leaf *pnode
+	if n.left != nil || n.right != nil {
+		leaf = &pnode{left: n.left, right: n.right}
+	}
+	// drop nodes left without value and children
+	path = path[:bits]
+	for leaf == nil && len(path) > 1 {
+		p := path[len(path)-1]
+		sibling := p.left
+		if !keyBit(key, len(path)-1) {
+			sibling = p.right
+		}
+		if p.value != nil || sibling != nil {
+			break
+		}
+		path = path[:len(path)-1]
+	}
+	if leaf == nil && len(path) > 0 {
+		// cut the child of the last kept node
+		p := path[len(path)-1]
+		c := &pnode{left: p.left, right: p.right, value: p.value}
+		if keyBit(key, len(path)-1) {
+			c.right = nil
+		} else {
+			c.left = nil
+		}
+		leaf = c
+		path = path[:len(path)-1]
+	}
+	return &PersistentTree{root: copyPath(path, key, leaf), count: t.count - 1}, nil
+}
+
+// path returns nodes on the path of the key down to depth of bits (or to the deepest existing one),
+// path[d] is at depth d. Empty tree has an empty root.
+func (t *PersistentTree) path(key [16]byte, bits int) []*pnode {
+	n := t.root
+	if n == nil {
+		n = new(pnode)
+	}
+	path := []*pnode{n}
+	for d := 0; d < bits; d++ {
+		if keyBit(key, d) {
+			n = n.right
+		} else {
+			n = n.left
+		}
+		if n == nil {
+			break
+		}
+		path = append(path, n)
+	}
+	return path
+}
+
+// copyPath returns copy of the root with the nodes of path copied and node put below the last of them
+// (node replaces the root if path is empty).
+func copyPath(path []*pnode, key [16]byte, n *pnode) *pnode {
+	for d := len(path) - 1; d >= 0; d-- {
+		c := *path[d]
+		if keyBit(key, d) {
+			c.right = n
+		} else {
+			c.left = n
+		}
+		n = &c
+	}
+	if n == nil {
+		n = new(pnode)
+	}
+	return n
+}
+
+// FindCIDR returns the value of the longest IP/mask covering the cidr, see Tree.FindCIDR.
+func (t *PersistentTree) FindCIDR(cidr string) (interface{}, error) {
+	key, bits, err := cidrKey(cidr)
+	if err != nil {
+		return nil, err
+	}
+	var ret interface{}
+	n := t.root
+	for d := 0; n != nil; d++ {
+		if n.value != nil {
+			ret = n.value
+		}
+		if d == bits {
+			break
+		}
+		if keyBit(key, d) {
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	return ret, nil
+}
+
+// FindExactCIDR returns the value of exactly the cidr. Will return ErrNotFound if there is none.
+func (t *PersistentTree) FindExactCIDR(cidr string) (interface{}, error) {
+	key, bits, err := cidrKey(cidr)
+	if err != nil {
+		return nil, err
+	}
+	path := t.path(key, bits)
+	if n := path[len(path)-1]; len(path) > bits && n.value != nil {
+		return n.value, nil
+	}
+	return nil, ErrNotFound
+}
+
+// WalkTree walks IP/masks with values in address order, see Tree.WalkTree (order bits of opt are ignored).
+func (t *PersistentTree) WalkTree(opt OptWalk, wtfunc WalkTreeFunc) error {
+	_, err := walkPersistent(t.root, opt, make([]byte, 0, 128), wtfunc)
+	return err
+}
+
+func walkPersistent(n *pnode, opt OptWalk, walkpath []byte, wtfunc WalkTreeFunc) (bool, error) {
+	if n == nil {
+		return true, nil
+	}
+	if n.value != nil {
+		goDeeper, err := wtfunc(walkpath2net(opt, walkpath), n.value)
+		if err != nil || !goDeeper {
+			return err == nil, err
+		}
+	}
+	if ok, err := walkPersistent(n.left, opt, append(walkpath, 0), wtfunc); !ok {
+		return false, err
+	}
+	return walkPersistent(n.right, opt, append(walkpath, 1), wtfunc)
+}
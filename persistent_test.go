@@ -0,0 +1,95 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func walkedPersistent(t *PersistentTree) string {
+	var walked []string
+	t.WalkTree(OptWalkIPAuto, func(cidr net.IPNet, value interface{}) (bool, error) {
+		walked = append(walked, fmt.Sprintf("%s=%v", cidr.String(), value))
+		return true, nil
+	})
+	return fmt.Sprint(walked)
+}
+
+func TestPersistentTree(t *testing.T) {
+	var empty PersistentTree
+	v1, err := empty.AddCIDR("10.0.0.0/8", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2, err := v1.AddCIDR("10.1.0.0/16", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v3, err := v2.SetCIDR("10.0.0.0/8", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v4, err := v3.DeleteCIDR("10.1.0.0/16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = v4.AddCIDR("10.0.0.0/8", 4); err != ErrNodeBusy {
+		t.Errorf("Wrong error, expected %v, got %v", ErrNodeBusy, err)
+	}
+	if _, err = v4.DeleteCIDR("10.1.0.0/16"); err != ErrNotFound {
+		t.Errorf("Wrong error, expected %v, got %v", ErrNotFound, err)
+	}
+
+	for _, tc := range []struct {
+		tree     *PersistentTree
+		count    int
+		expected string
+	}{
+		{&empty, 0, "[]"},
+		{v1, 1, "[10.0.0.0/8=1]"},
+		{v2, 2, "[10.0.0.0/8=1 10.1.0.0/16=2]"},
+		{v3, 2, "[10.0.0.0/8=3 10.1.0.0/16=2]"},
+		{v4, 1, "[10.0.0.0/8=3]"},
+	} {
+		if walked := walkedPersistent(tc.tree); walked != tc.expected || tc.tree.Len() != tc.count {
+			t.Errorf("Wrong version, expected %s (%d), got %s (%d)", tc.expected, tc.count, walked, tc.tree.Len())
+		}
+	}
+	if inf, _ := v2.FindCIDR("10.1.1.1"); inf != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+	if inf, _ := v4.FindCIDR("10.1.1.1"); inf != 3 {
+		t.Errorf("Wrong value, expected 3, got %v", inf)
+	}
+	if inf, err := v3.FindExactCIDR("10.1.0.0/16"); err != nil || inf != 2 {
+		t.Errorf("Wrong exact value, expected 2, got %v, %v", inf, err)
+	}
+	if v3.root == v2.root || v3.root.left == v2.root.left {
+		t.Errorf("Changed path is shared")
+	}
+
+	// unchanged subtrees are shared
+	v5, _ := v2.AddCIDR("192.168.0.0/16", 5)
+	if v5.root.left != v2.root.left {
+		t.Errorf("Unchanged subtree is not shared")
+	}
+
+	// deleting the only value leaves the tree empty
+	v6, _ := v1.DeleteCIDR("10.0.0.0/8")
+	if v6.root.left != nil || v6.root.right != nil || v6.Len() != 0 {
+		t.Errorf("Deleted path is kept")
+	}
+	v7, _ := v2.DeleteCIDR("10.0.0.0/8")
+	if walked := walkedPersistent(v7); walked != "[10.1.0.0/16=2]" {
+		t.Errorf("Wrong tree, expected [10.1.0.0/16=2], got %s", walked)
+	}
+	v8, _ := v7.SetCIDR("2001:db8::/48", 8)
+	v8, _ = v8.SetCIDR("::/0", 0)
+	if walked := walkedPersistent(v8); walked != "[0.0.0.0/0=0 10.1.0.0/16=2 2001:db8::/48=8]" {
+		t.Errorf("Wrong tree, got %s", walked)
+	}
+}
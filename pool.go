@@ -0,0 +1,153 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"sync"
+)
+
+// poolBatch is number of nodes a tree takes from its pool at once.
+const poolBatch = 64
+
+// Pool keeps free nodes shared by trees created WithPool, so short-lived trees reuse nodes of each other instead of
+// growing arenas of their own. Nodes go back to the pool by Release of the tree (or Compact of the tree for its free
+// nodes). Pool is safe for concurrent use by trees.
+type Pool struct {
+	chunk     int
+	free      *node
+	freeCount int
+	allocated int
+	taken     int
+	sync.Mutex
+}
+
+// PoolStats are statistics of the Pool.
+type PoolStats struct {
+	Allocated int // nodes allocated by the pool
+	Free      int // nodes in the pool, ready for trees
+	Taken     int // nodes taken by trees since the pool was created
+}
+
+// NewPool creates Pool allocating nodes in chunks of chunk (at least poolBatch) nodes.
+func NewPool(chunk int) *Pool {
+	if chunk < poolBatch {
+		chunk = poolBatch
+	}
+	return &Pool{chunk: chunk}
+}
+
+// WithPool makes the tree take its nodes from the pool.
+func WithPool(p *Pool) Option {
+	return func(tree *Tree) {
+		tree.pool = p
+	}
+}
+
+// Stats returns statistics of the pool.
+func (p *Pool) Stats() PoolStats {
+	p.Lock()
+	defer p.Unlock()
+	return PoolStats{Allocated: p.allocated, Free: p.freeCount, Taken: p.taken}
+}
+
+// Drain drops all free nodes of the pool, so their memory can be returned to the runtime once trees release
+// the rest of their chunks, and returns how many nodes were dropped.
+func (p *Pool) Drain() int {
+	p.Lock()
+	defer p.Unlock()
+	n := p.freeCount
+	p.free, p.freeCount = nil, 0
+	p.allocated -= n
+	return n
+}
+
+// get returns list (linked by right) of n free nodes.
+func (p *Pool) get(n int) *node {
+	p.Lock()
+	defer p.Unlock()
+	for p.freeCount < n {
+		chunk := make([]node, p.chunk)
+		for i := range chunk {
+			chunk[i].right = p.free
+			p.free = &chunk[i]
+		}
+		p.freeCount += p.chunk
+		p.allocated += p.chunk
+	}
+	head := p.free
+	last := head
+	for i := 1; i < n; i++ {
+		last = last.right
+	}
+	p.free = last.right
+	last.right = nil
+	p.freeCount -= n
+	p.taken += n
+	return head
+}
+
+// put returns list (linked by right) of n free nodes to the pool.
+func (p *Pool) put(head *node, n int) {
+	if head == nil {
+		return
+	}
+	p.Lock()
+	defer p.Unlock()
+	last := head
+	for last.right != nil {
+		last = last.right
+	}
+	last.right = p.free
+	p.free = head
+	p.freeCount += n
+}
+
+// Release removes all values of the tree and returns its nodes (but the root) to the pool of the tree (see
+// WithPool), the tree stays usable. Does nothing for trees without pool.
+func (tree *Tree) Release() {
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	if tree.pool == nil {
+		return
+	}
+	if tree.guard != nil {
+		tree.guard.enterWrite("Release")
+		defer tree.guard.exitWrite()
+	}
+	tree.release()
+}
+
+func (tree *Tree) release() {
+	tree.generation++
+	root := tree.root
+	tree.removing(root)
+	for _, child := range []*node{root.left, root.right} {
+		if child != nil {
+			tree.updateUnused(child)
+		}
+	}
+	root.left, root.right = nil, nil
+	if root.value != nil {
+		root.value, root.meta = nil, nil
+		tree.countValuedNodes--
+	}
+	tree.releaseFree()
+	tree.hasExpiry = false
+	if tree.misses != nil {
+		tree.misses.invalidate(nil, 0)
+	}
+}
+
+// releaseFree returns free nodes of the tree to its pool.
+func (tree *Tree) releaseFree() {
+	for n := tree.free; n != nil; n = n.right {
+		n.left, n.parent, n.value, n.meta = nil, nil, nil, nil
+	}
+	tree.pool.put(tree.free, tree.countFreeNodes)
+	tree.countAllocNodes -= tree.countFreeNodes
+	tree.free, tree.countFreeNodes = nil, 0
+}
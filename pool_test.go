@@ -0,0 +1,73 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"testing"
+)
+
+func TestPool(t *testing.T) {
+	p := NewPool(1000)
+	a := NewTree(WithPool(p))
+	for _, cidr := range []string{"10.0.0.0/8", "10.1.0.0/16", "192.168.1.0/24", "2001:db8::/48"} {
+		if err := a.AddCIDR(cidr, cidr); err != nil {
+			t.Error(err)
+		}
+	}
+	st := p.Stats()
+	if st.Allocated != 1000 || st.Taken != 2*poolBatch || st.Free != 1000-2*poolBatch {
+		t.Errorf("Wrong pool stats, got %+v", st)
+	}
+	if inf, _ := a.FindCIDR("10.1.2.3"); inf != "10.1.0.0/16" {
+		t.Errorf("Wrong value, expected 10.1.0.0/16, got %v", inf)
+	}
+
+	var removed []string
+	a.OnChange(func(op Op, cidr net.IPNet, old, new interface{}) {
+		removed = append(removed, cidr.String())
+	})
+	a.AddCIDR("0.0.0.0/0", "default")
+	removed = nil
+	a.Release()
+	if len(removed) != 5 {
+		t.Errorf("Wrong removals, expected 5, got %v", removed)
+	}
+	nodes, valued, total, free := a.GetStats()
+	if nodes != 1 || valued != 0 || total != 1 || free != 0 {
+		t.Errorf("Wrong stats of released tree, got %d %d %d %d", nodes, valued, total, free)
+	}
+	if st := p.Stats(); st.Free != 999 {
+		t.Errorf("Wrong free nodes of the pool, expected 999, got %d", st.Free)
+	}
+
+	// another tree reuses released nodes
+	b := NewTree(WithPool(p))
+	b.AddCIDR("172.16.0.0/12", 1)
+	if st := p.Stats(); st.Allocated != 1000 {
+		t.Errorf("Wrong allocated nodes of the pool, expected 1000, got %d", st.Allocated)
+	}
+	if inf, _ := b.FindCIDR("172.16.1.1"); inf != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+	a.AddCIDR("10.0.0.0/8", 2)
+	if inf, _ := a.FindCIDR("10.1.2.3"); inf != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+
+	b.DeleteCIDR("172.16.0.0/12")
+	b.Compact()
+	if _, _, _, free := b.GetStats(); free != 0 {
+		t.Errorf("Wrong free nodes after Compact, expected 0, got %d", free)
+	}
+	before := p.Stats()
+	if n := p.Drain(); n != before.Free {
+		t.Errorf("Wrong number of drained nodes, expected %d, got %d", before.Free, n)
+	}
+	if st := p.Stats(); st.Free != 0 || st.Allocated != before.Allocated-before.Free {
+		t.Errorf("Wrong pool stats after Drain, got %+v", st)
+	}
+	NewTree().Release()
+}
@@ -0,0 +1,115 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"math"
+	"math/rand"
+	"net"
+)
+
+// RandomIPCovered returns random address inside the cidr covered by some value of the tree.
+// All covered addresses are equally likely. Will return ErrNotFound if the cidr has no covered address.
+func (tree *Tree) RandomIPCovered(cidr string) (net.IP, error) {
+	return tree.randomIP(cidr, true)
+}
+
+// RandomIPUncovered returns random address inside the cidr not covered by any value of the tree.
+// All uncovered addresses are equally likely. Will return ErrNotFound if the cidr has no uncovered address.
+func (tree *Tree) RandomIPUncovered(cidr string) (net.IP, error) {
+	return tree.randomIP(cidr, false)
+}
+
+func (tree *Tree) randomIP(cidr string, covered bool) (net.IP, error) {
+	key, bits, err := tree.cidrKey(cidr)
+	if err != nil {
+		return nil, err
+	}
+	maxbits := net.IPv6len * 8
+	if tree.isIPv4([]byte(cidr)) && !tree.mapped4 {
+		maxbits = net.IPv4len * 8
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+
+	var blocks []block
+	var weights []float64
+	var total float64
+	tree.coverage(block{ip: key, bits: bits}, maxbits, func(b block, isCovered bool) {
+		if isCovered == covered {
+			w := math.Ldexp(1, maxbits-b.bits)
+			blocks = append(blocks, b)
+			weights = append(weights, w)
+			total += w
+		}
+	})
+	if len(blocks) == 0 {
+		return nil, ErrNotFound
+	}
+
+	pick := rand.Float64() * total
+	i := 0
+	for ; i < len(blocks)-1 && pick >= weights[i]; i++ {
+		pick -= weights[i]
+	}
+	b := blocks[i]
+	for bit := b.bits; bit < maxbits; bit++ {
+		setKeyBit(&b.ip, bit, rand.Intn(2) == 1)
+	}
+	return net.IP(append([]byte(nil), b.ip[:maxbits/8]...)), nil
+}
+
+// coverage splits the block (down to maxbits deep) into largest blocks that are either covered by a value
+// of the tree or not covered at all and calls fn for each of them in address order.
+func (tree *Tree) coverage(b block, maxbits int, fn func(b block, covered bool)) {
+	n := tree.root
+	for i := 0; ; i++ {
+		if n.value != nil {
+			fn(b, true)
+			return
+		}
+		if i == b.bits {
+			break
+		}
+		if keyBit(b.ip, i) {
+			n = n.right
+		} else {
+			n = n.left
+		}
+		if n == nil {
+			fn(b, false)
+			return
+		}
+	}
+	coverageBelow(n, b, maxbits, fn)
+}
+
+func coverageBelow(n *node, b block, maxbits int, fn func(b block, covered bool)) {
+	if n.value != nil {
+		fn(b, true)
+		return
+	}
+	if b.bits >= maxbits {
+		// node exists only for deeper keys of the other address family
+		fn(b, false)
+		return
+	}
+	for _, right := range []bool{false, true} {
+		cb := b
+		cb.bits++
+		setKeyBit(&cb.ip, b.bits, right)
+		child := n.left
+		if right {
+			child = n.right
+		}
+		if child == nil {
+			fn(cb, false)
+		} else {
+			coverageBelow(child, cb, maxbits, fn)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestRandomIP(t *testing.T) {
+	tr := NewTree()
+	tr.AddCIDR("10.0.0.0/25", 1)
+	tr.AddCIDR("10.0.0.192/26", 2)
+	tr.AddCIDR("dead::/16", 3)
+
+	_, within, _ := net.ParseCIDR("10.0.0.0/24")
+	for i := 0; i < 100; i++ {
+		ip, err := tr.RandomIPCovered("10.0.0.0/24")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !within.Contains(ip) || (ip[3] >= 128 && ip[3] < 192) || len(ip) != net.IPv4len {
+			t.Fatalf("Random covered IP %s is not covered", ip)
+		}
+		ip, err = tr.RandomIPUncovered("10.0.0.0/24")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ip[3] < 128 || ip[3] >= 192 || ip[2] != 0 {
+			t.Fatalf("Random uncovered IP %s is covered", ip)
+		}
+	}
+
+	// whole range covered by a less specific value
+	ip, err := tr.RandomIPCovered("dead:beef::/32")
+	if err != nil {
+		t.Error(err)
+	} else if ip[0] != 0xde || ip[1] != 0xad || ip[2] != 0xbe || ip[3] != 0xef || len(ip) != net.IPv6len {
+		t.Errorf("Random covered IP %s is outside the range", ip)
+	}
+	if _, err = tr.RandomIPUncovered("dead:beef::/32"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	// nothing stored in the range
+	if _, err = tr.RandomIPCovered("11.0.0.0/8"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	ip, err = tr.RandomIPUncovered("11.0.0.0/8")
+	if err != nil {
+		t.Error(err)
+	} else if ip[0] != 11 {
+		t.Errorf("Random uncovered IP %s is outside the range", ip)
+	}
+
+	if _, err = tr.RandomIPCovered("10.0.0.x"); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Expected ErrBadIP, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+)
+
+// RangerEntry is a network stored in the Ranger, it has the method set of cidranger.RangerEntry.
+type RangerEntry interface {
+	Network() net.IPNet
+}
+
+type basicRangerEntry struct {
+	ipNet net.IPNet
+}
+
+func (b *basicRangerEntry) Network() net.IPNet {
+	return b.ipNet
+}
+
+// NewBasicRangerEntry returns a RangerEntry holding only the network, like cidranger.NewBasicRangerEntry.
+func NewBasicRangerEntry(ipNet net.IPNet) RangerEntry {
+	return &basicRangerEntry{ipNet: ipNet}
+}
+
+// Ranger is an adapter with the methods of cidranger.Ranger backed by the Tree, so call sites written against
+// cidranger port to nradix with few changes. It takes RangerEntry of this package, not cidranger.RangerEntry, so
+// *Ranger does not implement cidranger.Ranger. Entries are the values stored in the tree, IPv4 and IPv6 networks
+// never share a node.
+type Ranger struct {
+	tree *Tree
+}
+
+// NewRanger creates Ranger backed by a new Tree configured by opts, the tree keeps IPv4 networks apart from IPv6
+// ones (see WithIPv4Mapped).
+func NewRanger(opts ...Option) *Ranger {
+	opts = append(opts[:len(opts):len(opts)], WithIPv4Mapped())
+	return &Ranger{tree: NewTree(opts...)}
+}
+
+// Insert adds entry to the Ranger, replacing an entry previously stored for the same network.
+func (r *Ranger) Insert(entry RangerEntry) error {
+	e, err := net2entry(entry.Network())
+	if err != nil {
+		return err
+	}
+	e.value = entry
+	tree := r.tree
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	if found := tree.findEntry(&e, findExact); found != nil {
+		return tree.insertEntry(&e, true)
+	}
+	return tree.insertEntry(&e, false)
+}
+
+// Remove removes the entry of the network and returns it, nil is returned if there was no such entry.
+func (r *Ranger) Remove(network net.IPNet) (RangerEntry, error) {
+	e, err := net2entry(network)
+	if err != nil {
+		return nil, err
+	}
+	tree := r.tree
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	found := tree.findEntry(&e, findExact)
+	if found == nil {
+		return nil, nil
+	}
+	if err = tree.deleteEntry(&e, false); err != nil {
+		return nil, err
+	}
+	return found.(RangerEntry), nil
+}
+
+// Contains returns true if ip is within any of the stored networks.
+func (r *Ranger) Contains(ip net.IP) (bool, error) {
+	entries, err := r.ContainingNetworks(ip)
+	return len(entries) > 0, err
+}
+
+// ContainingNetworks returns entries of all stored networks containing ip, from least to most specific.
+func (r *Ranger) ContainingNetworks(ip net.IP) ([]RangerEntry, error) {
+	e, err := ip2entry(ip)
+	if err != nil {
+		return nil, err
+	}
+	tree := r.tree
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	return rangerEntries(tree.findAllEntry(&e), e.v4), nil
+}
+
+// CoveredNetworks returns entries of all stored networks that are covered by network, including network itself.
+func (r *Ranger) CoveredNetworks(network net.IPNet) ([]RangerEntry, error) {
+	e, err := net2entry(network)
+	if err != nil {
+		return nil, err
+	}
+	tree := r.tree
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	n := tree.entryNode(&e)
+	if n == nil {
+		return nil, nil
+	}
+	var values []interface{}
+	retn, _, _ := subtreenodes(n)
+	for _, sn := range retn {
+		if sn.value != nil {
+			values = append(values, sn.value)
+		}
+	}
+	return rangerEntries(values, e.v4), nil
+}
+
+// Len returns number of networks stored in the Ranger.
+func (r *Ranger) Len() int {
+	tree := r.tree
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	return tree.countValuedNodes
+}
+
+// rangerEntries converts values to entries, dropping entries of other address family (IPv6 networks covering the
+// IPv4-mapped block).
+func rangerEntries(values []interface{}, v4 bool) []RangerEntry {
+	var ret []RangerEntry
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		entry := v.(RangerEntry)
+		if (entry.Network().IP.To4() != nil) == v4 {
+			ret = append(ret, entry)
+		}
+	}
+	return ret
+}
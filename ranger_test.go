@@ -0,0 +1,121 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"testing"
+)
+
+func TestRanger(t *testing.T) {
+	r := NewRanger()
+	for _, cidr := range []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24", "192.168.0.0/16", "dead::/16", "dead:beef::/32"} {
+		_, ipnet, _ := net.ParseCIDR(cidr)
+		if err := r.Insert(NewBasicRangerEntry(*ipnet)); err != nil {
+			t.Error(err)
+		}
+	}
+	if r.Len() != 6 {
+		t.Errorf("Wrong length, expected 6, got %d", r.Len())
+	}
+
+	// replacing entry keeps length
+	_, ipnet, _ := net.ParseCIDR("10.1.0.0/16")
+	if err := r.Insert(NewBasicRangerEntry(*ipnet)); err != nil {
+		t.Error(err)
+	}
+	if r.Len() != 6 {
+		t.Errorf("Wrong length after replace, expected 6, got %d", r.Len())
+	}
+
+	entries, err := r.ContainingNetworks(net.ParseIP("10.1.2.3"))
+	if err != nil {
+		t.Error(err)
+	}
+	expected := []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24"}
+	if len(entries) != len(expected) {
+		t.Fatalf("Wrong number of containing networks, expected %d, got %d", len(expected), len(entries))
+	}
+	for i, e := range entries {
+		n := e.Network()
+		if n.String() != expected[i] {
+			t.Errorf("Wrong containing network at %d, expected %s, got %s", i, expected[i], n.String())
+		}
+	}
+
+	if ok, err := r.Contains(net.ParseIP("dead:beef::1")); err != nil || !ok {
+		t.Errorf("Expected dead:beef::1 to be contained, got %v %v", ok, err)
+	}
+	if ok, err := r.Contains(net.ParseIP("11.0.0.1")); err != nil || ok {
+		t.Errorf("Expected 11.0.0.1 not to be contained, got %v %v", ok, err)
+	}
+
+	_, ipnet, _ = net.ParseCIDR("10.0.0.0/8")
+	entries, err = r.CoveredNetworks(*ipnet)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("Wrong number of covered networks, expected 3, got %d", len(entries))
+	}
+
+	_, ipnet, _ = net.ParseCIDR("10.1.0.0/16")
+	removed, err := r.Remove(*ipnet)
+	if err != nil {
+		t.Error(err)
+	}
+	if removed == nil {
+		t.Fatal("Expected removed entry")
+	}
+	if n := removed.Network(); n.String() != "10.1.0.0/16" {
+		t.Errorf("Wrong removed entry %s", n.String())
+	}
+	if r.Len() != 5 {
+		t.Errorf("Wrong length after remove, expected 5, got %d", r.Len())
+	}
+	if removed, err = r.Remove(*ipnet); removed != nil || err != nil {
+		t.Errorf("Expected nothing to remove, got %v %v", removed, err)
+	}
+}
+
+func TestRangerMixedFamilies(t *testing.T) {
+	r := NewRanger()
+	_, v4, _ := net.ParseCIDR("222.173.0.0/16")
+	_, v6, _ := net.ParseCIDR("dead::/16")
+	if err := r.Insert(NewBasicRangerEntry(*v4)); err != nil {
+		t.Error(err)
+	}
+	if err := r.Insert(NewBasicRangerEntry(*v6)); err != nil {
+		t.Error(err)
+	}
+	if r.Len() != 2 {
+		t.Errorf("Wrong length, expected 2, got %d", r.Len())
+	}
+	if ok, err := r.Contains(net.ParseIP("222.173.1.1")); err != nil || !ok {
+		t.Errorf("Expected 222.173.1.1 to be contained, got %v %v", ok, err)
+	}
+	if ok, err := r.Contains(net.ParseIP("dead::1")); err != nil || !ok {
+		t.Errorf("Expected dead::1 to be contained, got %v %v", ok, err)
+	}
+	removed, err := r.Remove(*v6)
+	if err != nil {
+		t.Error(err)
+	}
+	if removed == nil {
+		t.Fatal("Expected removed entry")
+	}
+	if n := removed.Network(); n.String() != "dead::/16" {
+		t.Errorf("Wrong removed entry, expected dead::/16, got %s", n.String())
+	}
+	if ok, err := r.Contains(net.ParseIP("222.173.1.1")); err != nil || !ok {
+		t.Errorf("Expected 222.173.1.1 to be contained after remove, got %v %v", ok, err)
+	}
+	if ok, _ := r.Contains(net.ParseIP("dead::1")); ok {
+		t.Error("Expected dead::1 not to be contained after remove")
+	}
+	if r.Len() != 1 {
+		t.Errorf("Wrong length after remove, expected 1, got %d", r.Len())
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// RCUTree keeps immutable versions of the Tree: readers do wait-free lookups on the current version
+// while a writer builds the next version on a copy and publishes it atomically.
+type RCUTree struct {
+	current atomic.Pointer[Tree]
+	writer  sync.Mutex
+}
+
+// NewRCUTree creates RCUTree with empty Tree configured by opts. Versions never use locking and their lookups write
+// nothing (WithHitCounting and WithMissCache are ignored), so any number of readers can share a version.
+func NewRCUTree(opts ...Option) *RCUTree {
+	r := new(RCUTree)
+	r.current.Store(NewTree(append(append([]Option(nil), opts...), WithLocking(false), withoutLookupState)...))
+	return r
+}
+
+// withoutLookupState makes lookups of the tree write nothing: no LRU order, hit counts or cached misses.
+func withoutLookupState(tree *Tree) {
+	tree.lru = nil
+	tree.countHits = false
+	tree.misses = nil
+}
+
+// Snapshot returns the current version of the tree, it never changes.
+func (r *RCUTree) Snapshot() *ReadOnlyTree {
+	return &ReadOnlyTree{tree: r.current.Load()}
+}
+
+// Update copies the current version (values are copied with the CloneValueFunc if set), applies fn on the copy
+// and publishes it as the new version. If fn returns error the copy is dropped and the error is returned.
+// Updates are serialized, batch many changes in one fn as every update copies the whole tree.
+func (r *RCUTree) Update(fn func(next *Tree) error) error {
+	r.writer.Lock()
+	defer r.writer.Unlock()
+	next := r.current.Load().Clone()
+	if err := fn(next); err != nil {
+		return err
+	}
+	r.current.Store(next)
+	return nil
+}
+
+// FindCIDR finds the value in the current version, see Tree.FindCIDR.
+func (r *RCUTree) FindCIDR(cidr string) (interface{}, error) {
+	return r.current.Load().FindCIDR(cidr)
+}
@@ -0,0 +1,96 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestRCUTree(t *testing.T) {
+	r := NewRCUTree(WithLocking(true))
+	if r.Snapshot().tree.safe {
+		t.Error("Versions should not use locking")
+	}
+	err := r.Update(func(next *Tree) error {
+		return next.AddCIDR("10.0.0.0/8", 1)
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	old := r.Snapshot()
+
+	errStop := errors.New("stop")
+	err = r.Update(func(next *Tree) error {
+		next.AddCIDR("10.1.0.0/16", 2)
+		return errStop
+	})
+	if err != errStop {
+		t.Errorf("Expected update error, got %v", err)
+	}
+	if r.Snapshot().tree != old.tree {
+		t.Error("Failed update was published")
+	}
+
+	r.Update(func(next *Tree) error {
+		return next.AddCIDR("10.1.0.0/16", 2)
+	})
+	if inf, _ := r.FindCIDR("10.1.1.1"); inf.(int) != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+	if inf, _ := old.FindCIDR("10.1.1.1"); inf.(int) != 1 {
+		t.Errorf("Old version changed, expected 1, got %v", inf)
+	}
+
+	// readers run concurrently with updates
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				if inf, _ := r.FindCIDR("10.2.0.1"); inf == nil {
+					t.Error("Expected value")
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		r.Update(func(next *Tree) error {
+			return next.AddCIDR(fmt.Sprintf("11.%d.0.0/16", i), i)
+		})
+	}
+	wg.Wait()
+	if _, values, _, _ := r.Snapshot().GetStats(); values != 22 {
+		t.Errorf("Wrong valued nodes, expected 22, got %d", values)
+	}
+}
+
+func TestRCUTreeConcurrentReaders(t *testing.T) {
+	r := NewRCUTree(WithHitCounting(), WithMissCache(16))
+	r.Update(func(next *Tree) error {
+		return next.AddCIDR("10.0.0.0/8", 1)
+	})
+	snap := r.Snapshot()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				snap.FindCIDR("10.1.1.1")
+				snap.FindCIDR("11.1.1.1")
+				snap.FindAllCIDR("10.1.1.1")
+			}
+		}()
+	}
+	wg.Wait()
+	if snap.tree.countHits || snap.tree.misses != nil {
+		t.Error("Versions should not keep lookup state")
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+// Intersect returns a new tree (created with the options of the tree) covering addresses for which both the tree
+// and other tree find a value (by longest match), each with the value the tree finds for it.
+func (tree *Tree) Intersect(other *Tree) *Tree {
+	return tree.setOp(other, func(a, b interface{}) bool { return b != nil })
+}
+
+// Subtract returns a new tree (created with the options of the tree) covering addresses for which the tree finds
+// a value (by longest match) and other tree finds none, each with the value the tree finds for it.
+func (tree *Tree) Subtract(other *Tree) *Tree {
+	return tree.setOp(other, func(a, b interface{}) bool { return b == nil })
+}
+
+// setOp builds tree of addresses whose value a in the tree is kept given value b of other tree,
+// the result is stored as the smallest set of disjoint IP/masks.
+func (tree *Tree) setOp(other *Tree, keep func(a, b interface{}) bool) *Tree {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	if other.safe && other != tree {
+		other.RLock()
+		defer other.RUnlock()
+	}
+	o := setOperation{keep: keep, nowA: tree.expiryNow(), nowB: other.expiryNow()}
+	if uniform, value := o.walk(tree.root, other.root, make([]byte, 0, 128), nil, nil); uniform && value != nil {
+		o.ret = append(o.ret, summary{value: value})
+	}
+
+	dst := tree.emptyCopy()
+	entries := make([]prefixEntry, 0, len(o.ret))
+	for _, s := range o.ret {
+		e, err := net2entry(walkpath2net(tree.walkOpt(OptWalkIPAuto), s.walkpath))
+		if err != nil {
+			continue
+		}
+		e.value = dst.copyValue(s.value)
+		entries = append(entries, e)
+	}
+	dst.insertEntries(entries, false)
+	return dst
+}
+
+type setOperation struct {
+	keep       func(a, b interface{}) bool
+	nowA, nowB int64
+	ret        []summary
+}
+
+// walk descends both trees along the same path with values inherited from above, returning whether all addresses
+// under the path get the same result value (then also the value), otherwise result blocks are appended.
+func (o *setOperation) walk(a, b *node, walkpath []byte, ia, ib interface{}) (bool, interface{}) {
+	var al, ar, bl, br *node
+	if a != nil {
+		if a.value != nil && !expired(a, o.nowA) {
+			ia = a.value
+		}
+		al, ar = a.left, a.right
+	}
+	if b != nil {
+		if b.value != nil && !expired(b, o.nowB) {
+			ib = b.value
+		}
+		bl, br = b.left, b.right
+	}
+	if al == nil && ar == nil && bl == nil && br == nil {
+		if ia != nil && o.keep(ia, ib) {
+			return true, ia
+		}
+		return true, nil
+	}
+
+	mark := len(o.ret)
+	lu, lv := o.walk(al, bl, append(walkpath, byte(0)), ia, ib)
+	lmark := len(o.ret)
+	ru, rv := o.walk(ar, br, append(walkpath, byte(1)), ia, ib)
+	if lu && ru && (lv == nil && rv == nil || lv != nil && rv != nil && valuesEqual(lv, rv)) {
+		return true, lv
+	}
+
+	var blocks []summary
+	if lu && lv != nil {
+		blocks = append(blocks, summary{walkpath: append(append([]byte(nil), walkpath...), byte(0)), value: lv})
+	}
+	blocks = append(blocks, o.ret[mark:lmark]...)
+	if ru && rv != nil {
+		blocks = append(blocks, summary{walkpath: append(append([]byte(nil), walkpath...), byte(1)), value: rv})
+	}
+	blocks = append(blocks, o.ret[lmark:]...)
+	o.ret = append(o.ret[:mark], blocks...)
+	return false, nil
+}
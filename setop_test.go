@@ -0,0 +1,75 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"testing"
+)
+
+func setOpTrees() (a, b *Tree) {
+	a = NewTree()
+	a.AddCIDR("10.0.0.0/8", "a")
+	a.AddCIDR("10.1.0.0/16", "b")
+	a.AddCIDR("2001:db8::/48", "c")
+
+	b = NewTree()
+	b.AddCIDR("10.1.0.0/16", "x")
+	b.AddCIDR("10.2.0.0/15", "y")
+	b.AddCIDR("192.168.0.0/24", "z")
+	b.AddCIDR("2001:db8::/64", "w")
+	return a, b
+}
+
+func TestIntersect(t *testing.T) {
+	a, b := setOpTrees()
+	tr := a.Intersect(b)
+	for cidr, expected := range map[string]interface{}{"10.1.0.0/16": "b", "10.2.0.0/15": "a", "2001:db8::/64": "c"} {
+		if inf, err := tr.FindExactCIDR(cidr); err != nil || inf != expected {
+			t.Errorf("Wrong value for %s, expected %v, got %v %v", cidr, expected, inf, err)
+		}
+	}
+	for _, ip := range []string{"10.0.0.1", "10.4.0.1", "192.168.0.1", "2001:db8:0:1::1"} {
+		if inf, _ := tr.FindCIDR(ip); inf != nil {
+			t.Errorf("Expected no value for %s, got %v", ip, inf)
+		}
+	}
+	if _, values, _, _ := tr.GetStats(); values != 3 {
+		t.Errorf("Wrong valued count, expected 3, got %d", values)
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	a, b := setOpTrees()
+	tr := a.Subtract(b)
+	for ip, expected := range map[string]interface{}{
+		"10.0.0.1":           "a",
+		"10.1.0.1":           nil,
+		"10.2.0.1":           nil,
+		"10.3.255.255":       nil,
+		"10.4.0.1":           "a",
+		"10.200.0.1":         "a",
+		"192.168.0.1":        nil,
+		"2001:db8::1":        nil,
+		"2001:db8:0:1::1":    "c",
+		"2001:db8:0:ffff::1": "c",
+		"2001:db8:1::1":      nil,
+	} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != expected {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, expected, inf)
+		}
+	}
+	// 10.0.0.0/16, /14 ... /9 and 16 IPv6 blocks around the /64
+	if _, values, _, _ := tr.GetStats(); values != 23 {
+		t.Errorf("Wrong valued count, expected 23, got %d", values)
+	}
+	// blocks are disjoint, 10.0.0.0/8 is split around 10.1.0.0/16 into 8 blocks
+	if _, values, _, _ := a.Subtract(NewTree()).GetStats(); values != 10 {
+		t.Errorf("Wrong valued count subtracting empty tree, expected 10, got %d", values)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+)
+
+// ShardedTree partitions the keyspace by the leading bits of the key across independently locked trees, so
+// writes to different parts of the keyspace don't wait for each other. IP/masks shorter than the shard prefix
+// are kept in every shard they cover, writing them locks all such shards.
+type ShardedTree struct {
+	shards []*Tree
+	bits   int
+}
+
+// NewShardedTree creates ShardedTree of shards (rounded down to a power of two, at most 256) trees configured
+// by opts, the trees always use locking.
+func NewShardedTree(shards int, opts ...Option) *ShardedTree {
+	s := new(ShardedTree)
+	for s.bits < 8 && 2<<s.bits <= shards {
+		s.bits++
+	}
+	opts = append(append([]Option(nil), opts...), WithLocking(true))
+	s.shards = make([]*Tree, 1<<s.bits)
+	for i := range s.shards {
+		s.shards[i] = NewTree(opts...)
+	}
+	return s
+}
+
+// shardRange returns range of shards covered by the cidr.
+func (s *ShardedTree) shardRange(cidr string) (first, last int, err error) {
+	key, bits, err := s.shards[0].cidrKey(cidr)
+	if err != nil {
+		return 0, 0, err
+	}
+	first = int(key[0]) >> (8 - s.bits)
+	if bits >= s.bits {
+		return first, first, nil
+	}
+	return first, first + 1<<(s.bits-bits) - 1, nil
+}
+
+// write runs fn on all shards covered by the cidr, locked in order. If check is set it runs first on all of them.
+func (s *ShardedTree) write(cidr string, check func(tree *Tree) error, fn func(tree *Tree) error) error {
+	first, last, err := s.shardRange(cidr)
+	if err != nil {
+		return err
+	}
+	shards := s.shards[first : last+1]
+	for _, tree := range shards {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	if check != nil {
+		for _, tree := range shards {
+			if err = check(tree); err != nil {
+				return err
+			}
+		}
+	}
+	for _, tree := range shards {
+		if err = fn(tree); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddCIDR adds value associated with IP/mask, see Tree.AddCIDR.
+func (s *ShardedTree) AddCIDR(cidr string, val interface{}) error {
+	return s.write(cidr, func(tree *Tree) error {
+		if _, err := tree.findExactCIDRb([]byte(cidr)); err != ErrNotFound {
+			if err == nil {
+				return ErrNodeBusy
+			}
+			return err
+		}
+		return nil
+	}, func(tree *Tree) error {
+		return tree.addCIDRb([]byte(cidr), val)
+	})
+}
+
+// SetCIDR sets value associated with IP/mask, see Tree.SetCIDR.
+func (s *ShardedTree) SetCIDR(cidr string, val interface{}) error {
+	return s.write(cidr, nil, func(tree *Tree) error {
+		return tree.setCIDRb([]byte(cidr), val)
+	})
+}
+
+// DeleteCIDR removes value associated with IP/mask, see Tree.DeleteCIDR.
+func (s *ShardedTree) DeleteCIDR(cidr string) error {
+	return s.write(cidr, nil, func(tree *Tree) error {
+		return tree.deleteCIDRb([]byte(cidr))
+	})
+}
+
+// FindCIDR finds the value of the longest IP/mask covering the cidr, see Tree.FindCIDR.
+func (s *ShardedTree) FindCIDR(cidr string) (interface{}, error) {
+	first, _, err := s.shardRange(cidr)
+	if err != nil {
+		return nil, err
+	}
+	return s.shards[first].FindCIDR(cidr)
+}
+
+// FindExactCIDR finds the value of exactly the cidr, see Tree.FindExactCIDR.
+func (s *ShardedTree) FindExactCIDR(cidr string) (interface{}, error) {
+	first, _, err := s.shardRange(cidr)
+	if err != nil {
+		return nil, err
+	}
+	return s.shards[first].FindExactCIDR(cidr)
+}
+
+// WalkTree walks the shards in address order, see Tree.WalkTree. Shards are locked one at a time, so the walk
+// is not a consistent snapshot of the whole keyspace.
+func (s *ShardedTree) WalkTree(opt OptWalk, wtfunc WalkTreeFunc) error {
+	for i, tree := range s.shards {
+		i := i
+		err := tree.WalkTree(opt, func(cidr net.IPNet, value interface{}) (bool, error) {
+			// IP/masks covering several shards are walked in the first one only
+			if first, _, err := s.shardRange(cidr.String()); err == nil && first != i {
+				return true, nil
+			}
+			return wtfunc(cidr, value)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
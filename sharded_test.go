@@ -0,0 +1,81 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+)
+
+func TestShardedTree(t *testing.T) {
+	s := NewShardedTree(16)
+	if len(s.shards) != 16 {
+		t.Errorf("Wrong number of shards, expected 16, got %d", len(s.shards))
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 256; j++ {
+				if err := s.AddCIDR(fmt.Sprintf("%d.%d.0.0/16", i*32, j), j); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := s.AddCIDR("0.0.0.0/2", "short"); err != nil {
+		t.Error(err)
+	}
+	if err := s.AddCIDR("32.0.0.0/3", "busy"); err != nil {
+		t.Error(err)
+	}
+	if err := s.AddCIDR("0.0.0.0/2", "again"); err != ErrNodeBusy {
+		t.Errorf("Wrong error, expected %v, got %v", ErrNodeBusy, err)
+	}
+
+	for ip, expected := range map[string]interface{}{
+		"32.7.1.1":    7,
+		"33.1.1.1":    "busy",
+		"16.1.1.1":    "short",
+		"100.1.1.1":   nil,
+		"0.0.0.0/1":   nil,
+		"0.0.0.0/2":   "short",
+		"224.255.0.1": 255,
+	} {
+		inf, err := s.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != expected {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, expected, inf)
+		}
+	}
+
+	var walked []string
+	s.WalkTree(OptWalkIPv4, func(cidr net.IPNet, value interface{}) (bool, error) {
+		if value == "short" || value == "busy" || value == 3 {
+			walked = append(walked, cidr.String())
+		}
+		return true, nil
+	})
+	expected := []string{"0.0.0.0/2", "0.3.0.0/16", "32.0.0.0/3", "32.3.0.0/16", "64.3.0.0/16", "96.3.0.0/16", "128.3.0.0/16", "160.3.0.0/16", "192.3.0.0/16", "224.3.0.0/16"}
+	if fmt.Sprint(walked) != fmt.Sprint(expected) {
+		t.Errorf("Wrong walk, expected %v, got %v", expected, walked)
+	}
+
+	if err := s.DeleteCIDR("0.0.0.0/2"); err != nil {
+		t.Error(err)
+	}
+	if inf, _ := s.FindCIDR("48.1.1.1"); inf != "busy" {
+		t.Errorf("Wrong value, expected busy, got %v", inf)
+	}
+	if inf, _ := s.FindCIDR("16.1.1.1"); inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"math/big"
+	"net"
+	"sort"
+	"unsafe"
+)
+
+// Stats is detailed statistics of the tree.
+type Stats struct {
+	Nodes, ValuedNodes    int // nodes in use and those of them holding value
+	AllocNodes, FreeNodes int // nodes allocated in the arena and those of them on the free list
+
+	NodeBytes uintptr // bytes of the node arena (allocated nodes)
+	MetaBytes uintptr // bytes of metadata kept for values (WithMetadata, TTL, hit counting)
+
+	DepthHistogram []int // number of valued nodes at each depth (prefix length), up to MaxDepth
+	MaxDepth       int   // depth of the deepest node
+
+	Leaves, OneChild, TwoChildren int // nodes in use by number of children
+}
+
+// Counts returns the counters of Stats (node counts and NodeBytes) without walking the tree, other fields are zero.
+// It is cheap enough to be called by every metrics scrape.
+func (tree *Tree) Counts() Stats {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	return tree.counts()
+}
+
+func (tree *Tree) counts() Stats {
+	return Stats{
+		Nodes:       tree.countNodes,
+		ValuedNodes: tree.countValuedNodes,
+		AllocNodes:  tree.countAllocNodes,
+		FreeNodes:   tree.countFreeNodes,
+		NodeBytes:   uintptr(tree.countAllocNodes) * unsafe.Sizeof(node{}),
+	}
+}
+
+// Stats walks the tree and returns its statistics. Memory taken by the values themselves is not counted.
+func (tree *Tree) Stats() Stats {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	st := tree.counts()
+	type item struct {
+		n     *node
+		depth int
+	}
+	stack := []item{{tree.root, 0}}
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		n := it.n
+		if it.depth > st.MaxDepth {
+			st.MaxDepth = it.depth
+		}
+		if n.meta != nil {
+			st.MetaBytes += unsafe.Sizeof(nodeMeta{})
+		}
+		if n.value != nil {
+			for len(st.DepthHistogram) <= it.depth {
+				st.DepthHistogram = append(st.DepthHistogram, 0)
+			}
+			st.DepthHistogram[it.depth]++
+		}
+		children := 0
+		for _, c := range []*node{n.right, n.left} {
+			if c != nil {
+				children++
+				stack = append(stack, item{c, it.depth + 1})
+			}
+		}
+		switch children {
+		case 0:
+			st.Leaves++
+		case 1:
+			st.OneChild++
+		default:
+			st.TwoChildren++
+		}
+	}
+	return st
+}
+
+// SubtreeStats is size of the subtree under IP/mask.
+type SubtreeStats struct {
+	Net                net.IPNet
+	Nodes, ValuedNodes int
+}
+
+// LargestSubtrees returns up to n largest (by number of nodes) subtrees rooted at the depth (prefix length),
+// e.g. depth 8 compares IPv4 /8s. Subtrees of the same size are in walk order.
+func (tree *Tree) LargestSubtrees(depth, n int) []SubtreeStats {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	var ret []SubtreeStats
+	var visit func(n *node, walkpath []byte)
+	visit = func(n *node, walkpath []byte) {
+		if n == nil {
+			return
+		}
+		if len(walkpath) == depth {
+			st := SubtreeStats{Net: walkpath2net(tree.walkOpt(OptWalkIPAuto), walkpath)}
+			countSubtree(n, &st)
+			ret = append(ret, st)
+			return
+		}
+		visit(n.left, append(walkpath, 0))
+		visit(n.right, append(walkpath, 1))
+	}
+	visit(tree.root, make([]byte, 0, 128))
+	sort.SliceStable(ret, func(i, j int) bool { return ret[i].Nodes > ret[j].Nodes })
+	if len(ret) > n {
+		ret = ret[:n]
+	}
+	return ret
+}
+
+func countSubtree(n *node, st *SubtreeStats) {
+	for ; n != nil; n = n.right {
+		st.Nodes++
+		if n.value != nil {
+			st.ValuedNodes++
+		}
+		countSubtree(n.left, st)
+	}
+}
+
+// MemoryFootprint returns approximate number of bytes taken by the tree (node arena and metadata), see Stats.
+func (tree *Tree) MemoryFootprint() uintptr {
+	st := tree.Stats()
+	return st.NodeBytes + st.MetaBytes
+}
+
+// CoveredAddresses returns number of addresses inside the cidr covered by values of the tree
+// (IPv6 counts may not fit into uint64).
+func (tree *Tree) CoveredAddresses(cidr string) (*big.Int, error) {
+	key, bits, err := tree.cidrKey(cidr)
+	if err != nil {
+		return nil, err
+	}
+	maxbits := net.IPv6len * 8
+	if tree.isIPv4([]byte(cidr)) && !tree.mapped4 {
+		maxbits = net.IPv4len * 8
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	total, size := new(big.Int), new(big.Int)
+	tree.coverage(block{ip: key, bits: bits}, maxbits, func(b block, covered bool) {
+		if covered {
+			total.Add(total, size.Lsh(big.NewInt(1), uint(maxbits-b.bits)))
+		}
+	})
+	return total, nil
+}
+
+// Gaps returns the largest IP/masks inside the cidr not covered by any value of the tree (unassigned address
+// space), in address order.
+func (tree *Tree) Gaps(within string) ([]net.IPNet, error) {
+	key, bits, err := tree.cidrKey(within)
+	if err != nil {
+		return nil, err
+	}
+	v4 := tree.isIPv4([]byte(within))
+	maxbits := net.IPv6len * 8
+	if v4 && !tree.mapped4 {
+		maxbits = net.IPv4len * 8
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	var ret []net.IPNet
+	tree.coverage(block{ip: key, bits: bits}, maxbits, func(b block, covered bool) {
+		if !covered {
+			ret = append(ret, tree.blockNet(b, v4))
+		}
+	})
+	return ret, nil
+}
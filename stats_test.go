@@ -0,0 +1,134 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestStats(t *testing.T) {
+	tr := NewTree()
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("10.2.0.0/16", 3)
+	tr.AddCIDR("192.168.0.0/16", 4)
+
+	st := tr.Stats()
+	nodes, values, alloc, free := tr.GetStats()
+	if st.Nodes != nodes || st.ValuedNodes != values || st.AllocNodes != alloc || st.FreeNodes != free {
+		t.Errorf("Stats do not match GetStats: %+v", st)
+	}
+	if st.MaxDepth != 16 || len(st.DepthHistogram) != 17 {
+		t.Errorf("Wrong max depth, expected 16, got %d (histogram %v)", st.MaxDepth, st.DepthHistogram)
+	}
+	if st.DepthHistogram[8] != 1 || st.DepthHistogram[16] != 3 {
+		t.Errorf("Wrong depth histogram: %v", st.DepthHistogram)
+	}
+	// branches at the root (10/192) and at 10.0.0.0/14 (10.1/10.2)
+	if st.Leaves != 3 || st.TwoChildren != 2 || st.Leaves+st.OneChild+st.TwoChildren != st.Nodes {
+		t.Errorf("Wrong branch counts: %+v", st)
+	}
+	if st.MetaBytes != 0 || st.NodeBytes != uintptr(alloc)*unsafe.Sizeof(node{}) {
+		t.Errorf("Wrong memory usage: %+v", st)
+	}
+	if m := tr.MemoryFootprint(); m != st.NodeBytes {
+		t.Errorf("Wrong memory footprint, expected %d, got %d", st.NodeBytes, m)
+	}
+	if c := tr.Counts(); c.Nodes != nodes || c.ValuedNodes != values || c.NodeBytes != st.NodeBytes || c.MaxDepth != 0 {
+		t.Errorf("Counts do not match Stats: %+v", c)
+	}
+
+	tr = NewTree(WithMetadata())
+	tr.AddCIDR("10.0.0.0/8", 1)
+	if st = tr.Stats(); st.MetaBytes != unsafe.Sizeof(nodeMeta{}) {
+		t.Errorf("Wrong metadata bytes, expected %d, got %d", unsafe.Sizeof(nodeMeta{}), st.MetaBytes)
+	}
+}
+
+func TestCoveredAddresses(t *testing.T) {
+	tr := NewTree()
+	for _, cidr := range []string{"10.0.0.0/24", "10.0.0.128/25", "10.0.1.0/30", "10.0.2.5", "2001:db8::/32", "2001:db8:1::/48"} {
+		if err := tr.AddCIDR(cidr, cidr); err != nil {
+			t.Error(err)
+		}
+	}
+	for cidr, expected := range map[string]string{
+		"10.0.0.0/16":     "261",
+		"10.0.0.128/25":   "128",
+		"10.0.0.130":      "1",
+		"10.0.3.0/24":     "0",
+		"10.0.0.0/8":      "261",
+		"2001:db8::/16":   "79228162514264337593543950336",
+		"2001:db8:1::/64": "18446744073709551616",
+	} {
+		n, err := tr.CoveredAddresses(cidr)
+		if err != nil {
+			t.Error(err)
+		} else if n.String() != expected {
+			t.Errorf("Wrong count for %s, expected %s, got %v", cidr, expected, n)
+		}
+	}
+	if _, err := tr.CoveredAddresses("10.0.0.x"); err == nil {
+		t.Errorf("Expected error for bad cidr")
+	}
+}
+
+func TestLargestSubtrees(t *testing.T) {
+	tr := NewTree()
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("10.2.0.0/16", 3)
+	tr.AddCIDR("192.168.0.0/16", 4)
+	tr.AddCIDR("192.168.1.0/24", 5)
+
+	sub := tr.LargestSubtrees(8, 10)
+	if len(sub) != 2 {
+		t.Fatalf("Wrong subtrees, expected 2, got %v", sub)
+	}
+	// 192.168.1.0/24 hangs 16 nodes deep under 192.0.0.0/8, 10.1 and 10.2 share the path down to 10.0.0.0/14
+	if sub[0].Net.String() != "192.0.0.0/8" || sub[0].ValuedNodes != 2 || sub[0].Nodes != 17 {
+		t.Errorf("Wrong subtree, expected 192.0.0.0/8 with 17 nodes and 2 values, got %+v", sub[0])
+	}
+	if sub[1].Net.String() != "10.0.0.0/8" || sub[1].ValuedNodes != 3 || sub[1].Nodes != 11 {
+		t.Errorf("Wrong subtree, expected 10.0.0.0/8 with 11 nodes and 3 values, got %+v", sub[1])
+	}
+	if sub = tr.LargestSubtrees(8, 1); len(sub) != 1 {
+		t.Errorf("Wrong subtrees, expected 1, got %v", sub)
+	}
+}
+
+func TestGaps(t *testing.T) {
+	for _, tr := range []*Tree{NewTree(), NewTree(WithIPv4Mapped())} {
+		tr.AddCIDR("10.0.0.0/25", 1)
+		tr.AddCIDR("10.0.1.0/24", 2)
+		tr.AddCIDR("10.0.3.4/32", 3)
+		tr.AddCIDR("2001:db8::/33", 4)
+
+		for within, expected := range map[string]string{
+			"10.0.0.0/22":   "10.0.0.128/25 10.0.2.0/24 10.0.3.0/30 10.0.3.5/32 10.0.3.6/31 10.0.3.8/29 10.0.3.16/28 10.0.3.32/27 10.0.3.64/26 10.0.3.128/25",
+			"10.0.1.0/24":   "",
+			"10.0.1.7":      "",
+			"10.1.0.0/16":   "10.1.0.0/16",
+			"2001:db8::/32": "2001:db8:8000::/33",
+		} {
+			gaps, err := tr.Gaps(within)
+			if err != nil {
+				t.Error(err)
+			}
+			var got []string
+			for _, g := range gaps {
+				got = append(got, g.String())
+			}
+			if strings.Join(got, " ") != expected {
+				t.Errorf("Wrong gaps of %s, expected %s, got %v", within, expected, got)
+			}
+		}
+		if _, err := tr.Gaps("10.0.0.x"); err == nil {
+			t.Errorf("Expected error for bad cidr")
+		}
+	}
+}
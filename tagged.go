@@ -0,0 +1,127 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"reflect"
+)
+
+// Tagged is the value of IP/mask shared by several owners: list of values with caller-defined keys, in order they
+// were added. Tagged values are added by AddCIDRTagged, lookups like FindCIDR return all of them as Tagged.
+// Tagged saved in the tree is never modified, changes replace it by a new one.
+type Tagged []TaggedValue
+
+// TaggedValue is one value of Tagged.
+type TaggedValue struct {
+	Key   interface{}
+	Value interface{}
+}
+
+// Get returns value of the key.
+func (t Tagged) Get(key interface{}) (interface{}, bool) {
+	for _, tv := range t {
+		if valuesEqual(tv.Key, key) {
+			return tv.Value, true
+		}
+	}
+	return nil, false
+}
+
+// AddCIDRTagged adds value with the key to values of IP/mask (see Tagged). Will return error for invalid CIDR, for
+// not comparable key, if the key already has value or if IP/mask has value which is not Tagged.
+func (tree *Tree) AddCIDRTagged(cidr string, key, val interface{}) error {
+	if key == nil || !reflect.TypeOf(key).Comparable() {
+		return ErrBadKey
+	}
+	if zt, addr, zoned := tree.zoneTree(cidr, true); zoned {
+		return zt.AddCIDRTagged(addr, key, val)
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	old, err := tree.findExactCIDRb([]byte(cidr))
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	var tagged Tagged
+	if old != nil {
+		var ok bool
+		if tagged, ok = old.(Tagged); !ok {
+			return ErrNodeBusy
+		}
+		if _, ok = tagged.Get(key); ok {
+			return ErrNodeBusy
+		}
+	}
+	tagged = append(tagged[:len(tagged):len(tagged)], TaggedValue{Key: key, Value: val})
+	return tree.setCIDRb([]byte(cidr), tagged)
+}
+
+// DeleteCIDRTagged removes value with the key from values of IP/mask, IP/mask without values is removed.
+// Will return ErrNotFound if there is no value with the key.
+func (tree *Tree) DeleteCIDRTagged(cidr string, key interface{}) error {
+	if zt, addr, zoned := tree.zoneTree(cidr, false); zoned {
+		if zt == nil {
+			return ErrNotFound
+		}
+		return zt.DeleteCIDRTagged(addr, key)
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	old, err := tree.findExactCIDRb([]byte(cidr))
+	if err != nil {
+		return err
+	}
+	tagged, ok := old.(Tagged)
+	if !ok {
+		return ErrNotFound
+	}
+	for i, tv := range tagged {
+		if valuesEqual(tv.Key, key) {
+			if len(tagged) == 1 {
+				return tree.deleteCIDRb([]byte(cidr))
+			}
+			rest := append(tagged[:i:i], tagged[i+1:]...)
+			return tree.setCIDRb([]byte(cidr), rest)
+		}
+	}
+	return ErrNotFound
+}
+
+// FindCIDRTagged returns value with the key of the most specific IP/mask covering the cidr having value with the key.
+func (tree *Tree) FindCIDRTagged(cidr string, key interface{}) (interface{}, error) {
+	if zt, addr, zoned := tree.zoneTree(cidr, false); zoned {
+		if zt != nil {
+			if val, err := zt.FindCIDRTagged(addr, key); err != nil || val != nil {
+				return val, err
+			}
+		}
+		cidr = addr
+	}
+	k, bits, err := tree.cidrKey(cidr)
+	if err != nil {
+		return nil, err
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	if tree.guard != nil {
+		tree.guard.enterRead("FindCIDRTagged")
+		defer tree.guard.exitRead()
+	}
+	nets := tree.findAllNets(k, bits, false)
+	for i := len(nets) - 1; i >= 0; i-- {
+		if tagged, ok := nets[i].Value.(Tagged); ok {
+			if val, ok := tagged.Get(key); ok {
+				return val, nil
+			}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,73 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"testing"
+)
+
+func TestTagged(t *testing.T) {
+	tr := NewTree()
+	if err := tr.AddCIDRTagged("10.0.0.0/8", "firewall", "allow"); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDRTagged("10.0.0.0/8", "qos", 5); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDRTagged("10.1.0.0/16", "qos", 7); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDRTagged("10.0.0.0/8", "qos", 6); err != ErrNodeBusy {
+		t.Errorf("Wrong error, expected %v, got %v", ErrNodeBusy, err)
+	}
+	if err := tr.AddCIDRTagged("10.0.0.0/8", []int{1}, 6); err != ErrBadKey {
+		t.Errorf("Wrong error, expected %v, got %v", ErrBadKey, err)
+	}
+	tr.AddCIDR("192.168.0.0/16", "plain")
+	if err := tr.AddCIDRTagged("192.168.0.0/16", "qos", 1); err != ErrNodeBusy {
+		t.Errorf("Wrong error, expected %v, got %v", ErrNodeBusy, err)
+	}
+
+	inf, err := tr.FindCIDR("10.2.0.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if tagged, ok := inf.(Tagged); !ok || len(tagged) != 2 || tagged[0].Key != "firewall" || tagged[1].Value != 5 {
+		t.Errorf("Wrong value, expected [{firewall allow} {qos 5}], got %v", inf)
+	}
+	for cidr, expected := range map[string]interface{}{
+		"10.1.0.1":    7,
+		"10.2.0.1":    5,
+		"11.0.0.1":    nil,
+		"192.168.0.1": nil,
+	} {
+		inf, err := tr.FindCIDRTagged(cidr, "qos")
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != expected {
+			t.Errorf("Wrong value for %s, expected %v, got %v", cidr, expected, inf)
+		}
+	}
+	if inf, _ := tr.FindCIDRTagged("10.1.0.1", "firewall"); inf != "allow" {
+		t.Errorf("Wrong value, expected allow, got %v", inf)
+	}
+
+	if err := tr.DeleteCIDRTagged("10.0.0.0/8", "firewall"); err != nil {
+		t.Error(err)
+	}
+	if err := tr.DeleteCIDRTagged("10.0.0.0/8", "firewall"); err != ErrNotFound {
+		t.Errorf("Wrong error, expected %v, got %v", ErrNotFound, err)
+	}
+	if inf, _ := tr.FindCIDRTagged("10.1.0.1", "firewall"); inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+	if err := tr.DeleteCIDRTagged("10.0.0.0/8", "qos"); err != nil {
+		t.Error(err)
+	}
+	if _, err := tr.FindExactCIDR("10.0.0.0/8"); err != ErrNotFound {
+		t.Errorf("Wrong error, expected %v, got %v", ErrNotFound, err)
+	}
+}
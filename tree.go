@@ -6,14 +6,19 @@ package nradix
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"net"
+	"strconv"
 	"sync"
+	"time"
+	"unsafe"
 )
 
 type node struct {
 	left, right, parent *node
 	value               interface{}
+	meta                *nodeMeta
 }
 
 // Tree implements radix tree for working with IP/mask. Thread safety is not guaranteed, you should choose your own style of protecting safety of operations.
@@ -24,7 +29,64 @@ type Tree struct {
 	alloc                                                         []node
 	countNodes, countValuedNodes, countAllocNodes, countFreeNodes int
 	safe                                                          bool
-	sync.Mutex
+	preallocate                                                   int
+	cloneValue                                                    CloneValueFunc
+	shrinkRatio                                                   float64
+	misses                                                        *missCache
+	aggregateEqual                                                func(a, b interface{}) bool
+	guard                                                         *guard
+	metaNow                                                       func() time.Time
+	metaSource                                                    string
+	clock                                                         func() time.Time
+	pendingExpiry                                                 int64
+	hasExpiry                                                     bool
+	onExpire                                                      func(cidr net.IPNet, value interface{})
+	generation                                                    uint64
+	version                                                       uint64
+	countHits                                                     bool
+	index                                                         *valueIndex
+	strict                                                        bool
+	mapped4                                                       bool
+	unmap4                                                        bool
+	jsonDecode                                                    func(data []byte) (interface{}, error)
+	binaryEncode                                                  func(value interface{}) ([]byte, error)
+	binaryDecode                                                  func(data []byte) (interface{}, error)
+	defaultRoute                                                  interface{}
+	metrics                                                       Metrics
+	subscribers                                                   []*subscriber
+	lru                                                           *lruList
+	growth                                                        func(last int) int
+	capacityHint                                                  int
+	pool                                                          *Pool
+	onEvict                                                       func(cidr net.IPNet, value interface{})
+	onNormalize                                                   func(cidr string, network net.IPNet)
+	zones                                                         *zoneTrees
+	opts                                                          []Option
+	sync.RWMutex
+}
+
+// Option configures optional behaviour of the Tree, options are applied when the tree is created.
+type Option func(*Tree)
+
+// WithLocking sets whether the tree protects its operations with its own read-write mutex:
+// lookups and walks run concurrently, while inserts and deletes are exclusive.
+func WithLocking(safe bool) Option {
+	return func(tree *Tree) {
+		tree.safe = safe
+	}
+}
+
+// WithStrictCIDR makes the tree reject IP/masks with bits set beyond the mask, same as WithStrictHostBits(true).
+func WithStrictCIDR() Option {
+	return WithStrictHostBits(true)
+}
+
+// WithPreallocate makes the tree preallocate nodes for all IPv4 prefixes of up to preallocate (at most 6) bits,
+// so they are ready to fill with data.
+func WithPreallocate(preallocate int) Option {
+	return func(tree *Tree) {
+		tree.preallocate = preallocate
+	}
 }
 
 const (
@@ -38,6 +100,21 @@ const (
 	OptWalkIPv4   = OptWalk(0x1)
 	OptWalkIPv6   = OptWalk(0x2)
 	OptWalkIPAuto = OptWalk(0x3)
+
+	// OptWalkCollectErrors makes the walk continue when WalkTreeFunc returns error, all errors are returned
+	// at the end of the walk joined together, each as *WalkError holding the cidr it was returned for.
+	OptWalkCollectErrors = OptWalk(0x4)
+
+	// Walk order, by default nodes are walked in pre-order (which is address order): IP/mask, then IP/masks under
+	// its lower half, then under its upper half. In-order walks IP/masks under the lower half before the IP/mask,
+	// post-order walks IP/masks under both halves before the IP/mask. WalkTreeFunc returning false skips the upper
+	// half in in-order, the whole subtree in pre-order and nothing in post-order.
+	OptWalkPreOrder  = OptWalk(0x0)
+	OptWalkInOrder   = OptWalk(0x10)
+	OptWalkPostOrder = OptWalk(0x20)
+
+	// optWalkExpired makes the walk visit expired values too (used by Sweep).
+	optWalkExpired = OptWalk(0x80000000)
 )
 
 type findWhat int
@@ -54,17 +131,59 @@ var (
 	ErrBadIP    = errors.New("Bad IP address or mask")
 )
 
+// ParseError is returned for IP/mask that can't be parsed, it tells the input and why it is bad.
+// It matches ErrBadIP with errors.Is.
+type ParseError struct {
+	Input  string
+	Reason string
+}
+
+func badIP(input []byte, reason string) error {
+	return &ParseError{Input: string(input), Reason: reason}
+}
+
+func (e *ParseError) Error() string {
+	return ErrBadIP.Error() + " " + strconv.Quote(e.Input) + ": " + e.Reason
+}
+
+func (e *ParseError) Unwrap() error {
+	return ErrBadIP
+}
+
 // GetStats get tree stats count of nodes, valued nodes, allocated nodes and free nodes
 func (tree *Tree) GetStats() (treeNodes, valuetreeNodes, totalNodes, freetotalNodes int) {
 	return tree.countNodes, tree.countValuedNodes, tree.countAllocNodes, tree.countFreeNodes
 }
 
-// NewTree creates Tree and preallocates (if preallocate not zero) number of countAllocNodes that would be ready to fill with data.
-func NewTree(preallocate int, safe bool) *Tree {
+// StatsFor gets stats for the subtree under the given cidr: count of nodes, valued nodes and the memory (in bytes) taken by those nodes.
+// Zero counts and no error are returned if the tree has no node for the cidr.
+func (tree *Tree) StatsFor(cidr string) (treeNodes, valuetreeNodes int, memBytes uintptr, err error) {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	n, err := tree.nodeCIDRb([]byte(cidr))
+	if err != nil || n == nil {
+		return 0, 0, 0, err
+	}
+	_, treeNodes, valuetreeNodes = subtreenodes(n)
+	return treeNodes, valuetreeNodes, uintptr(treeNodes) * unsafe.Sizeof(*n), nil
+}
+
+// NewTree creates Tree configured by the options, e.g. NewTree(WithPreallocate(6), WithLocking(true)) preallocates
+// nodes that would be ready to fill with data and protects operations with the lock of the tree.
+func NewTree(opts ...Option) *Tree {
 	tree := new(Tree)
-	tree.safe = safe
+	tree.opts = opts
+	for _, opt := range opts {
+		opt(tree)
+	}
+	if tree.capacityHint > 0 {
+		tree.reserve(estimateNodes(tree.capacityHint))
+	}
 	tree.countNodes++
 	tree.root = tree.newnode()
+	preallocate := tree.preallocate
 	if preallocate == 0 {
 		return tree
 	}
@@ -95,6 +214,9 @@ func NewTree(preallocate int, safe bool) *Tree {
 
 // AddCIDR adds value associated with IP/mask to the tree. Will return error for invalid CIDR or if value already exists.
 func (tree *Tree) AddCIDR(cidr string, val interface{}) error {
+	if zt, addr, zoned := tree.zoneTree(cidr, true); zoned {
+		return zt.AddCIDR(addr, val)
+	}
 	if tree.safe {
 		tree.Lock()
 		defer tree.Unlock()
@@ -103,7 +225,13 @@ func (tree *Tree) AddCIDR(cidr string, val interface{}) error {
 }
 
 func (tree *Tree) addCIDRb(cidr []byte, val interface{}) error {
-	if bytes.IndexByte(cidr, '.') > 0 {
+	if err := tree.checkZone(cidr); err != nil {
+		return err
+	}
+	if err := tree.checkStrict(cidr); err != nil {
+		return err
+	}
+	if tree.isIPv4(cidr) {
 		ip, mask, err := parsecidr4(cidr)
 		if err != nil {
 			return err
@@ -117,8 +245,50 @@ func (tree *Tree) addCIDRb(cidr []byte, val interface{}) error {
 	return tree.insert(ip, mask, val, false)
 }
 
+// AddCIDRGet is AddCIDR also returning the existing value when it returns ErrNodeBusy.
+func (tree *Tree) AddCIDRGet(cidr string, val interface{}) (existing interface{}, err error) {
+	if zt, addr, zoned := tree.zoneTree(cidr, true); zoned {
+		return zt.AddCIDRGet(addr, val)
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	if err = tree.addCIDRb([]byte(cidr), val); err == ErrNodeBusy {
+		existing, _ = tree.findExactCIDRb([]byte(cidr))
+	}
+	return existing, err
+}
+
+// AddCIDRMerge adds value associated with IP/mask to the tree, if value already exists it is replaced by
+// merge(old, val) (nil removes the value), so duplicate IP/masks accumulate values. All is done under one lock.
+func (tree *Tree) AddCIDRMerge(cidr string, val interface{}, merge func(old, new interface{}) interface{}) error {
+	if zt, addr, zoned := tree.zoneTree(cidr, true); zoned {
+		return zt.AddCIDRMerge(addr, val, merge)
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	err := tree.addCIDRb([]byte(cidr), val)
+	if err != ErrNodeBusy {
+		return err
+	}
+	old, err := tree.findExactCIDRb([]byte(cidr))
+	if err != nil {
+		return err
+	}
+	if val = merge(old, val); val == nil {
+		return tree.deleteCIDRb([]byte(cidr))
+	}
+	return tree.setCIDRb([]byte(cidr), val)
+}
+
 // SetCIDR adds value associated with IP/mask to the tree. Will return error for invalid CIDR.
 func (tree *Tree) SetCIDR(cidr string, val interface{}) error {
+	if zt, addr, zoned := tree.zoneTree(cidr, true); zoned {
+		return zt.SetCIDR(addr, val)
+	}
 	if tree.safe {
 		tree.Lock()
 		defer tree.Unlock()
@@ -126,8 +296,33 @@ func (tree *Tree) SetCIDR(cidr string, val interface{}) error {
 	return tree.setCIDRb([]byte(cidr), val)
 }
 
+// SetCIDRReport is SetCIDR also reporting whether the IP/mask had value before (updated) and the value it had.
+func (tree *Tree) SetCIDRReport(cidr string, val interface{}) (updated bool, prev interface{}, err error) {
+	if zt, addr, zoned := tree.zoneTree(cidr, true); zoned {
+		return zt.SetCIDRReport(addr, val)
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	prev, err = tree.findExactCIDRb([]byte(cidr))
+	if err != nil && err != ErrNotFound {
+		return false, nil, err
+	}
+	if err = tree.setCIDRb([]byte(cidr), val); err != nil {
+		return false, nil, err
+	}
+	return prev != nil, prev, nil
+}
+
 func (tree *Tree) setCIDRb(cidr []byte, val interface{}) error {
-	if bytes.IndexByte(cidr, '.') > 0 {
+	if err := tree.checkZone(cidr); err != nil {
+		return err
+	}
+	if err := tree.checkStrict(cidr); err != nil {
+		return err
+	}
+	if tree.isIPv4(cidr) {
 		ip, mask, err := parsecidr4(cidr)
 		if err != nil {
 			return err
@@ -144,6 +339,12 @@ func (tree *Tree) setCIDRb(cidr []byte, val interface{}) error {
 // DeleteWholeRangeCIDR removes all values associated with IPs
 // in the entire subnet specified by the CIDR.
 func (tree *Tree) DeleteWholeRangeCIDR(cidr string) error {
+	if zt, addr, zoned := tree.zoneTree(cidr, false); zoned {
+		if zt == nil {
+			return ErrNotFound
+		}
+		return zt.DeleteWholeRangeCIDR(addr)
+	}
 	if tree.safe {
 		tree.Lock()
 		defer tree.Unlock()
@@ -152,7 +353,10 @@ func (tree *Tree) DeleteWholeRangeCIDR(cidr string) error {
 }
 
 func (tree *Tree) deleteWholeRangeCIDRb(cidr []byte) error {
-	if bytes.IndexByte(cidr, '.') > 0 {
+	if err := tree.checkStrict(cidr); err != nil {
+		return err
+	}
+	if tree.isIPv4(cidr) {
 		ip, mask, err := parsecidr4(cidr)
 		if err != nil {
 			return err
@@ -168,6 +372,12 @@ func (tree *Tree) deleteWholeRangeCIDRb(cidr []byte) error {
 
 // DeleteCIDR removes value associated with IP/mask from the tree.
 func (tree *Tree) DeleteCIDR(cidr string) error {
+	if zt, addr, zoned := tree.zoneTree(cidr, false); zoned {
+		if zt == nil {
+			return ErrNotFound
+		}
+		return zt.DeleteCIDR(addr)
+	}
 	if tree.safe {
 		tree.Lock()
 		defer tree.Unlock()
@@ -176,7 +386,13 @@ func (tree *Tree) DeleteCIDR(cidr string) error {
 }
 
 func (tree *Tree) deleteCIDRb(cidr []byte) error {
-	if bytes.IndexByte(cidr, '.') > 0 {
+	if err := tree.checkZone(cidr); err != nil {
+		return err
+	}
+	if err := tree.checkStrict(cidr); err != nil {
+		return err
+	}
+	if tree.isIPv4(cidr) {
 		ip, mask, err := parsecidr4(cidr)
 		if err != nil {
 			return err
@@ -190,58 +406,286 @@ func (tree *Tree) deleteCIDRb(cidr []byte) error {
 	return tree.delete(ip, mask, false)
 }
 
-// FindCIDR traverses tree to proper Node and returns previously saved information in longest covered IP.
-func (tree *Tree) FindCIDR(cidr string) (interface{}, error) {
+// DeleteCIDRIf removes value associated with IP/mask from the tree only if pred approves the value, all under one lock.
+// Returns whether the value was removed, ErrNotFound is returned if there is no value for the IP/mask.
+func (tree *Tree) DeleteCIDRIf(cidr string, pred func(val interface{}) bool) (bool, error) {
+	if zt, addr, zoned := tree.zoneTree(cidr, false); zoned {
+		if zt == nil {
+			return false, ErrNotFound
+		}
+		return zt.DeleteCIDRIf(addr, pred)
+	}
 	if tree.safe {
 		tree.Lock()
 		defer tree.Unlock()
 	}
+	val, err := tree.findExactCIDRb([]byte(cidr))
+	if err != nil {
+		return false, err
+	}
+	if !pred(val) {
+		return false, nil
+	}
+	if err = tree.deleteCIDRb([]byte(cidr)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UpdateCIDR calls fn with value associated with IP/mask (found is false if there is none) and saves the value
+// fn returns, or removes the value if fn returns delete set. All is done under one lock.
+func (tree *Tree) UpdateCIDR(cidr string, fn func(old interface{}, found bool) (new interface{}, delete bool)) error {
+	if zt, addr, zoned := tree.zoneTree(cidr, true); zoned {
+		return zt.UpdateCIDR(addr, fn)
+	}
+	if err := tree.checkStrict([]byte(cidr)); err != nil {
+		return err
+	}
+	e, err := tree.parseEntry([]byte(cidr))
+	if err != nil {
+		return err
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	var old interface{}
+	if n := tree.entryNode(&e); n != nil {
+		old = n.value
+	}
+	val, del := fn(old, old != nil)
+	if del || val == nil {
+		if old == nil {
+			return nil
+		}
+		return tree.deleteEntry(&e, false)
+	}
+	e.value = val
+	return tree.insertEntry(&e, true)
+}
+
+// FindCIDR traverses tree to proper Node and returns previously saved information in longest covered IP.
+func (tree *Tree) FindCIDR(cidr string) (interface{}, error) {
+	if zt, addr, zoned := tree.zoneTree(cidr, false); zoned {
+		if zt != nil {
+			if val, err := zt.FindCIDR(addr); err != nil || val != nil {
+				return val, err
+			}
+		}
+		cidr = addr
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
 	return tree.findCIDRb([]byte(cidr))
 }
 
 func (tree *Tree) findCIDRb(cidr []byte) (interface{}, error) {
-	if bytes.IndexByte(cidr, '.') > 0 {
+	if tree.isIPv4(cidr) {
 		ip, mask, err := parsecidr4(cidr)
 		if err != nil {
 			return nil, err
 		}
-		values := tree.find32(ip, mask, findBest)
-		if len(values) > 0 {
-			return values[0], nil
-		} else {
-			return nil, nil
-		}
+		return tree.find32(ip, mask, findBest), nil
 	}
 	ip, mask, err := parsecidr6(cidr)
 	if err != nil || ip == nil {
 		return nil, err
 	}
-	values := tree.find(ip, mask, findBest)
-	if len(values) > 0 {
-		return values[0], nil
-	} else {
-		return nil, nil
+	return tree.find(ip, mask, findBest), nil
+}
+
+// Find32 returns previously saved information in longest covered IP of the IPv4 address given as uint32
+// (most significant byte first), without any parsing.
+func (tree *Tree) Find32(ip uint32) interface{} {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
 	}
+	return tree.find32(ip, 0xffffffff, findBest)
+}
+
+// Find128 returns previously saved information in longest covered IP of the IPv6 address, without any parsing.
+func (tree *Tree) Find128(ip [16]byte) interface{} {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	return tree.find(ip[:], fullmask6, findBest)
+}
+
+// FindCIDRNet traverses tree to proper Node and returns previously saved information in longest covered IP
+// together with the IP/mask the information was saved for.
+func (tree *Tree) FindCIDRNet(cidr string) (interface{}, net.IPNet, error) {
+	if zt, addr, zoned := tree.zoneTree(cidr, false); zoned {
+		if zt != nil {
+			if val, n, err := zt.FindCIDRNet(addr); err != nil || val != nil {
+				return val, n, err
+			}
+		}
+		cidr = addr
+	}
+	key, bits, err := tree.cidrKey(cidr)
+	if err != nil {
+		return nil, net.IPNet{}, err
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	if tree.guard != nil {
+		tree.guard.enterRead("FindCIDRNet")
+		defer tree.guard.exitRead()
+	}
+	n, depth := tree.bestNode(key, bits)
+	if n == nil {
+		return nil, net.IPNet{}, nil
+	}
+	return n.value, tree.blockNet(keyBlock(key, depth), tree.isIPv4([]byte(cidr))), nil
+}
+
+// FindResult is the longest match of FindCIDRResult: the value, mask length of the IP/mask it was saved for and
+// whether that IP/mask is the looked up cidr itself.
+type FindResult struct {
+	Value interface{}
+	Bits  int
+	Exact bool
+}
+
+// FindCIDRResult is FindCIDR returning also the mask length of the match, e.g. to prefer the more specific match
+// of several trees. Value of the default route (see SetDefaultRoute) is returned with Bits 0, a miss with nil Value.
+func (tree *Tree) FindCIDRResult(cidr string) (FindResult, error) {
+	if zt, addr, zoned := tree.zoneTree(cidr, false); zoned {
+		if zt != nil {
+			if r, err := zt.FindCIDRResult(addr); err != nil || r.Value != nil {
+				return r, err
+			}
+		}
+		cidr = addr
+	}
+	key, bits, err := tree.cidrKey(cidr)
+	if err != nil {
+		return FindResult{}, err
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	if tree.guard != nil {
+		tree.guard.enterRead("FindCIDRResult")
+		defer tree.guard.exitRead()
+	}
+	n, depth := tree.bestNode(key, bits)
+	if n == nil {
+		return FindResult{Value: tree.notFound(findBest)}, nil
+	}
+	r := FindResult{Value: n.value, Bits: depth, Exact: depth == bits}
+	if tree.mapped4 && depth >= mappedBits && tree.isIPv4([]byte(cidr)) {
+		r.Bits -= mappedBits
+	}
+	return r, nil
+}
+
+// ContainsCIDR tells whether the cidr is covered by an IP/mask with value and whether it has value itself (exact),
+// without counting hits or building results, for set membership. Invalid cidr and the default route (see
+// SetDefaultRoute) are not contained.
+func (tree *Tree) ContainsCIDR(cidr string) (contained, exact bool) {
+	if zt, addr, zoned := tree.zoneTree(cidr, false); zoned {
+		if zt != nil {
+			if contained, exact = zt.ContainsCIDR(addr); contained {
+				return contained, exact
+			}
+		}
+		cidr = addr
+	}
+	key, bits, err := tree.cidrKey(cidr)
+	if err != nil {
+		return false, false
+	}
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	now := tree.expiryNow()
+	n := tree.root
+	for i := 0; n != nil; i++ {
+		if n.value != nil && !expired(n, now) {
+			contained = true
+			exact = i == bits
+		}
+		if i == bits {
+			break
+		}
+		if keyBit(key, i) {
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	return contained, exact
+}
+
+// MatchFirst returns value of the most specific IP/mask covering addr whose value satisfies all preds, falling
+// back to less specific IP/masks otherwise (longest match with permitting entry), together with the IP/mask.
+func (tree *Tree) MatchFirst(addr string, preds ...func(val interface{}) bool) (interface{}, net.IPNet, error) {
+	if zt, a, zoned := tree.zoneTree(addr, false); zoned {
+		if zt != nil {
+			if val, n, err := zt.MatchFirst(a, preds...); err != nil || val != nil {
+				return val, n, err
+			}
+		}
+		addr = a
+	}
+	key, bits, err := tree.cidrKey(addr)
+	if err != nil {
+		return nil, net.IPNet{}, err
+	}
+	v4 := tree.isIPv4([]byte(addr))
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	if tree.guard != nil {
+		tree.guard.enterRead("MatchFirst")
+		defer tree.guard.exitRead()
+	}
+	nets := tree.findAllNets(key, bits, v4)
+next:
+	for i := len(nets) - 1; i >= 0; i-- {
+		for _, pred := range preds {
+			if !pred(nets[i].Value) {
+				continue next
+			}
+		}
+		return nets[i].Value, nets[i].Net, nil
+	}
+	return nil, net.IPNet{}, nil
 }
 
 // FindExactCIDR traverses tree to proper Node and returns previously saved information for an exact match.
 func (tree *Tree) FindExactCIDR(cidr string) (interface{}, error) {
+	if zt, addr, zoned := tree.zoneTree(cidr, false); zoned {
+		if zt == nil {
+			return nil, ErrNotFound
+		}
+		return zt.FindExactCIDR(addr)
+	}
 	if tree.safe {
-		tree.Lock()
-		defer tree.Unlock()
+		tree.RLock()
+		defer tree.RUnlock()
 	}
 	return tree.findExactCIDRb([]byte(cidr))
 }
 
 func (tree *Tree) findExactCIDRb(cidr []byte) (interface{}, error) {
-	if bytes.IndexByte(cidr, '.') > 0 {
+	if tree.isIPv4(cidr) {
 		ip, mask, err := parsecidr4(cidr)
 		if err != nil {
 			return nil, err
 		}
-		values := tree.find32(ip, mask, findExact)
-		if len(values) > 0 {
-			return values[0], nil
+		if value := tree.find32(ip, mask, findExact); value != nil {
+			return value, nil
 		}
 		return nil, ErrNotFound
 	}
@@ -249,38 +693,120 @@ func (tree *Tree) findExactCIDRb(cidr []byte) (interface{}, error) {
 	if err != nil || ip == nil {
 		return nil, err
 	}
-	values := tree.find(ip, mask, findExact)
-	if len(values) > 0 {
-		return values[0], nil
+	if value := tree.find(ip, mask, findExact); value != nil {
+		return value, nil
 	}
 	return nil, ErrNotFound
 }
 
 // FindAllCIDR traverses tree to proper Node and returns previously saved information in all covered IPs.
 func (tree *Tree) FindAllCIDR(cidr string) ([]interface{}, error) {
+	if zt, addr, zoned := tree.zoneTree(cidr, false); zoned {
+		if zt == nil {
+			return nil, nil
+		}
+		return zt.FindAllCIDR(addr)
+	}
 	if tree.safe {
-		tree.Lock()
-		defer tree.Unlock()
+		tree.RLock()
+		defer tree.RUnlock()
 	}
 	return tree.findAllCIDRb([]byte(cidr))
 }
 
 func (tree *Tree) findAllCIDRb(cidr []byte) ([]interface{}, error) {
-	var ret []interface{}
-	if bytes.IndexByte(cidr, '.') > 0 {
+	if tree.isIPv4(cidr) {
 		ip, mask, err := parsecidr4(cidr)
 		if err != nil {
 			return nil, err
 		}
-		ret = append(ret, tree.find32(ip, mask, findAll)...)
-		return ret, nil
+		return tree.findAll32(ip, mask), nil
 	}
 	ip, mask, err := parsecidr6(cidr)
 	if err != nil || ip == nil {
 		return nil, err
 	}
-	ret = append(ret, tree.find(ip, mask, findAll)...)
-	return ret, nil
+	return tree.findAll(ip, mask), nil
+}
+
+// NetValue is a value saved in the tree together with the IP/mask it was saved for.
+type NetValue struct {
+	Net   net.IPNet
+	Value interface{}
+}
+
+// FindAllCIDRNets traverses tree to proper Node and returns previously saved information in all covered IPs
+// together with IP/masks they were saved for, ordered from least to most specific.
+func (tree *Tree) FindAllCIDRNets(cidr string) ([]NetValue, error) {
+	if zt, addr, zoned := tree.zoneTree(cidr, false); zoned {
+		if zt == nil {
+			return nil, nil
+		}
+		return zt.FindAllCIDRNets(addr)
+	}
+	key, bits, err := tree.cidrKey(cidr)
+	if err != nil {
+		return nil, err
+	}
+	v4 := tree.isIPv4([]byte(cidr))
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	if tree.guard != nil {
+		tree.guard.enterRead("FindAllCIDRNets")
+		defer tree.guard.exitRead()
+	}
+	return tree.findAllNets(key, bits, v4), nil
+}
+
+// Supernets returns values saved for all IP/masks strictly covering the cidr together with the IP/masks,
+// ordered from shortest to longest prefix. Value saved for the cidr itself is not included.
+func (tree *Tree) Supernets(cidr string) ([]NetValue, error) {
+	if zt, addr, zoned := tree.zoneTree(cidr, false); zoned {
+		if zt == nil {
+			return nil, nil
+		}
+		return zt.Supernets(addr)
+	}
+	key, bits, err := tree.cidrKey(cidr)
+	if err != nil {
+		return nil, err
+	}
+	v4 := tree.isIPv4([]byte(cidr))
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	if tree.guard != nil {
+		tree.guard.enterRead("Supernets")
+		defer tree.guard.exitRead()
+	}
+	if bits == 0 {
+		return nil, nil
+	}
+	return tree.findAllNets(key, bits-1, v4), nil
+}
+
+// findAllNets returns values on the path of the key down to depth of bits.
+func (tree *Tree) findAllNets(key [16]byte, bits int, v4 bool) []NetValue {
+	var ret []NetValue
+	now := tree.expiryNow()
+	n := tree.root
+	for i := 0; n != nil; i++ {
+		if n.value != nil && !expired(n, now) {
+			ret = append(ret, NetValue{Net: tree.blockNet(keyBlock(key, i), v4), Value: n.value})
+		}
+		if i == bits {
+			break
+		}
+		if keyBit(key, i) {
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	return ret
 }
 
 // WalkTreeFunc is the type of function for caller of WalkTree function
@@ -290,30 +816,242 @@ type WalkTreeFunc func(cidr net.IPNet, value interface{}) (bool, error)
 // WalkTree walks the tree (depth first) and calls the `WalkTreeFunc` for each node with a value.
 func (tree *Tree) WalkTree(opt OptWalk, wtfunc WalkTreeFunc) error {
 	if tree.safe {
-		tree.Lock()
-		defer tree.Unlock()
+		tree.RLock()
+		defer tree.RUnlock()
 	}
-	walkpath := make([]byte, 0, 128)
-	return tree.walk(opt, wtfunc, walkpath, tree.root)
+	if tree.guard != nil {
+		tree.guard.enterRead("WalkTree")
+		defer tree.guard.exitRead()
+	}
+	return tree.walkNodes(opt, func(cidr net.IPNet, n *node) (bool, error) {
+		return wtfunc(cidr, n.value)
+	})
 }
 
-func (tree *Tree) walk(opt OptWalk, wtfunc WalkTreeFunc, walkpath []byte, node *node) error {
-	if node.value != nil {
-		ipnet := walkpath2net(opt, walkpath)
-		if goDeeper, err := wtfunc(ipnet, node.value); err != nil {
-			return err
-		} else if !goDeeper {
+// WalkTreeFiltered is WalkTree calling wtfunc only for values passing the filter, IP/masks of other values are
+// not even built.
+func (tree *Tree) WalkTreeFiltered(opt OptWalk, filter func(value interface{}) bool, wtfunc WalkTreeFunc) error {
+	return tree.walkTreeFiltered(opt, func(n *node, walkpath []byte) bool {
+		return filter(n.value)
+	}, net.IPv6len*8, wtfunc)
+}
+
+// WalkTreeLengths is WalkTree calling wtfunc only for IP/masks with mask length between minLen and maxLen
+// (inclusive, e.g. 24 and 24 for only /24s), the tree is not walked below maxLen.
+func (tree *Tree) WalkTreeLengths(opt OptWalk, minLen, maxLen int, wtfunc WalkTreeFunc) error {
+	maxDepth := maxLen
+	if tree.mapped4 {
+		maxDepth += mappedBits
+	}
+	return tree.walkTreeFiltered(opt, func(n *node, walkpath []byte) bool {
+		l := len(walkpath)
+		if tree.mapped4 && isMappedPath(walkpath) {
+			l -= mappedBits
+		}
+		return l >= minLen && l <= maxLen
+	}, maxDepth, wtfunc)
+}
+
+func (tree *Tree) walkTreeFiltered(opt OptWalk, keep func(n *node, walkpath []byte) bool, maxDepth int, wtfunc WalkTreeFunc) error {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	if tree.guard != nil {
+		tree.guard.enterRead("WalkTree")
+		defer tree.guard.exitRead()
+	}
+	return tree.walkNodesFiltered(opt, keep, maxDepth, func(cidr net.IPNet, n *node) (bool, error) {
+		return wtfunc(cidr, n.value)
+	})
+}
+
+// WalkTreeOrdered walks IP/masks with values in ascending order of their keys: by address, with IP/mask covering
+// others before them or, if mostSpecificFirst, after them (10.0.0.0/9, 10.128.0.0/9, 10.0.0.0/8). Order bits of opt
+// are ignored. IPv4 takes the first 32 bits of the key unless the tree is WithIPv4Mapped, so IPv4 and IPv6 IP/masks
+// may interleave. WalkTreeFunc returning false skips the IP/masks inside the cidr, unless mostSpecificFirst.
+func (tree *Tree) WalkTreeOrdered(opt OptWalk, mostSpecificFirst bool, wtfunc WalkTreeFunc) error {
+	opt &^= OptWalkInOrder | OptWalkPostOrder
+	if mostSpecificFirst {
+		opt |= OptWalkPostOrder
+	}
+	return tree.WalkTree(opt, wtfunc)
+}
+
+// WalkSubtree walks (depth first) only the part of the tree under the cidr and calls the `WalkTreeFunc`
+// for each node with a value, including the node of the cidr itself.
+func (tree *Tree) WalkSubtree(cidr string, wtfunc WalkTreeFunc) error {
+	if zt, addr, zoned := tree.zoneTree(cidr, false); zoned {
+		if zt == nil {
 			return nil
 		}
+		return zt.WalkSubtree(addr, wtfunc)
 	}
-	if node.left != nil {
-		if err := tree.walk(opt, wtfunc, append(walkpath, byte(0)), node.left); err != nil {
-			return err
+	key, bits, err := tree.cidrKey(cidr)
+	if err != nil {
+		return err
+	}
+	opt := tree.subtreeOpt(cidr)
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	if tree.guard != nil {
+		tree.guard.enterRead("WalkSubtree")
+		defer tree.guard.exitRead()
+	}
+	return tree.walkSubtree(key, bits, opt, func(cidr net.IPNet, n *node) (bool, error) {
+		return wtfunc(cidr, n.value)
+	})
+}
+
+// Subnets returns values saved for all IP/masks inside the cidr (including the cidr itself)
+// together with the IP/masks, in walk order.
+func (tree *Tree) Subnets(cidr string) ([]NetValue, error) {
+	if zt, addr, zoned := tree.zoneTree(cidr, false); zoned {
+		if zt == nil {
+			return nil, nil
 		}
+		return zt.Subnets(addr)
 	}
-	if node.right != nil {
-		if err := tree.walk(opt, wtfunc, append(walkpath, byte(1)), node.right); err != nil {
-			return err
+	key, bits, err := tree.cidrKey(cidr)
+	if err != nil {
+		return nil, err
+	}
+	opt := tree.subtreeOpt(cidr)
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	if tree.guard != nil {
+		tree.guard.enterRead("Subnets")
+		defer tree.guard.exitRead()
+	}
+	var ret []NetValue
+	tree.walkSubtree(key, bits, opt, func(cidr net.IPNet, n *node) (bool, error) {
+		ret = append(ret, NetValue{Net: cidr, Value: n.value})
+		return true, nil
+	})
+	return ret, nil
+}
+
+func (tree *Tree) walkSubtree(key [16]byte, bits int, opt OptWalk, fn walkNodeFunc) error {
+	walkpath := make([]byte, 0, 128)
+	n := tree.root
+	for i := 0; i < bits && n != nil; i++ {
+		if keyBit(key, i) {
+			n = n.right
+			walkpath = append(walkpath, byte(1))
+		} else {
+			n = n.left
+			walkpath = append(walkpath, byte(0))
+		}
+	}
+	if n == nil {
+		return nil
+	}
+	return tree.walk(opt, fn, walkpath, n)
+}
+
+// walkNodeFunc is the type of function called by walk for each node with a value.
+type walkNodeFunc func(cidr net.IPNet, n *node) (bool, error)
+
+func (tree *Tree) walkNodes(opt OptWalk, fn walkNodeFunc) error {
+	return tree.walkNodesFiltered(opt, nil, net.IPv6len*8, fn)
+}
+
+func (tree *Tree) walkNodesFiltered(opt OptWalk, keep func(n *node, walkpath []byte) bool, maxDepth int, fn walkNodeFunc) error {
+	walkpath := make([]byte, 0, 128)
+	if opt&OptWalkCollectErrors == 0 {
+		return tree.walkFiltered(opt, keep, maxDepth, fn, walkpath, tree.root)
+	}
+	var errs []error
+	tree.walkFiltered(opt, keep, maxDepth, func(cidr net.IPNet, n *node) (bool, error) {
+		goDeeper, err := fn(cidr, n)
+		if err != nil {
+			errs = append(errs, &WalkError{CIDR: cidr, Err: err})
+		}
+		return goDeeper, nil
+	}, walkpath, tree.root)
+	return errors.Join(errs...)
+}
+
+// WalkError is an error returned by WalkTreeFunc for the cidr, collected by walk with OptWalkCollectErrors.
+type WalkError struct {
+	CIDR net.IPNet
+	Err  error
+}
+
+func (e *WalkError) Error() string {
+	return e.CIDR.String() + ": " + e.Err.Error()
+}
+
+func (e *WalkError) Unwrap() error {
+	return e.Err
+}
+
+// walkFrame is a node on the explicit stack of the walk.
+type walkFrame struct {
+	node  *node
+	depth int
+	bit   byte
+	state byte // 0 before, 1 between and 2 after subtrees of the node
+}
+
+// walk walks the subtree of the node whose path from the root is walkpath, without recursion: frames of the nodes
+// on the current path are kept on a stack and the path itself in walkpath (the frame sets its bit when entered).
+func (tree *Tree) walk(opt OptWalk, wtfunc walkNodeFunc, walkpath []byte, root *node) error {
+	return tree.walkFiltered(opt, nil, net.IPv6len*8, wtfunc, walkpath, root)
+}
+
+// walkFiltered is walk calling wtfunc only for nodes passing keep (if set) and not going deeper than maxDepth,
+// keep is called before the cidr of the node is built.
+func (tree *Tree) walkFiltered(opt OptWalk, keep func(n *node, walkpath []byte) bool, maxDepth int, wtfunc walkNodeFunc, walkpath []byte, root *node) error {
+	var visitState byte
+	switch opt & (OptWalkInOrder | OptWalkPostOrder) {
+	case OptWalkInOrder:
+		visitState = 1
+	case OptWalkPostOrder:
+		visitState = 2
+	}
+	opt = tree.walkOpt(opt)
+	now := tree.expiryNow()
+	base := len(walkpath)
+	stack := make([]walkFrame, 1, net.IPv6len*8+1)
+	stack[0] = walkFrame{node: root, depth: base}
+	for len(stack) > 0 {
+		f := &stack[len(stack)-1]
+		n := f.node
+		visit := f.state == visitState && n.value != nil && (opt&optWalkExpired != 0 || !expired(n, now))
+		if f.state == 0 && f.depth > base {
+			walkpath = append(walkpath[:f.depth-1], f.bit)
+		}
+		if visit && keep != nil {
+			visit = keep(n, walkpath[:f.depth])
+		}
+		if visit {
+			goDeeper, err := wtfunc(walkpath2net(opt, walkpath[:f.depth]), n)
+			if err != nil {
+				return err
+			}
+			if !goDeeper {
+				stack = stack[:len(stack)-1]
+				continue
+			}
+		}
+		var next *node
+		switch f.state {
+		case 0:
+			next = n.left
+		case 1:
+			next = n.right
+		default:
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		f.state++
+		if next != nil && f.depth < maxDepth {
+			stack = append(stack, walkFrame{node: next, depth: f.depth + 1, bit: f.state - 1})
 		}
 	}
 	return nil
@@ -337,6 +1075,15 @@ func walkpath2net(opt OptWalk, walkpath []byte) net.IPNet {
 		bitval >>= 1
 	}
 	ip = append(ip, byteval)
+	if opt&optWalkMapped4 != 0 {
+		if isMappedPath(walkpath) {
+			if opt&OptWalkIPv4 == 0 {
+				return net.IPNet{}
+			}
+			return walkpath2net(OptWalkIPv4, walkpath[mappedBits:])
+		}
+		opt &^= OptWalkIPv4
+	}
 	switch {
 	case opt&OptWalkIPv4 != 0 && len(ip) <= net.IPv4len:
 		mask := net.CIDRMask(len(walkpath), net.IPv4len*8)
@@ -355,6 +1102,24 @@ func walkpath2net(opt OptWalk, walkpath []byte) net.IPNet {
 }
 
 func (tree *Tree) insert32(key, mask uint32, value interface{}, overwrite bool) error {
+	if tree.mapped4 {
+		ip, m := mapped6(key, mask)
+		return tree.insert(ip, m, value, overwrite)
+	}
+	if tree.lru != nil {
+		defer tree.evict()
+	}
+	if tree.metrics != nil {
+		defer tree.observe("insert", time.Now())
+	}
+	if tree.guard != nil {
+		tree.guard.enterWrite("insert")
+		defer tree.guard.exitWrite()
+	}
+	tree.generation++
+	if tree.misses != nil {
+		tree.misses.invalidate32(key, mask)
+	}
 	bit := startbit
 	node := tree.root
 	next := tree.root
@@ -371,15 +1136,28 @@ func (tree *Tree) insert32(key, mask uint32, value interface{}, overwrite bool)
 		node = next
 	}
 	if next != nil {
-		if node.value != nil && !overwrite {
+		if node.value != nil && !overwrite && !expired(node, tree.expiryNow()) {
 			return ErrNodeBusy
 		}
-		node.value = value
-		if !overwrite {
+		switch {
+		case node.value == nil && value != nil:
 			tree.countValuedNodes++
+		case node.value != nil && value == nil:
+			tree.countValuedNodes--
+		}
+		old := node.value
+		node.value = value
+		tree.changed(node, old, value)
+		tree.inserted(node)
+		if value == nil {
+			tree.trimEmpty(node)
 		}
 		return nil
 	}
+	if value == nil {
+		// no value, no nodes
+		return nil
+	}
 	for bit&mask != 0 {
 		next = tree.newnode()
 		tree.countNodes++
@@ -393,15 +1171,34 @@ func (tree *Tree) insert32(key, mask uint32, value interface{}, overwrite bool)
 		node = next
 	}
 	node.value = value
-	tree.countValuedNodes++
+	if value != nil {
+		tree.countValuedNodes++
+	}
+	tree.changed(node, nil, value)
+	tree.inserted(node)
 
 	return nil
 }
 
 func (tree *Tree) insert(key net.IP, mask net.IPMask, value interface{}, overwrite bool) error {
+	if tree.lru != nil {
+		defer tree.evict()
+	}
+	if tree.metrics != nil {
+		defer tree.observe("insert", time.Now())
+	}
+	if tree.guard != nil {
+		tree.guard.enterWrite("insert")
+		defer tree.guard.exitWrite()
+	}
+	tree.generation++
 	if len(key) != len(mask) {
 		return ErrBadIP
 	}
+	if tree.misses != nil {
+		ones, _ := mask.Size()
+		tree.misses.invalidate(key, ones)
+	}
 
 	var i int
 	bit := startbyte
@@ -428,13 +1225,26 @@ func (tree *Tree) insert(key net.IP, mask net.IPMask, value interface{}, overwri
 
 	}
 	if next != nil {
-		if node.value != nil && !overwrite {
+		if node.value != nil && !overwrite && !expired(node, tree.expiryNow()) {
 			return ErrNodeBusy
 		}
-		node.value = value
-		if !overwrite {
+		switch {
+		case node.value == nil && value != nil:
 			tree.countValuedNodes++
+		case node.value != nil && value == nil:
+			tree.countValuedNodes--
 		}
+		old := node.value
+		node.value = value
+		tree.changed(node, old, value)
+		tree.inserted(node)
+		if value == nil {
+			tree.trimEmpty(node)
+		}
+		return nil
+	}
+	if value == nil {
+		// no value, no nodes
 		return nil
 	}
 
@@ -456,11 +1266,46 @@ func (tree *Tree) insert(key net.IP, mask net.IPMask, value interface{}, overwri
 		}
 	}
 	node.value = value
-	tree.countValuedNodes++
+	if value != nil {
+		tree.countValuedNodes++
+	}
+	tree.changed(node, nil, value)
+	tree.inserted(node)
 
 	return nil
 }
 
+// inserted is called for the node which just got its value set by insert.
+func (tree *Tree) inserted(n *node) {
+	if tree.metaNow != nil {
+		tree.touchMeta(n)
+	}
+	if n.meta != nil || tree.pendingExpiry != 0 {
+		tree.setExpiry(n)
+	}
+	if tree.countHits && n.value != nil && n.meta == nil {
+		n.meta = new(nodeMeta)
+	}
+	if tree.aggregateEqual != nil && n.value != nil {
+		tree.aggregate(n)
+	}
+}
+
+// trimEmpty releases node n if it has neither value nor children, then its parents left the same way (not the root).
+func (tree *Tree) trimEmpty(n *node) {
+	for n != tree.root && n.value == nil && n.left == nil && n.right == nil {
+		p := n.parent
+		if p.right == n {
+			p.right = nil
+		} else {
+			p.left = nil
+		}
+		n.meta = nil
+		tree.updateUnused(n)
+		n = p
+	}
+}
+
 func subtreenodes(n *node) (retn []*node, nodeCount, valueCount int) {
 	if n.value != nil {
 		valueCount++
@@ -497,6 +1342,18 @@ func (tree *Tree) updateUnused(n *node) {
 }
 
 func (tree *Tree) delete32(key, mask uint32, wholeRange bool) error {
+	if tree.mapped4 {
+		ip, m := mapped6(key, mask)
+		return tree.delete(ip, m, wholeRange)
+	}
+	if tree.metrics != nil {
+		defer tree.observe("delete", time.Now())
+	}
+	if tree.guard != nil {
+		tree.guard.enterWrite("delete")
+		defer tree.guard.exitWrite()
+	}
+	tree.generation++
 	bit := startbit
 	node := tree.root
 	for node != nil && bit&mask != 0 {
@@ -514,12 +1371,20 @@ func (tree *Tree) delete32(key, mask uint32, wholeRange bool) error {
 	if !wholeRange && (node.right != nil || node.left != nil) {
 		// keep it just trim value
 		if node.value != nil {
+			tree.changed(node, node.value, nil)
 			node.value = nil
+			node.meta = nil
 			tree.countValuedNodes--
 			return nil
 		}
 		return ErrNotFound
 	}
+	if !wholeRange && node.value == nil {
+		// empty root (or preallocated leaf) has nothing to delete
+		return ErrNotFound
+	}
+
+	tree.removing(node)
 
 	// need to trim whole branch
 	for {
@@ -533,6 +1398,11 @@ func (tree *Tree) delete32(key, mask uint32, wholeRange bool) error {
 				tree.updateUnused(node.left)
 				node.left = nil
 			}
+			if node.value != nil {
+				node.value = nil
+				node.meta = nil
+				tree.countValuedNodes--
+			}
 			break
 		} else if node.parent.right == node {
 			node.parent.right = nil
@@ -553,11 +1423,20 @@ func (tree *Tree) delete32(key, mask uint32, wholeRange bool) error {
 			break
 		}
 	}
+	tree.autoShrink()
 
 	return nil
 }
 
 func (tree *Tree) delete(key net.IP, mask net.IPMask, wholeRange bool) error {
+	if tree.metrics != nil {
+		defer tree.observe("delete", time.Now())
+	}
+	if tree.guard != nil {
+		tree.guard.enterWrite("delete")
+		defer tree.guard.exitWrite()
+	}
+	tree.generation++
 	if len(key) != len(mask) {
 		return ErrBadIP
 	}
@@ -585,12 +1464,20 @@ func (tree *Tree) delete(key net.IP, mask net.IPMask, wholeRange bool) error {
 	if !wholeRange && (node.right != nil || node.left != nil) {
 		// keep it just trim value
 		if node.value != nil {
+			tree.changed(node, node.value, nil)
 			node.value = nil
+			node.meta = nil
 			tree.countValuedNodes--
 			return nil
 		}
 		return ErrNotFound
 	}
+	if !wholeRange && node.value == nil {
+		// empty root (or preallocated leaf) has nothing to delete
+		return ErrNotFound
+	}
+
+	tree.removing(node)
 
 	// need to trim whole branch
 	for {
@@ -604,6 +1491,11 @@ func (tree *Tree) delete(key net.IP, mask net.IPMask, wholeRange bool) error {
 				tree.updateUnused(node.left)
 				node.left = nil
 			}
+			if node.value != nil {
+				node.value = nil
+				node.meta = nil
+				tree.countValuedNodes--
+			}
 			break
 		} else if node.parent.right == node {
 			node.parent.right = nil
@@ -623,23 +1515,116 @@ func (tree *Tree) delete(key net.IP, mask net.IPMask, wholeRange bool) error {
 			break
 		}
 	}
+	tree.autoShrink()
 
 	return nil
 }
 
-func (tree *Tree) find32(key, mask uint32, what findWhat) []interface{} {
+// nodeCIDRb returns the node located exactly at the IP/mask, nil if there is no such node in the tree.
+func (tree *Tree) nodeCIDRb(cidr []byte) (*node, error) {
+	if err := tree.checkZone(cidr); err != nil {
+		return nil, err
+	}
+	if tree.isIPv4(cidr) {
+		ip, mask, err := parsecidr4(cidr)
+		if err != nil {
+			return nil, err
+		}
+		return tree.node32(ip, mask), nil
+	}
+	ip, mask, err := parsecidr6(cidr)
+	if err != nil {
+		return nil, err
+	}
+	return tree.node(ip, mask), nil
+}
+
+func (tree *Tree) node32(key, mask uint32) *node {
+	if tree.mapped4 {
+		ip, m := mapped6(key, mask)
+		return tree.node(ip, m)
+	}
+	bit := startbit
+	node := tree.root
+	for node != nil && bit&mask != 0 {
+		if key&bit != 0 {
+			node = node.right
+		} else {
+			node = node.left
+		}
+		bit >>= 1
+	}
+	return node
+}
+
+func (tree *Tree) node(key net.IP, mask net.IPMask) *node {
+	if len(key) != len(mask) {
+		return nil
+	}
+
+	var i int
+	bit := startbyte
+	node := tree.root
+	for node != nil && bit&mask[i] != 0 {
+		if key[i]&bit != 0 {
+			node = node.right
+		} else {
+			node = node.left
+		}
+		if bit >>= 1; bit == 0 {
+			if i++; i == len(key) {
+				break
+			}
+			bit = startbyte
+		}
+	}
+	return node
+}
+
+// find32 returns value of the longest match of the key (or of the exact match for findExact), the default route
+// or nil if there is none.
+func (tree *Tree) find32(key, mask uint32, what findWhat) interface{} {
+	if n := tree.match32(key, mask, what, nil); n != nil {
+		return n.value
+	}
+	return tree.notFound(what)
+}
+
+// findAll32 returns values of all matches of the key, from the least specific.
+func (tree *Tree) findAll32(key, mask uint32) []interface{} {
 	var ret []interface{}
+	tree.match32(key, mask, findAll, &ret)
+	return ret
+}
+
+// match32 returns node of the longest match of the key (of the exact match for findExact), nil if there is none.
+// Values of all matches are appended to all for findAll, other lookups do not allocate.
+func (tree *Tree) match32(key, mask uint32, what findWhat, all *[]interface{}) *node {
+	if tree.mapped4 {
+		ip, m := mapped6(key, mask)
+		return tree.match(ip, m, what, all)
+	}
+	if tree.metrics != nil {
+		defer tree.observe("find", time.Now())
+	}
+	if tree.guard != nil {
+		tree.guard.enterRead("find")
+		defer tree.guard.exitRead()
+	}
+	if tree.misses != nil && mask == 0xffffffff && tree.misses.has(missKey32(key)) {
+		return nil
+	}
 	var exact bool
+	var hit *node
+	now := tree.expiryNow()
 	bit := startbit
 	node := tree.root
 	for node != nil {
-		if node.value != nil {
+		if node.value != nil && !expired(node, now) {
 			if what == findAll {
-				ret = append(ret, node.value)
-			} else {
-				ret = append(ret[:0], node.value)
+				*all = append(*all, node.value)
 			}
-			exact = (mask&bit == 0)
+			exact, hit = (mask&bit == 0), node
 		}
 		if mask&bit == 0 {
 			break
@@ -651,29 +1636,64 @@ func (tree *Tree) find32(key, mask uint32, what findWhat) []interface{} {
 		}
 		bit >>= 1
 	}
-	if !exact && what == findExact {
-		return nil
+	if tree.misses != nil && mask == 0xffffffff && hit == nil {
+		tree.misses.add(missKey32(key))
+	}
+	if what == findExact {
+		if !exact {
+			return nil
+		}
+		return hit
+	}
+	if hit != nil {
+		tree.hit(hit)
 	}
+	return hit
+}
+
+// find returns value of the longest match of the key (or of the exact match for findExact), the default route
+// or nil if there is none.
+func (tree *Tree) find(key net.IP, mask net.IPMask, what findWhat) interface{} {
+	if n := tree.match(key, mask, what, nil); n != nil {
+		return n.value
+	}
+	return tree.notFound(what)
+}
+
+// findAll returns values of all matches of the key, from the least specific.
+func (tree *Tree) findAll(key net.IP, mask net.IPMask) []interface{} {
+	var ret []interface{}
+	tree.match(key, mask, findAll, &ret)
 	return ret
 }
 
-func (tree *Tree) find(key net.IP, mask net.IPMask, what findWhat) []interface{} {
+// match is match32 of IPv6 key.
+func (tree *Tree) match(key net.IP, mask net.IPMask, what findWhat, all *[]interface{}) *node {
+	if tree.metrics != nil {
+		defer tree.observe("find", time.Now())
+	}
+	if tree.guard != nil {
+		tree.guard.enterRead("find")
+		defer tree.guard.exitRead()
+	}
 	if len(key) != len(mask) {
 		return nil
 	}
-	var ret []interface{}
+	if tree.misses != nil && len(key) == net.IPv6len && bytes.Equal(mask, fullmask6) && tree.misses.has(missKey128(key)) {
+		return nil
+	}
 	var exact bool
+	var hit *node
 	var i int
+	now := tree.expiryNow()
 	bit := startbyte
 	node := tree.root
 	for node != nil {
-		if node.value != nil {
+		if node.value != nil && !expired(node, now) {
 			if what == findAll {
-				ret = append(ret, node.value)
-			} else {
-				ret = append(ret[:0], node.value)
+				*all = append(*all, node.value)
 			}
-			exact = mask[i]&bit == 0
+			exact, hit = mask[i]&bit == 0, node
 		}
 		if mask[i]&bit == 0 {
 			break
@@ -687,25 +1707,39 @@ func (tree *Tree) find(key net.IP, mask net.IPMask, what findWhat) []interface{}
 			i, bit = i+1, startbyte
 			if i >= len(key) {
 				// reached depth of the tree, there should be matching node...
-				if node != nil {
+				if node != nil && node.value != nil && !expired(node, now) {
 					if what == findAll {
-						ret = append(ret, node.value)
-					} else {
-						ret = append(ret[:0], node.value)
+						*all = append(*all, node.value)
 					}
-					exact = (node.value != nil)
+					exact, hit = true, node
+				} else {
+					exact = false
 				}
 				break
 			}
 		}
 	}
-	if !exact && what == findExact {
-		return nil
+	if tree.misses != nil && len(key) == net.IPv6len && bytes.Equal(mask, fullmask6) && hit == nil {
+		tree.misses.add(missKey128(key))
 	}
-	return ret
+	if what == findExact {
+		if !exact {
+			return nil
+		}
+		return hit
+	}
+	if hit != nil {
+		tree.hit(hit)
+	}
+	return hit
 }
 
 func (tree *Tree) newnode() (p *node) {
+	if tree.free == nil && tree.pool != nil {
+		tree.free = tree.pool.get(poolBatch)
+		tree.countAllocNodes += poolBatch
+		tree.countFreeNodes += poolBatch
+	}
 	if tree.free != nil {
 		p = tree.free
 		tree.free = tree.free.right
@@ -716,14 +1750,16 @@ func (tree *Tree) newnode() (p *node) {
 		p.parent = nil
 		p.left = nil
 		p.value = nil
+		p.meta = nil
 		return p
 	}
 
 	ln := len(tree.alloc)
 	if ln == cap(tree.alloc) {
 		// filled one row, make bigger one
-		tree.countAllocNodes += ln + 200
-		tree.alloc = make([]node, ln+200)[:1] // 200, 600, 1400, 3000, 6200, 12600 ...
+		size := tree.chunkSize(ln)
+		tree.countAllocNodes += size
+		tree.alloc = make([]node, size)[:1] // 200, 400, 600, 800 ... by default
 		ln = 0
 	} else {
 		tree.alloc = tree.alloc[:ln+1]
@@ -731,7 +1767,7 @@ func (tree *Tree) newnode() (p *node) {
 	return &(tree.alloc[ln])
 }
 
-func loadip4(ipstr []byte) (uint32, error) {
+func loadip4(ipstr []byte) (uint32, string) {
 	var (
 		ip  uint32
 		oct uint32
@@ -743,63 +1779,141 @@ func loadip4(ipstr []byte) (uint32, error) {
 		switch {
 		case b == '.':
 			num++
-			if 0xffffffff-ip < oct {
-				return 0, ErrBadIP
+			if num > 3 {
+				return 0, "too many octets"
 			}
 			ip = ip<<8 + oct
 			oct = 0
 		case b >= '0' && b <= '9':
 			oct = oct*10 + uint32(b-'0')
 			if oct > 255 {
-				return 0, ErrBadIP
+				return 0, "octet out of range"
 			}
 		default:
-			return 0, ErrBadIP
+			return 0, "unexpected character " + strconv.QuoteRune(rune(b))
 		}
 	}
 	if num != 3 {
-		return 0, ErrBadIP
+		return 0, "too few octets"
 	}
-	if 0xffffffff-ip < oct {
-		return 0, ErrBadIP
+	return ip<<8 + oct, ""
+}
+
+// isIPv4 tells whether the cidr is IPv4 one.
+func isIPv4(cidr []byte) bool {
+	return bytes.IndexByte(cidr, ':') < 0 && bytes.IndexByte(cidr, '.') > 0
+}
+
+// isIPv4 tells whether the cidr is IPv4 one, IPv4-mapped IPv6 addresses (::ffff:1.2.3.4, with mask of at least
+// 96 bits if any) are taken as IPv4 ones if the tree is WithUnmapIPv4.
+func (tree *Tree) isIPv4(cidr []byte) bool {
+	if isIPv4(cidr) {
+		return true
+	}
+	if !tree.unmap4 {
+		return false
+	}
+	_, _, ok := mapped4(cidr)
+	return ok
+}
+
+// mappedGroup is the group every IPv4-mapped address has.
+var mappedGroup = []byte("ffff:")
+
+// mapped4 parses IPv4-mapped IPv6 cidr into IPv4 address and mask.
+func mapped4(cidr []byte) (ip, mask uint32, ok bool) {
+	// skip parsing of other IPv6 addresses
+	found := false
+	for i := 0; i+len(mappedGroup) <= len(cidr) && !found; i++ {
+		found = bytes.EqualFold(cidr[i:i+len(mappedGroup)], mappedGroup)
 	}
-	return ip<<8 + oct, nil
+	if !found {
+		return 0, 0, false
+	}
+	addr, ones := cidr, 128
+	if p := bytes.IndexByte(cidr, '/'); p > 0 {
+		n, err := strconv.Atoi(string(cidr[p+1:]))
+		if err != nil || n < 96 || n > 128 {
+			return 0, 0, false
+		}
+		addr, ones = cidr[:p], n
+	}
+	ip4 := net.ParseIP(string(addr)).To4()
+	if ip4 == nil {
+		return 0, 0, false
+	}
+	return binary.BigEndian.Uint32(ip4), 0xffffffff << (128 - ones), true
 }
 
 func parsecidr4(cidr []byte) (uint32, uint32, error) {
+	if bytes.IndexByte(cidr, ':') >= 0 {
+		ip, mask, ok := mapped4(cidr)
+		if !ok {
+			return 0, 0, badIP(cidr, "not an IPv4-mapped address")
+		}
+		return ip, mask, nil
+	}
 	var mask uint32
+	input := cidr
 	p := bytes.IndexByte(cidr, '/')
 	if p > 0 {
+		if p == len(cidr)-1 {
+			return 0, 0, badIP(input, "bad mask")
+		}
 		for _, c := range cidr[p+1:] {
 			if c < '0' || c > '9' {
-				return 0, 0, ErrBadIP
+				return 0, 0, badIP(input, "bad mask")
+			}
+			if mask = mask*10 + uint32(c-'0'); mask > 32 {
+				return 0, 0, badIP(input, "mask longer than 32 bits")
 			}
-			mask = mask*10 + uint32(c-'0')
 		}
 		mask = 0xffffffff << (32 - mask)
 		cidr = cidr[:p]
 	} else {
 		mask = 0xffffffff
 	}
-	ip, err := loadip4(cidr)
-	if err != nil {
-		return 0, 0, err
+	ip, reason := loadip4(cidr)
+	if reason != "" {
+		return 0, 0, badIP(input, reason)
 	}
 	return ip, mask, nil
 }
 
+// checkStrict rejects IP/mask with bits set beyond the mask if the tree is strict (see WithStrictHostBits),
+// otherwise reports its normalization to the function set by WithOnNormalize.
+func (tree *Tree) checkStrict(cidr []byte) error {
+	if !tree.strict && tree.onNormalize == nil {
+		return nil
+	}
+	network, set, err := hostBits(cidr, tree.isIPv4(cidr))
+	switch {
+	case err != nil:
+		return err
+	case !set:
+		return nil
+	case tree.strict:
+		return badIP(cidr, "host bits set beyond mask")
+	}
+	tree.onNormalize(string(cidr), network)
+	return nil
+}
+
 func parsecidr6(cidr []byte) (net.IP, net.IPMask, error) {
+	cidr = stripZone(cidr)
 	p := bytes.IndexByte(cidr, '/')
 	if p > 0 {
 		_, ipm, err := net.ParseCIDR(string(cidr))
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, badIP(cidr, "malformed IPv6 address or mask")
 		}
 		return ipm.IP, ipm.Mask, nil
 	}
 	ip := net.ParseIP(string(cidr))
 	if ip == nil {
-		return nil, nil, ErrBadIP
+		return nil, nil, badIP(cidr, "malformed IPv6 address")
 	}
-	return ip, net.IPMask{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, nil
+	return ip, fullmask6, nil
 }
+
+var fullmask6 = net.IPMask{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
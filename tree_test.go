@@ -5,12 +5,16 @@
 package nradix
 
 import (
+	"errors"
+	"fmt"
 	"net"
+	"net/netip"
+	"strings"
 	"testing"
 )
 
 func TestTree(t *testing.T) {
-	tr := NewTree(0)
+	tr := NewTree()
 	if tr == nil || tr.root == nil {
 		t.Error("Did not create tree properly")
 	}
@@ -189,7 +193,7 @@ func TestTree(t *testing.T) {
 }
 
 func TestFindExact(t *testing.T) {
-	tr := NewTree(0)
+	tr := NewTree()
 	if tr == nil || tr.root == nil {
 		t.Error("Did not create tree properly")
 	}
@@ -283,7 +287,7 @@ func TestFindExact(t *testing.T) {
 }
 
 func TestFindAll(t *testing.T) {
-	tr := NewTree(0)
+	tr := NewTree()
 	if tr == nil || tr.root == nil {
 		t.Error("Did not create tree properly")
 	}
@@ -509,7 +513,7 @@ func TestFindAll(t *testing.T) {
 }
 
 func TestSet(t *testing.T) {
-	tr := NewTree(0)
+	tr := NewTree()
 	if tr == nil || tr.root == nil {
 		t.Error("Did not create tree properly")
 	}
@@ -587,7 +591,7 @@ func TestSet(t *testing.T) {
 }
 
 func TestRegression(t *testing.T) {
-	tr := NewTree(0)
+	tr := NewTree()
 	if tr == nil || tr.root == nil {
 		t.Error("Did not create tree properly")
 	}
@@ -607,7 +611,7 @@ func TestRegression(t *testing.T) {
 }
 
 func TestTree6(t *testing.T) {
-	tr := NewTree(0)
+	tr := NewTree()
 	if tr == nil || tr.root == nil {
 		t.Error("Did not create tree properly")
 	}
@@ -661,7 +665,7 @@ func TestTree6(t *testing.T) {
 }
 
 func TestRegression6(t *testing.T) {
-	tr := NewTree(0)
+	tr := NewTree()
 	if tr == nil || tr.root == nil {
 		t.Error("Did not create tree properly")
 	}
@@ -677,8 +681,138 @@ func TestRegression6(t *testing.T) {
 	}
 }
 
+func TestParseError(t *testing.T) {
+	tr := NewTree()
+	for _, tc := range []struct {
+		cidr, reason string
+	}{
+		{"1.2.3.256", "octet out of range"},
+		{"1.2.3", "too few octets"},
+		{"1.2.3.4.5", "too many octets"},
+		{"1.2.3.x/24", "unexpected character 'x'"},
+		{"1.2.3.0/2a", "bad mask"},
+		{"1.2.3.0/", "bad mask"},
+		{"1.2.3.0/33", "mask longer than 32 bits"},
+		{"1.2.3.0/4294967328", "mask longer than 32 bits"},
+		{"2001:db8::g", "malformed IPv6 address"},
+		{"2001:db8::/129", "malformed IPv6 address or mask"},
+	} {
+		err := tr.AddCIDR(tc.cidr, 1)
+		if !errors.Is(err, ErrBadIP) {
+			t.Errorf("Expected ErrBadIP for %s, got %v", tc.cidr, err)
+		}
+		var perr *ParseError
+		if !errors.As(err, &perr) || perr.Input != tc.cidr || perr.Reason != tc.reason {
+			t.Errorf("Wrong parse error for %s, expected reason %q, got %v", tc.cidr, tc.reason, err)
+		}
+	}
+}
+
+func TestStrictCIDR(t *testing.T) {
+	tr := NewTree()
+	if err := tr.AddCIDR("10.1.2.3/8", 1); err != nil {
+		t.Error(err)
+	}
+	if inf, err := tr.FindExactCIDR("10.0.0.0/8"); err != nil || inf != 1 {
+		t.Errorf("Expected normalized 10.0.0.0/8, got %v %v", inf, err)
+	}
+
+	tr = NewTree(WithStrictCIDR())
+	for _, cidr := range []string{"10.1.2.3/8", "2001:db8::1/64"} {
+		err := tr.AddCIDR(cidr, 1)
+		var perr *ParseError
+		if !errors.As(err, &perr) || perr.Reason != "host bits set beyond mask" {
+			t.Errorf("Expected host bits error for %s, got %v", cidr, err)
+		}
+	}
+	for _, cidr := range []string{"10.0.0.0/8", "10.1.2.3", "2001:db8::/64", "2001:db8::1"} {
+		if err := tr.AddCIDR(cidr, 1); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := tr.DeleteCIDR("10.1.2.3/8"); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Expected ErrBadIP, got %v", err)
+	}
+	if err := tr.BulkAdd([]PrefixValue{{"11.0.0.0/8", 1}, {"12.0.0.1/8", 2}}); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Expected ErrBadIP, got %v", err)
+	}
+	if _, err := NewTreeFromMap(map[string]interface{}{"12.0.0.1/8": 1}, WithStrictCIDR()); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Expected ErrBadIP, got %v", err)
+	}
+}
+
+func TestIPv4Mapped(t *testing.T) {
+	tr := NewTree(WithUnmapIPv4())
+	if err := tr.AddCIDR("1.2.3.0/24", 1); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("::ffff:10.0.0.0/104", 2); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("64:ff9b::1.2.3.0/120", 3); err != nil {
+		t.Error(err)
+	}
+	for ip, expected := range map[string]interface{}{
+		"::ffff:1.2.3.4":   1,
+		"::FFFF:102:304":   1,
+		"10.1.1.1":         2,
+		"::ffff:10.1.1.1":  2,
+		"::1.2.3.4":        nil,
+		"64:ff9b::1.2.3.4": 3,
+		"::ffff:1.2.4.1":   nil,
+	} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != expected {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, expected, inf)
+		}
+	}
+	if inf, _ := tr.FindAddr(netip.MustParseAddr("::ffff:1.2.3.4")); inf != 1 {
+		t.Errorf("Wrong value for mapped netip address, expected 1, got %v", inf)
+	}
+	if _, ipnet, _ := tr.FindCIDRNet("::ffff:10.1.1.1"); ipnet.String() != "10.0.0.0/8" {
+		t.Errorf("Wrong network, expected 10.0.0.0/8, got %v", ipnet.String())
+	}
+	if _, err := tr.FindCIDR("::ffff:1.2.3.0/80"); err != nil {
+		t.Errorf("Expected IPv6 prefix shorter than mapped range to parse, got %v", err)
+	}
+}
+
+func TestIPv4MappedDefault(t *testing.T) {
+	tr := NewTree()
+	if err := tr.AddCIDR("1.2.3.0/24", 1); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("::ffff:10.0.0.0/104", 2); err != nil {
+		t.Error(err)
+	}
+	for ip, expected := range map[string]interface{}{
+		"1.2.3.4":         1,
+		"::ffff:1.2.3.4":  nil,
+		"::FFFF:a01:101":  2,
+		"::ffff:10.1.1.1": 2,
+		"10.1.1.1":        nil,
+	} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != expected {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, expected, inf)
+		}
+	}
+	if inf, _ := tr.FindAddr(netip.MustParseAddr("::ffff:10.1.1.1")); inf != 2 {
+		t.Errorf("Wrong value for mapped netip address, expected 2, got %v", inf)
+	}
+	if _, ipnet, _ := tr.FindCIDRNet("::ffff:10.1.1.1"); len(ipnet.IP) != net.IPv6len || ipnet.String() != "10.0.0.0/8" {
+		t.Errorf("Wrong network, expected IPv6 ::ffff:10.0.0.0/104, got %v", ipnet.String())
+	}
+}
+
 func TestWalkTree(t *testing.T) {
-	tr := NewTree(0)
+	tr := NewTree()
 	if tr == nil || tr.root == nil {
 		t.Error("Did not create tree properly")
 	}
@@ -711,7 +845,7 @@ func TestWalkTree(t *testing.T) {
 }
 
 func TestWalkTree4(t *testing.T) {
-	tr := NewTree(0)
+	tr := NewTree()
 	if tr == nil || tr.root == nil {
 		t.Error("Did not create tree properly")
 	}
@@ -743,7 +877,7 @@ func TestWalkTree4(t *testing.T) {
 }
 
 func TestWalkTree6(t *testing.T) {
-	tr := NewTree(0)
+	tr := NewTree()
 	if tr == nil || tr.root == nil {
 		t.Error("Did not create tree properly")
 	}
@@ -771,3 +905,639 @@ func TestWalkTree6(t *testing.T) {
 		}
 	}
 }
+
+func TestStatsFor(t *testing.T) {
+	tr := NewTree()
+	if tr == nil || tr.root == nil {
+		t.Error("Did not create tree properly")
+	}
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("10.1.2.0/24", 3)
+	tr.AddCIDR("11.0.0.0/8", 4)
+
+	nodes, values, mem, err := tr.StatsFor("10.1.0.0/16")
+	if err != nil {
+		t.Error(err)
+	}
+	if nodes != 9 || values != 2 {
+		t.Errorf("Wrong stats, expected 9 nodes and 2 values, got %d and %d", nodes, values)
+	}
+	if mem == 0 {
+		t.Error("Expected non zero memory attribution")
+	}
+
+	nodes, values, _, err = tr.StatsFor("10.0.0.0/8")
+	if err != nil {
+		t.Error(err)
+	}
+	if nodes != 17 || values != 3 {
+		t.Errorf("Wrong stats, expected 17 nodes and 3 values, got %d and %d", nodes, values)
+	}
+
+	nodes, values, mem, err = tr.StatsFor("12.0.0.0/8")
+	if err != nil {
+		t.Error(err)
+	}
+	if nodes != 0 || values != 0 || mem != 0 {
+		t.Errorf("Wrong stats for missing subtree, got %d, %d, %d", nodes, values, mem)
+	}
+
+	if _, _, _, err = tr.StatsFor("10.1.0.0/1x"); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Expected ErrBadIP, got %v", err)
+	}
+}
+
+func TestWalkTreeCollectErrors(t *testing.T) {
+	tr := NewTree()
+	if tr == nil || tr.root == nil {
+		t.Error("Did not create tree properly")
+	}
+	cidrs := []string{
+		"1.2.3.0/24",
+		"1.2.3.0/25",
+		"5.6.7.8/32",
+	}
+	for i, v := range cidrs {
+		tr.AddCIDR(v, i)
+	}
+	errBad := errors.New("bad record")
+	var visited int
+	err := tr.WalkTree(OptWalkIPv4|OptWalkCollectErrors, func(cidr net.IPNet, value interface{}) (bool, error) {
+		visited++
+		if value.(int) != 1 {
+			return true, errBad
+		}
+		return true, nil
+	})
+	if visited != len(cidrs) {
+		t.Errorf("Walk did not continue after error, visited %d of %d", visited, len(cidrs))
+	}
+	if !errors.Is(err, errBad) {
+		t.Fatalf("Expected collected errors, got %v", err)
+	}
+	var werr *WalkError
+	if !errors.As(err, &werr) || werr.CIDR.String() != "1.2.3.0/24" {
+		t.Errorf("Wrong first collected error: %v", werr)
+	}
+	if err.Error() != "1.2.3.0/24: bad record\n5.6.7.8/32: bad record" {
+		t.Errorf("Wrong collected errors: %q", err.Error())
+	}
+
+	// without the option walk stops on first error
+	visited = 0
+	err = tr.WalkTree(OptWalkIPv4, func(cidr net.IPNet, value interface{}) (bool, error) {
+		visited++
+		return true, errBad
+	})
+	if err != errBad || visited != 1 {
+		t.Errorf("Expected walk to stop on first error, got %v after %d nodes", err, visited)
+	}
+}
+
+func TestWalkTreeOrder(t *testing.T) {
+	tr := NewTree()
+	for _, v := range []string{"10.0.0.0/8", "10.128.0.0/9", "10.0.0.0/9", "10.0.0.0/10"} {
+		tr.AddCIDR(v, v)
+	}
+	for _, tc := range []struct {
+		opt      OptWalk
+		skip     string
+		expected string
+	}{
+		{OptWalkPreOrder, "", "10.0.0.0/8,10.0.0.0/9,10.0.0.0/10,10.128.0.0/9"},
+		{OptWalkInOrder, "", "10.0.0.0/10,10.0.0.0/9,10.0.0.0/8,10.128.0.0/9"},
+		{OptWalkPostOrder, "", "10.0.0.0/10,10.0.0.0/9,10.128.0.0/9,10.0.0.0/8"},
+		{OptWalkPreOrder, "10.0.0.0/9", "10.0.0.0/8,10.0.0.0/9,10.128.0.0/9"},
+		{OptWalkInOrder, "10.0.0.0/8", "10.0.0.0/10,10.0.0.0/9,10.0.0.0/8"},
+		{OptWalkPostOrder, "10.0.0.0/9", "10.0.0.0/10,10.0.0.0/9,10.128.0.0/9,10.0.0.0/8"},
+	} {
+		var results []string
+		tr.WalkTree(OptWalkIPv4|tc.opt, func(cidr net.IPNet, value interface{}) (bool, error) {
+			results = append(results, cidr.String())
+			return cidr.String() != tc.skip, nil
+		})
+		if strings.Join(results, ",") != tc.expected {
+			t.Errorf("Wrong walk order %x skipping %q, expected %s, got %v", tc.opt, tc.skip, tc.expected, results)
+		}
+	}
+
+	// single branch through all 128 levels
+	tr = NewTree()
+	for bits := 0; bits <= 128; bits++ {
+		tr.AddCIDR(fmt.Sprintf("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff/%d", bits), bits)
+	}
+	var visited int
+	tr.WalkTree(OptWalkIPv6|OptWalkPostOrder, func(cidr net.IPNet, value interface{}) (bool, error) {
+		if value.(int) != 128-visited {
+			t.Fatalf("Wrong post-order value, expected %d, got %v", 128-visited, value)
+		}
+		visited++
+		return true, nil
+	})
+	if visited != 129 {
+		t.Errorf("Wrong visited count, expected 129, got %d", visited)
+	}
+}
+
+func TestFindCIDRNet(t *testing.T) {
+	tr := NewTree()
+	if tr == nil || tr.root == nil {
+		t.Error("Did not create tree properly")
+	}
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("dead::/16", 3)
+
+	inf, ipnet, err := tr.FindCIDRNet("10.1.2.3")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 2 || ipnet.String() != "10.1.0.0/16" {
+		t.Errorf("Wrong match, expected 2 in 10.1.0.0/16, got %v in %s", inf, ipnet.String())
+	}
+	inf, ipnet, _ = tr.FindCIDRNet("10.2.0.0/24")
+	if inf.(int) != 1 || ipnet.String() != "10.0.0.0/8" {
+		t.Errorf("Wrong match, expected 1 in 10.0.0.0/8, got %v in %s", inf, ipnet.String())
+	}
+	inf, ipnet, _ = tr.FindCIDRNet("dead:beef::1")
+	if inf.(int) != 3 || ipnet.String() != "dead::/16" {
+		t.Errorf("Wrong match, expected 3 in dead::/16, got %v in %s", inf, ipnet.String())
+	}
+	inf, ipnet, err = tr.FindCIDRNet("11.0.0.1")
+	if err != nil || inf != nil || ipnet.IP != nil {
+		t.Errorf("Expected no match, got %v in %s, %v", inf, ipnet.String(), err)
+	}
+}
+
+func TestFindCIDRResult(t *testing.T) {
+	for _, tr := range []*Tree{NewTree(), NewTree(WithIPv4Mapped())} {
+		tr.AddCIDR("10.0.0.0/8", 1)
+		tr.AddCIDR("10.1.0.0/16", 2)
+		tr.AddCIDR("2001:db8::/48", 3)
+
+		for cidr, expected := range map[string]FindResult{
+			"10.1.2.3":        {Value: 2, Bits: 16},
+			"10.1.0.0/16":     {Value: 2, Bits: 16, Exact: true},
+			"10.2.0.0/24":     {Value: 1, Bits: 8},
+			"2001:db8::1":     {Value: 3, Bits: 48},
+			"2001:db8::/48":   {Value: 3, Bits: 48, Exact: true},
+			"11.0.0.1":        {},
+			"2001:db9::1/128": {},
+		} {
+			r, err := tr.FindCIDRResult(cidr)
+			if err != nil {
+				t.Error(err)
+			}
+			if r != expected {
+				t.Errorf("Wrong result for %s, expected %+v, got %+v", cidr, expected, r)
+			}
+		}
+		tr.SetDefaultRoute("default")
+		if r, _ := tr.FindCIDRResult("11.0.0.1"); r != (FindResult{Value: "default"}) {
+			t.Errorf("Wrong result, expected default route, got %+v", r)
+		}
+		if _, err := tr.FindCIDRResult("10.1.2.x"); err == nil {
+			t.Errorf("Should have gotten error for bad cidr")
+		}
+	}
+}
+
+func TestContainsCIDR(t *testing.T) {
+	tr := NewTree()
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("192.168.1.4/31", 2)
+	tr.AddCIDR("192.168.1.9/32", 3)
+	tr.SetDefaultRoute(4)
+
+	for cidr, expected := range map[string][2]bool{
+		"10.1.2.3":       {true, false},
+		"10.0.0.0/8":     {true, true},
+		"10.0.0.0/7":     {false, false},
+		"192.168.1.5":    {true, false},
+		"192.168.1.4/31": {true, true},
+		"192.168.1.9":    {true, true},
+		"192.168.1.8":    {false, false},
+		"bad":            {false, false},
+	} {
+		contained, exact := tr.ContainsCIDR(cidr)
+		if contained != expected[0] || exact != expected[1] {
+			t.Errorf("Wrong result for %s, expected %v, got [%v %v]", cidr, expected, contained, exact)
+		}
+	}
+}
+
+func TestMatchFirst(t *testing.T) {
+	tr := NewTree()
+	tr.AddCIDR("0.0.0.0/0", "deny")
+	tr.AddCIDR("10.0.0.0/8", "permit")
+	tr.AddCIDR("10.1.0.0/16", "deny")
+	tr.AddCIDR("10.1.2.0/24", "log")
+	permit := func(val interface{}) bool { return val != "deny" }
+
+	inf, ipnet, err := tr.MatchFirst("10.1.2.3", permit)
+	if err != nil {
+		t.Error(err)
+	}
+	if inf != "log" || ipnet.String() != "10.1.2.0/24" {
+		t.Errorf("Wrong match, expected log in 10.1.2.0/24, got %v in %s", inf, ipnet.String())
+	}
+	inf, ipnet, _ = tr.MatchFirst("10.1.3.3", permit)
+	if inf != "permit" || ipnet.String() != "10.0.0.0/8" {
+		t.Errorf("Wrong match, expected permit in 10.0.0.0/8, got %v in %s", inf, ipnet.String())
+	}
+	inf, ipnet, _ = tr.MatchFirst("10.1.2.3", permit, func(val interface{}) bool { return val != "log" })
+	if inf != "permit" || ipnet.String() != "10.0.0.0/8" {
+		t.Errorf("Wrong match, expected permit in 10.0.0.0/8, got %v in %s", inf, ipnet.String())
+	}
+	inf, ipnet, _ = tr.MatchFirst("10.1.2.3")
+	if inf != "log" {
+		t.Errorf("Wrong match, expected log, got %v in %s", inf, ipnet.String())
+	}
+	inf, ipnet, err = tr.MatchFirst("11.0.0.1", permit)
+	if err != nil || inf != nil || ipnet.IP != nil {
+		t.Errorf("Expected no match, got %v in %s, %v", inf, ipnet.String(), err)
+	}
+	if _, _, err = tr.MatchFirst("bad", permit); err == nil {
+		t.Error("Expected error for bad address")
+	}
+}
+
+func TestConcurrentReaders(t *testing.T) {
+	tr := NewTree(WithLocking(true))
+	tr.AddCIDR("10.0.0.0/8", 1)
+
+	// walk holds read lock while lookups run from other goroutine
+	err := tr.WalkTree(OptWalkIPv4, func(cidr net.IPNet, value interface{}) (bool, error) {
+		done := make(chan interface{})
+		go func() {
+			inf, _ := tr.FindCIDR("10.1.1.1")
+			done <- inf
+		}()
+		if inf := <-done; inf.(int) != 1 {
+			t.Errorf("Wrong value, expected 1, got %v", inf)
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFindAllCIDRNets(t *testing.T) {
+	tr := NewTree()
+	if tr == nil || tr.root == nil {
+		t.Error("Did not create tree properly")
+	}
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("10.1.2.0/24", 3)
+	tr.AddCIDR("10.1.3.0/24", 4)
+
+	found, err := tr.FindAllCIDRNets("10.1.2.3")
+	if err != nil {
+		t.Error(err)
+	}
+	expected := []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24"}
+	if len(found) != len(expected) {
+		t.Fatalf("Wrong number of matches, expected %d, got %d", len(expected), len(found))
+	}
+	for i, nv := range found {
+		if nv.Net.String() != expected[i] || nv.Value.(int) != i+1 {
+			t.Errorf("Wrong match at %d, expected %d in %s, got %v in %s", i, i+1, expected[i], nv.Value, nv.Net.String())
+		}
+	}
+	if found, _ = tr.FindAllCIDRNets("11.0.0.1"); len(found) != 0 {
+		t.Errorf("Expected no matches, got %v", found)
+	}
+}
+
+func TestFindBinary(t *testing.T) {
+	tr := NewTree()
+	if tr == nil || tr.root == nil {
+		t.Error("Did not create tree properly")
+	}
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("dead:beef::/32", 3)
+
+	if inf := tr.Find32(0x0a010203); inf.(int) != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+	if inf := tr.Find32(0x0a020203); inf.(int) != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+	if inf := tr.Find32(0x0b000001); inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+	if inf := tr.Find128([16]byte{0xde, 0xad, 0xbe, 0xef, 15: 1}); inf.(int) != 3 {
+		t.Errorf("Wrong value, expected 3, got %v", inf)
+	}
+	if inf := tr.Find128([16]byte{0xde, 0xad, 15: 1}); inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+}
+
+func TestWalkSubtree(t *testing.T) {
+	tr := NewTree()
+	if tr == nil || tr.root == nil {
+		t.Error("Did not create tree properly")
+	}
+	for i, v := range []string{"9.0.0.0/8", "10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24", "10.2.0.0/16", "11.0.0.0/8", "dead::/16", "dead:beef::/32"} {
+		tr.AddCIDR(v, i)
+	}
+	var results []string
+	collect := func(cidr net.IPNet, value interface{}) (bool, error) {
+		results = append(results, cidr.String())
+		return true, nil
+	}
+	if err := tr.WalkSubtree("10.1.0.0/16", collect); err != nil {
+		t.Error(err)
+	}
+	if len(results) != 2 || results[0] != "10.1.0.0/16" || results[1] != "10.1.2.0/24" {
+		t.Errorf("Wrong subtree walk: %v", results)
+	}
+
+	results = nil
+	tr.WalkSubtree("10.0.0.0/7", collect)
+	if len(results) != 5 {
+		t.Errorf("Wrong subtree walk: %v", results)
+	}
+
+	results = nil
+	tr.WalkSubtree("dead::/16", collect)
+	if len(results) != 2 || results[1] != "dead:beef::/32" {
+		t.Errorf("Wrong subtree walk: %v", results)
+	}
+
+	results = nil
+	if err := tr.WalkSubtree("12.0.0.0/8", collect); err != nil || len(results) != 0 {
+		t.Errorf("Expected empty walk, got %v %v", results, err)
+	}
+	if err := tr.WalkSubtree("12.0.0.x/8", collect); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Expected ErrBadIP, got %v", err)
+	}
+}
+
+func TestSupernets(t *testing.T) {
+	tr := NewTree()
+	if tr == nil || tr.root == nil {
+		t.Error("Did not create tree properly")
+	}
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("10.1.2.0/24", 3)
+	tr.AddCIDR("2620:10f::/32", 4)
+	tr.AddCIDR("2620:10f:d000::/48", 5)
+
+	found, err := tr.Supernets("10.1.2.0/24")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(found) != 2 || found[0].Net.String() != "10.0.0.0/8" || found[1].Net.String() != "10.1.0.0/16" {
+		t.Errorf("Wrong supernets: %v", found)
+	}
+	found, _ = tr.Supernets("2620:10f:d000:100::/64")
+	if len(found) != 2 || found[0].Net.String() != "2620:10f::/32" || found[1].Value.(int) != 5 {
+		t.Errorf("Wrong supernets: %v", found)
+	}
+	if found, _ = tr.Supernets("10.0.0.0/8"); len(found) != 0 {
+		t.Errorf("Expected no supernets, got %v", found)
+	}
+}
+
+func TestSubnets(t *testing.T) {
+	tr := NewTree()
+	if tr == nil || tr.root == nil {
+		t.Error("Did not create tree properly")
+	}
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("192.168.0.0/16", 2)
+	tr.AddCIDR("192.168.1.0/24", 3)
+	tr.AddCIDR("192.168.2.128/25", 4)
+
+	found, err := tr.Subnets("192.168.0.0/16")
+	if err != nil {
+		t.Error(err)
+	}
+	expected := []string{"192.168.0.0/16", "192.168.1.0/24", "192.168.2.128/25"}
+	if len(found) != len(expected) {
+		t.Fatalf("Wrong subnets: %v", found)
+	}
+	for i, nv := range found {
+		if nv.Net.String() != expected[i] || nv.Value.(int) != i+2 {
+			t.Errorf("Wrong subnet at %d, expected %d in %s, got %v in %s", i, i+2, expected[i], nv.Value, nv.Net.String())
+		}
+	}
+	if found, _ = tr.Subnets("192.168.2.0/24"); len(found) != 1 || found[0].Value.(int) != 4 {
+		t.Errorf("Wrong subnets: %v", found)
+	}
+	if found, _ = tr.Subnets("172.16.0.0/12"); len(found) != 0 {
+		t.Errorf("Expected no subnets, got %v", found)
+	}
+}
+
+func TestDeleteCIDRIf(t *testing.T) {
+	tr := NewTree()
+	if tr == nil || tr.root == nil {
+		t.Error("Did not create tree properly")
+	}
+	tr.AddCIDR("10.0.0.0/8", "owner-a")
+	ownedBy := func(owner string) func(val interface{}) bool {
+		return func(val interface{}) bool { return val == owner }
+	}
+
+	deleted, err := tr.DeleteCIDRIf("10.0.0.0/8", ownedBy("owner-b"))
+	if err != nil || deleted {
+		t.Errorf("Expected value to be kept, got %v %v", deleted, err)
+	}
+	if inf, _ := tr.FindCIDR("10.1.1.1"); inf != "owner-a" {
+		t.Errorf("Wrong value, expected owner-a, got %v", inf)
+	}
+	deleted, err = tr.DeleteCIDRIf("10.0.0.0/8", ownedBy("owner-a"))
+	if err != nil || !deleted {
+		t.Errorf("Expected value to be removed, got %v %v", deleted, err)
+	}
+	if inf, _ := tr.FindCIDR("10.1.1.1"); inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+	if _, err = tr.DeleteCIDRIf("10.0.0.0/8", ownedBy("owner-a")); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestUpdateCIDR(t *testing.T) {
+	tr := NewTree()
+	if tr == nil || tr.root == nil {
+		t.Error("Did not create tree properly")
+	}
+	inc := func(old interface{}, found bool) (interface{}, bool) {
+		if !found {
+			return 1, false
+		}
+		return old.(int) + 1, false
+	}
+	for i := 0; i < 3; i++ {
+		if err := tr.UpdateCIDR("10.0.0.0/8", inc); err != nil {
+			t.Error(err)
+		}
+	}
+	if inf, _ := tr.FindExactCIDR("10.0.0.0/8"); inf.(int) != 3 {
+		t.Errorf("Wrong value, expected 3, got %v", inf)
+	}
+	if _, values, _, _ := tr.GetStats(); values != 1 {
+		t.Errorf("Wrong valued nodes, expected 1, got %d", values)
+	}
+
+	err := tr.UpdateCIDR("10.0.0.0/8", func(old interface{}, found bool) (interface{}, bool) {
+		return nil, true
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if inf, _ := tr.FindCIDR("10.1.1.1"); inf != nil {
+		t.Errorf("Wrong value after delete, expected nil, got %v", inf)
+	}
+	if nodes, values, _, _ := tr.GetStats(); nodes != 1 || values != 0 {
+		t.Errorf("Wrong stats after delete, got %d nodes and %d values", nodes, values)
+	}
+	// deleting missing value is a no-op
+	if err = tr.UpdateCIDR("10.0.0.0/8", func(old interface{}, found bool) (interface{}, bool) {
+		return nil, true
+	}); err != nil {
+		t.Error(err)
+	}
+
+	// set on existing node without value counts the value
+	tr.AddCIDR("10.1.0.0/16", 1)
+	tr.SetCIDR("10.0.0.0/8", 2)
+	if _, values, _, _ := tr.GetStats(); values != 2 {
+		t.Errorf("Wrong valued nodes, expected 2, got %d", values)
+	}
+}
+
+func TestWalkTreeOrdered(t *testing.T) {
+	tr := NewTree()
+	for i := 0; i < 500; i++ {
+		bits := 16 + (i*7)%48
+		ip := net.IP{0x20, 0x01, byte(i * 37), byte(i * 11), byte(i), byte(i * 5), 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+		tr.SetCIDR((&net.IPNet{IP: ip.Mask(net.CIDRMask(bits, 128)), Mask: net.CIDRMask(bits, 128)}).String(), i)
+	}
+	for _, mostSpecificFirst := range []bool{false, true} {
+		var walked []netip.Prefix
+		tr.WalkTreeOrdered(OptWalkIPv6|OptWalkInOrder, mostSpecificFirst, func(cidr net.IPNet, value interface{}) (bool, error) {
+			walked = append(walked, netip.MustParsePrefix(cidr.String()))
+			return true, nil
+		})
+		if len(walked) != tr.countValuedNodes {
+			t.Errorf("Wrong number of walked IP/masks, expected %d, got %d", tr.countValuedNodes, len(walked))
+		}
+		for i := 1; i < len(walked); i++ {
+			a, b := walked[i-1], walked[i]
+			var ok bool
+			switch {
+			case a.Overlaps(b) && a.Bits() != b.Bits():
+				ok = (a.Bits() < b.Bits()) != mostSpecificFirst
+			default:
+				ok = a.Addr().Less(b.Addr())
+			}
+			if !ok {
+				t.Errorf("Wrong order (most specific first %v): %v before %v", mostSpecificFirst, a, b)
+			}
+		}
+	}
+}
+
+func TestWalkTreeFiltered(t *testing.T) {
+	for _, tr := range []*Tree{NewTree(), NewTree(WithIPv4Mapped())} {
+		tr.AddCIDR("10.0.0.0/8", "a")
+		tr.AddCIDR("10.1.0.0/16", "b")
+		tr.AddCIDR("10.1.2.0/24", "a")
+		tr.AddCIDR("192.168.1.0/24", "c")
+		tr.AddCIDR("2001:db8::/48", "a")
+
+		var walked []string
+		err := tr.WalkTreeFiltered(OptWalkIPAuto, func(value interface{}) bool { return value == "a" }, func(cidr net.IPNet, value interface{}) (bool, error) {
+			walked = append(walked, cidr.String())
+			return true, nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		if strings.Join(walked, " ") != "10.0.0.0/8 10.1.2.0/24 2001:db8::/48" {
+			t.Errorf("Wrong filtered walk, expected [10.0.0.0/8 10.1.2.0/24 2001:db8::/48], got %v", walked)
+		}
+
+		walked = nil
+		err = tr.WalkTreeLengths(OptWalkIPAuto, 16, 24, func(cidr net.IPNet, value interface{}) (bool, error) {
+			walked = append(walked, cidr.String())
+			return true, nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		if strings.Join(walked, " ") != "10.1.0.0/16 10.1.2.0/24 192.168.1.0/24" {
+			t.Errorf("Wrong walk of /16-/24, expected [10.1.0.0/16 10.1.2.0/24 192.168.1.0/24], got %v", walked)
+		}
+	}
+}
+
+func TestSetCIDRReport(t *testing.T) {
+	tr := NewTree()
+	updated, prev, err := tr.SetCIDRReport("10.0.0.0/8", 1)
+	if err != nil || updated || prev != nil {
+		t.Errorf("Wrong report of insert, expected false, <nil>, <nil>, got %v, %v, %v", updated, prev, err)
+	}
+	updated, prev, err = tr.SetCIDRReport("10.0.0.0/8", 2)
+	if err != nil || !updated || prev != 1 {
+		t.Errorf("Wrong report of update, expected true, 1, <nil>, got %v, %v, %v", updated, prev, err)
+	}
+	if inf, _ := tr.FindCIDR("10.1.1.1"); inf != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+	if _, _, err = tr.SetCIDRReport("10.0.0.x/8", 3); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Wrong error, expected ErrBadIP, got %v", err)
+	}
+}
+
+func TestAddCIDRGet(t *testing.T) {
+	tr := NewTree()
+	if existing, err := tr.AddCIDRGet("10.0.0.0/8", 1); err != nil || existing != nil {
+		t.Errorf("Wrong result of add, expected <nil>, <nil>, got %v, %v", existing, err)
+	}
+	if existing, err := tr.AddCIDRGet("10.0.0.0/8", 2); err != ErrNodeBusy || existing != 1 {
+		t.Errorf("Wrong result of conflict, expected 1, %v, got %v, %v", ErrNodeBusy, existing, err)
+	}
+	if existing, err := tr.AddCIDRGet("2001:db8::/32", 3); err != nil || existing != nil {
+		t.Errorf("Wrong result of add, expected <nil>, <nil>, got %v, %v", existing, err)
+	}
+}
+
+func TestAddCIDRMerge(t *testing.T) {
+	tr := NewTree()
+	appendTags := func(old, new interface{}) interface{} {
+		return append(old.([]string), new.([]string)...)
+	}
+	for _, tag := range []string{"botnet", "spam", "scanner"} {
+		if err := tr.AddCIDRMerge("198.51.100.0/24", []string{tag}, appendTags); err != nil {
+			t.Error(err)
+		}
+	}
+	inf, err := tr.FindExactCIDR("198.51.100.0/24")
+	if err != nil {
+		t.Error(err)
+	}
+	if tags, ok := inf.([]string); !ok || strings.Join(tags, ",") != "botnet,spam,scanner" {
+		t.Errorf("Wrong value, expected botnet,spam,scanner, got %v", inf)
+	}
+	err = tr.AddCIDRMerge("198.51.100.0/24", []string{"ok"}, func(old, new interface{}) interface{} { return nil })
+	if err != nil {
+		t.Error(err)
+	}
+	if _, err = tr.FindExactCIDR("198.51.100.0/24"); err != ErrNotFound {
+		t.Errorf("Wrong error, expected %v, got %v", ErrNotFound, err)
+	}
+	if err = tr.AddCIDRMerge("bad", 1, appendTags); err == nil {
+		t.Error("Expected error for bad CIDR")
+	}
+}
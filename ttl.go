@@ -0,0 +1,141 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"time"
+)
+
+// WithOnExpire sets function called for every expired value removed from the tree by Sweep.
+// It is called after the tree is unlocked, so it may use the tree.
+func WithOnExpire(fn func(cidr net.IPNet, value interface{})) Option {
+	return func(tree *Tree) {
+		tree.onExpire = fn
+	}
+}
+
+// AddCIDRWithTTL is AddCIDR for value which expires after ttl. Expired values are not returned by lookups and
+// walks, they are removed from the tree by Sweep (see also StartSweeper).
+func (tree *Tree) AddCIDRWithTTL(cidr string, val interface{}, ttl time.Duration) error {
+	if zt, addr, zoned := tree.zoneTree(cidr, true); zoned {
+		return zt.AddCIDRWithTTL(addr, val, ttl)
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	tree.pendingExpiry = tree.now().Add(ttl).UnixNano()
+	defer func() { tree.pendingExpiry = 0 }()
+	return tree.addCIDRb([]byte(cidr), val)
+}
+
+// SetCIDRWithTTL is SetCIDR for value which expires after ttl, see AddCIDRWithTTL.
+func (tree *Tree) SetCIDRWithTTL(cidr string, val interface{}, ttl time.Duration) error {
+	if zt, addr, zoned := tree.zoneTree(cidr, true); zoned {
+		return zt.SetCIDRWithTTL(addr, val, ttl)
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	tree.pendingExpiry = tree.now().Add(ttl).UnixNano()
+	defer func() { tree.pendingExpiry = 0 }()
+	return tree.setCIDRb([]byte(cidr), val)
+}
+
+func (tree *Tree) now() time.Time {
+	if tree.clock != nil {
+		return tree.clock()
+	}
+	return time.Now()
+}
+
+// expiryNow returns current time for expiry checks, zero if the tree has no expiring values.
+func (tree *Tree) expiryNow() int64 {
+	if !tree.hasExpiry {
+		return 0
+	}
+	return tree.now().UnixNano()
+}
+
+// expired tells whether value of the node expired at now (from expiryNow).
+func expired(n *node, now int64) bool {
+	return now != 0 && n.meta != nil && n.meta.expires != 0 && n.meta.expires <= now
+}
+
+// setExpiry sets expiry of the node which just got its value to the pending one (from the TTL call in progress).
+func (tree *Tree) setExpiry(n *node) {
+	if n.value == nil {
+		n.meta = nil
+		return
+	}
+	if tree.pendingExpiry == 0 {
+		n.meta.expires = 0
+		return
+	}
+	if n.meta == nil {
+		n.meta = new(nodeMeta)
+	}
+	n.meta.expires = tree.pendingExpiry
+	tree.hasExpiry = true
+}
+
+// Sweep removes all expired values from the tree and returns how many were removed.
+func (tree *Tree) Sweep() int {
+	removed := tree.sweep()
+	if tree.onExpire != nil {
+		for _, nv := range removed {
+			tree.onExpire(nv.Net, nv.Value)
+		}
+	}
+	return len(removed)
+}
+
+func (tree *Tree) sweep() []NetValue {
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	if !tree.hasExpiry {
+		return nil
+	}
+	now := tree.now().UnixNano()
+	var removed []NetValue
+	remaining := false
+	tree.walkNodes(OptWalkIPAuto|optWalkExpired, func(cidr net.IPNet, n *node) (bool, error) {
+		if expired(n, now) {
+			removed = append(removed, NetValue{Net: cidr, Value: n.value})
+		} else if n.meta != nil && n.meta.expires != 0 {
+			remaining = true
+		}
+		return true, nil
+	})
+	for _, nv := range removed {
+		if e, err := net2entry(nv.Net); err == nil {
+			tree.deleteEntry(&e, false)
+		}
+	}
+	tree.hasExpiry = remaining
+	return removed
+}
+
+// StartSweeper starts goroutine calling Sweep every interval, until the returned stop function is called.
+func (tree *Tree) StartSweeper(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				tree.Sweep()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTTL(t *testing.T) {
+	var expiredNets []string
+	tr := NewTree(WithOnExpire(func(cidr net.IPNet, value interface{}) {
+		expiredNets = append(expiredNets, cidr.String())
+	}))
+	clock := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr.clock = func() time.Time { return clock }
+
+	if err := tr.AddCIDR("10.0.0.0/8", 1); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDRWithTTL("10.1.0.0/16", 2, time.Minute); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDRWithTTL("2001:db8::/48", 3, time.Hour); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDRWithTTL("10.1.0.0/16", 4, time.Minute); err != ErrNodeBusy {
+		t.Errorf("Expected ErrNodeBusy, got %v", err)
+	}
+	inf, err := tr.FindCIDR("10.1.2.3")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+
+	clock = clock.Add(2 * time.Minute)
+	inf, err = tr.FindCIDR("10.1.2.3")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 1 {
+		t.Errorf("Wrong value, expected 1 after expiry, got %v", inf)
+	}
+	var walked int
+	tr.WalkTree(OptWalkIPAuto, func(cidr net.IPNet, value interface{}) (bool, error) {
+		walked++
+		return true, nil
+	})
+	if walked != 2 {
+		t.Errorf("Wrong walked count, expected 2, got %v", walked)
+	}
+
+	if n := tr.Sweep(); n != 1 {
+		t.Errorf("Wrong swept count, expected 1, got %v", n)
+	}
+	if len(expiredNets) != 1 || expiredNets[0] != "10.1.0.0/16" {
+		t.Errorf("Wrong expired networks, got %v", expiredNets)
+	}
+	if inf, err = tr.FindExactCIDR("10.1.0.0/16"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound after sweep, got %v %v", inf, err)
+	}
+	if _, valued, _, _ := tr.GetStats(); valued != 2 {
+		t.Errorf("Wrong valued count, expected 2, got %v", valued)
+	}
+}
+
+func TestTTLReplace(t *testing.T) {
+	tr := NewTree()
+	clock := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr.clock = func() time.Time { return clock }
+
+	// expired value does not block AddCIDR
+	tr.AddCIDRWithTTL("192.168.0.0/24", 1, time.Second)
+	clock = clock.Add(time.Minute)
+	if err := tr.AddCIDR("192.168.0.0/24", 2); err != nil {
+		t.Error(err)
+	}
+	if _, valued, _, _ := tr.GetStats(); valued != 1 {
+		t.Errorf("Wrong valued count, expected 1, got %v", valued)
+	}
+
+	// SetCIDR without TTL makes the value permanent
+	tr.SetCIDRWithTTL("192.168.1.0/24", 3, time.Second)
+	tr.SetCIDR("192.168.1.0/24", 4)
+	clock = clock.Add(time.Minute)
+	inf, err := tr.FindCIDR("192.168.1.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 4 {
+		t.Errorf("Wrong value, expected 4, got %v", inf)
+	}
+	if n := tr.Sweep(); n != 0 {
+		t.Errorf("Wrong swept count, expected 0, got %v", n)
+	}
+}
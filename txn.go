@@ -0,0 +1,68 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+// Txn buffers changes of the tree made in Apply.
+type Txn struct {
+	ops []txnOp
+}
+
+type txnOp struct {
+	cidr  []byte
+	value interface{}
+	apply func(tree *Tree, cidr []byte, value interface{}) error
+}
+
+// AddCIDR buffers AddCIDR of the tree.
+func (tx *Txn) AddCIDR(cidr string, val interface{}) {
+	tx.ops = append(tx.ops, txnOp{cidr: []byte(cidr), value: val, apply: (*Tree).addCIDRb})
+}
+
+// SetCIDR buffers SetCIDR of the tree.
+func (tx *Txn) SetCIDR(cidr string, val interface{}) {
+	tx.ops = append(tx.ops, txnOp{cidr: []byte(cidr), value: val, apply: (*Tree).setCIDRb})
+}
+
+// DeleteCIDR buffers DeleteCIDR of the tree.
+func (tx *Txn) DeleteCIDR(cidr string) {
+	tx.ops = append(tx.ops, txnOp{cidr: []byte(cidr), apply: func(tree *Tree, cidr []byte, value interface{}) error {
+		return tree.deleteCIDRb(cidr)
+	}})
+}
+
+// Apply calls fn to buffer changes in the Txn and commits them in order under one lock, so readers see either
+// none or all of them. If fn returns error nothing is changed, if a change fails the changes applied before it
+// are rolled back (restored values lose their Metadata and expiration) and its error is returned.
+func (tree *Tree) Apply(fn func(tx *Txn) error) error {
+	tx := new(Txn)
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if tree.safe {
+		tree.Lock()
+		defer tree.Unlock()
+	}
+	undo := make([]txnOp, 0, len(tx.ops))
+	for _, op := range tx.ops {
+		prev, err := tree.findExactCIDRb(op.cidr)
+		if err == nil || err == ErrNotFound {
+			err = op.apply(tree, op.cidr, op.value)
+		}
+		if err != nil {
+			for i := len(undo) - 1; i >= 0; i-- {
+				undo[i].apply(tree, undo[i].cidr, undo[i].value)
+			}
+			return err
+		}
+		if prev != nil {
+			undo = append(undo, txnOp{cidr: op.cidr, value: prev, apply: (*Tree).setCIDRb})
+		} else {
+			undo = append(undo, txnOp{cidr: op.cidr, apply: func(tree *Tree, cidr []byte, value interface{}) error {
+				return tree.deleteCIDRb(cidr)
+			}})
+		}
+	}
+	return nil
+}
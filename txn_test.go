@@ -0,0 +1,60 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tr := NewTree()
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("192.168.0.0/16", 2)
+
+	err := tr.Apply(func(tx *Txn) error {
+		tx.SetCIDR("10.0.0.0/8", 10)
+		tx.AddCIDR("10.1.0.0/16", 11)
+		tx.DeleteCIDR("192.168.0.0/16")
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	for cidr, expected := range map[string]interface{}{"10.2.2.2": 10, "10.1.1.1": 11, "192.168.1.1": nil} {
+		if inf, _ := tr.FindCIDR(cidr); inf != expected {
+			t.Errorf("Wrong value for %s, expected %v, got %v", cidr, expected, inf)
+		}
+	}
+
+	// failing change rolls back the changes before it
+	err = tr.Apply(func(tx *Txn) error {
+		tx.SetCIDR("10.0.0.0/8", 20)
+		tx.DeleteCIDR("10.1.0.0/16")
+		tx.AddCIDR("172.16.0.0/12", 21)
+		tx.AddCIDR("10.0.0.0/8", 22)
+		return nil
+	})
+	if err != ErrNodeBusy {
+		t.Errorf("Wrong error, expected %v, got %v", ErrNodeBusy, err)
+	}
+	for cidr, expected := range map[string]interface{}{"10.2.2.2": 10, "10.1.1.1": 11, "172.16.1.1": nil} {
+		if inf, _ := tr.FindCIDR(cidr); inf != expected {
+			t.Errorf("Wrong value after rollback for %s, expected %v, got %v", cidr, expected, inf)
+		}
+	}
+
+	stop := errors.New("stop")
+	err = tr.Apply(func(tx *Txn) error {
+		tx.DeleteCIDR("10.0.0.0/8")
+		return stop
+	})
+	if err != stop {
+		t.Errorf("Wrong error, expected %v, got %v", stop, err)
+	}
+	if inf, _ := tr.FindCIDR("10.2.2.2"); inf != 10 {
+		t.Errorf("Wrong value, expected 10, got %v", inf)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrCorrupt is returned (wrapped with the details) by Validate for inconsistent tree.
+var ErrCorrupt = errors.New("Tree is corrupted")
+
+// Validate checks the internal consistency of the tree: parent links of all nodes, node and value counters
+// against a traversal, empty leaves (deletes and nil values leave none), integrity of the free node list (cycles,
+// nodes both free and in use) and of the LRU list. It returns nil for a consistent tree and error wrapping ErrCorrupt
+// describing the first inconsistency otherwise, e.g. as a health check after bulk mutations. Trees of zones are
+// checked too.
+func (tree *Tree) Validate() error {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	err := tree.validate()
+	tree.eachZone(func(zone string, zt *Tree) {
+		if err == nil {
+			if err = zt.Validate(); err != nil {
+				err = fmt.Errorf("zone %s: %w", zone, err)
+			}
+		}
+	})
+	return err
+}
+
+func (tree *Tree) validate() error {
+	if tree.root == nil {
+		return corrupt("no root node")
+	}
+	if tree.root.parent != nil {
+		return corrupt("root node has parent")
+	}
+	seen := make(map[*node]bool, tree.countNodes)
+	nodes, values := 0, 0
+	type item struct {
+		n     *node
+		depth int
+	}
+	stack := []item{{tree.root, 0}}
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		n := it.n
+		if seen[n] {
+			return corrupt("node reached twice, tree has cycle or shared subtree")
+		}
+		seen[n] = true
+		nodes++
+		if n.value != nil {
+			values++
+		} else if n.left == nil && n.right == nil && n != tree.root && tree.preallocate == 0 {
+			return corrupt("empty leaf at depth %d", it.depth)
+		}
+		for _, c := range []*node{n.right, n.left} {
+			if c == nil {
+				continue
+			}
+			if it.depth == net.IPv6len*8 {
+				return corrupt("node deeper than %d bits", net.IPv6len*8)
+			}
+			if c.parent != n {
+				return corrupt("node at depth %d has wrong parent", it.depth+1)
+			}
+			stack = append(stack, item{c, it.depth + 1})
+		}
+	}
+	if nodes != tree.countNodes {
+		return corrupt("%d nodes counted, %d reachable", tree.countNodes, nodes)
+	}
+	if values != tree.countValuedNodes {
+		return corrupt("%d values counted, %d reachable", tree.countValuedNodes, values)
+	}
+
+	if l := tree.lru; l != nil {
+		l.Lock()
+		defer l.Unlock()
+		for n := range l.elems {
+			if !seen[n] || n.value == nil {
+				return corrupt("LRU list holds node not in use or without value")
+			}
+		}
+	}
+
+	free := 0
+	for n := tree.free; n != nil; n = n.right {
+		if seen[n] {
+			return corrupt("free node is in use or free list has cycle")
+		}
+		seen[n] = true
+		free++
+	}
+	if free != tree.countFreeNodes {
+		return corrupt("%d free nodes counted, %d on free list", tree.countFreeNodes, free)
+	}
+	if tree.countNodes+tree.countFreeNodes > tree.countAllocNodes {
+		return corrupt("%d nodes in use and %d free, only %d allocated", tree.countNodes, tree.countFreeNodes, tree.countAllocNodes)
+	}
+	return nil
+}
+
+// Validate checks consistency of all shards (see Tree.Validate) and that every shard holds only IP/masks
+// of its part of the keyspace.
+func (s *ShardedTree) Validate() error {
+	for i, tree := range s.shards {
+		if err := tree.Validate(); err != nil {
+			return fmt.Errorf("shard %d: %w", i, err)
+		}
+		if err := s.validateShard(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateShard checks that nodes of shard i branch off the path of its leading bits only below the shard prefix.
+func (s *ShardedTree) validateShard(i int) error {
+	tree := s.shards[i]
+	tree.RLock()
+	defer tree.RUnlock()
+	n := tree.root
+	for depth := 0; depth < s.bits && n != nil; depth++ {
+		next, other := n.left, n.right
+		if i>>(s.bits-1-depth)&1 != 0 {
+			next, other = other, next
+		}
+		if other != nil {
+			return corrupt("shard %d holds IP/masks of other shards", i)
+		}
+		n = next
+	}
+	return nil
+}
+
+func corrupt(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{ErrCorrupt}, args...)...)
+}
@@ -0,0 +1,108 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tr := NewTree()
+	for _, cidr := range FillRandom(tr, 1000)[:500] {
+		if err := tr.DeleteCIDR(cidr); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := tr.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	tr.Compact()
+	if err := tr.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, corruption := range map[string]func(tr *Tree){
+		"parent": func(tr *Tree) { tr.root.left.left.parent = tr.root },
+		"nodes":  func(tr *Tree) { tr.countNodes++ },
+		"values": func(tr *Tree) { tr.countValuedNodes-- },
+		"cycle":  func(tr *Tree) { tr.root.left.left.left = tr.root.left },
+		"free":   func(tr *Tree) { n := tr.newnode(); n.right = n; tr.free = n; tr.countFreeNodes = 1 },
+		"in use": func(tr *Tree) { tr.root.left.right = tr.free; tr.free = tr.root.left },
+		"leaf":   func(tr *Tree) { n := tr.newnode(); n.parent = tr.root; tr.root.right = n; tr.countNodes++ },
+	} {
+		tr := NewTree()
+		tr.AddCIDR("10.0.0.0/8", 1)
+		tr.AddCIDR("10.1.0.0/16", 2)
+		tr.DeleteCIDR("10.1.0.0/16")
+		corruption(tr)
+		if err := tr.Validate(); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("Wrong error for %s, expected %v, got %v", name, ErrCorrupt, err)
+		}
+	}
+}
+
+func TestValidateAfterOperations(t *testing.T) {
+	evicting := NewTreeLRU(100)
+	FillRandom(evicting, 300)
+	aggregated := NewTree(WithOnlineAggregation(nil))
+	aggregated.AddCIDR("10.0.0.0/25", 1)
+	aggregated.AddCIDR("10.0.0.128/25", 1)
+	excluded := NewTree()
+	excluded.AddCIDR("10.0.0.0/8", 1)
+	excluded.ExcludeCIDR("10.0.0.0/8", "10.1.2.0/24")
+	ranged := NewTree()
+	ranged.AddRange("10.0.0.3", "10.0.1.200", 1)
+	ranged.DeleteWholeRangeCIDR("10.0.0.0/24")
+	cloned := excluded.Clone()
+	cloned.Merge(ranged, nil)
+	cleared := NewTree()
+	FillRandom(cleared, 100)
+	cleared.Clear()
+
+	for name, tr := range map[string]*Tree{
+		"evicting": evicting, "aggregated": aggregated, "excluded": excluded, "ranged": ranged, "cloned": cloned, "cleared": cleared,
+	} {
+		if err := tr.Validate(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	s := NewShardedTree(4)
+	s.AddCIDR("0.0.0.0/1", 1)
+	s.AddCIDR("200.0.0.0/8", 2)
+	if err := s.Validate(); err != nil {
+		t.Error(err)
+	}
+	s.shards[0].AddCIDR("200.0.0.0/8", 3)
+	if err := s.Validate(); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Wrong error, expected %v, got %v", ErrCorrupt, err)
+	}
+
+	evicting.lru.use(evicting.newnode())
+	if err := evicting.Validate(); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Wrong error, expected %v, got %v", ErrCorrupt, err)
+	}
+}
+
+func TestValidateNilValues(t *testing.T) {
+	tr := NewTree()
+	tr.AddCIDR("10.0.0.0/8", 1)
+	if err := tr.AddCIDR("10.1.0.0/16", nil); err != nil {
+		t.Error(err)
+	}
+	tr.AddCIDR("2001:db8::/48", 2)
+	tr.SetCIDR("2001:db8::/48", nil)
+	if err := tr.BulkAdd([]PrefixValue{{"192.168.0.0/16", nil}, {"192.168.1.0/24", 3}, {"172.16.0.0/12", nil}}); err != nil {
+		t.Error(err)
+	}
+	if err := tr.Validate(); err != nil {
+		t.Error(err)
+	}
+	nodes, values, _, _ := tr.GetStats()
+	if values != 2 || nodes != 1+8+24 {
+		t.Errorf("Wrong stats, expected 33 nodes and 2 values, got %d %d", nodes, values)
+	}
+}
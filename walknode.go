@@ -0,0 +1,68 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+)
+
+// WalkNode describes a node with value passed to WalkTreeNodeFunc.
+type WalkNode struct {
+	Net         net.IPNet
+	Value       interface{}
+	PrefixLen   int  // prefix length of Net
+	Depth       int  // depth of the node in the tree (differs from PrefixLen for IPv4 of WithIPv4Mapped tree)
+	HasChildren bool // the node has nodes below it (possibly without values)
+	Subnets     int  // number of values below the node (not counting its own)
+}
+
+// WalkTreeNodeFunc is the type of function for caller of WalkTreeNodes function, see WalkTreeFunc.
+type WalkTreeNodeFunc func(n WalkNode) (bool, error)
+
+// WalkTreeNodes is WalkTree passing description of each node with value, so hierarchy can be built without
+// looking up the tree again.
+func (tree *Tree) WalkTreeNodes(opt OptWalk, wtfunc WalkTreeNodeFunc) error {
+	if tree.safe {
+		tree.RLock()
+		defer tree.RUnlock()
+	}
+	if tree.guard != nil {
+		tree.guard.enterRead("WalkTreeNodes")
+		defer tree.guard.exitRead()
+	}
+	subnets := make(map[*node]int, tree.countValuedNodes)
+	countSubnets(tree.root, tree.expiryNow(), subnets)
+	return tree.walkNodes(opt, func(cidr net.IPNet, n *node) (bool, error) {
+		prefixLen, _ := cidr.Mask.Size()
+		return wtfunc(WalkNode{
+			Net:         cidr,
+			Value:       n.value,
+			PrefixLen:   prefixLen,
+			Depth:       nodeDepth(n),
+			HasChildren: n.left != nil || n.right != nil,
+			Subnets:     subnets[n],
+		})
+	})
+}
+
+// countSubnets returns number of values in the subtree of n, storing number of values below each node with value.
+func countSubnets(n *node, now int64, subnets map[*node]int) int {
+	if n == nil {
+		return 0
+	}
+	below := countSubnets(n.left, now, subnets) + countSubnets(n.right, now, subnets)
+	if n.value == nil || expired(n, now) {
+		return below
+	}
+	subnets[n] = below
+	return below + 1
+}
+
+func nodeDepth(n *node) (depth int) {
+	for ; n.parent != nil; n = n.parent {
+		depth++
+	}
+	return depth
+}
@@ -0,0 +1,49 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestWalkTreeNodes(t *testing.T) {
+	tr := NewTree()
+	for _, cidr := range []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.1.0/24", "10.2.0.0/16", "2001:db8::/48"} {
+		if err := tr.AddCIDR(cidr, cidr); err != nil {
+			t.Error(err)
+		}
+	}
+	var walked []string
+	err := tr.WalkTreeNodes(OptWalkIPAuto, func(n WalkNode) (bool, error) {
+		if n.Value != n.Net.String() {
+			t.Errorf("Wrong value of %v: %v", n.Net.String(), n.Value)
+		}
+		walked = append(walked, fmt.Sprintf("%v %d %d %v %d", n.Net.String(), n.PrefixLen, n.Depth, n.HasChildren, n.Subnets))
+		return true, nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	expected := []string{
+		"10.0.0.0/8 8 8 true 3",
+		"10.1.0.0/16 16 16 true 1",
+		"10.1.1.0/24 24 24 false 0",
+		"10.2.0.0/16 16 16 false 0",
+		"2001:db8::/48 48 48 false 0",
+	}
+	if fmt.Sprint(walked) != fmt.Sprint(expected) {
+		t.Errorf("Wrong walk, expected %v, got %v", expected, walked)
+	}
+
+	tr = NewTree(WithIPv4Mapped())
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.WalkTreeNodes(OptWalkIPAuto, func(n WalkNode) (bool, error) {
+		if n.PrefixLen != 8 || n.Depth != 104 {
+			t.Errorf("Wrong prefix length or depth, expected 8 and 104, got %d and %d", n.PrefixLen, n.Depth)
+		}
+		return true, nil
+	})
+}
@@ -0,0 +1,144 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// IPv6 addresses may carry zone (fe80::1%eth0, fe80::%eth0/64), by default the zone is ignored and the address is
+// taken as if it had none. WithZones keeps IP/masks with zone in per-zone trees instead, so the same link-local
+// space of different links holds different values: the Add, Set, Update and Delete operations on IP/mask with
+// zone change the tree of the zone, FindExactCIDR, FindAllCIDR, Supernets, Subnets and WalkSubtree look only there,
+// while longest match lookups (FindCIDR, FindCIDRNet, FindCIDRResult, ContainsCIDR, MatchFirst and the like) fall
+// back to IP/masks without zone when the tree of the zone has no match. Other operations taking cidr (Txn, LoadFrom
+// and the like included) return ErrZoneNotSupported for IP/mask with zone, zone given to IPv4 or malformed address
+// is ErrBadIP. Walks see only IP/masks without zone. Clear, Clone and Validate cover trees of zones, Marshal does
+// not write them. Trees of zones are returned by Zone.
+func WithZones() Option {
+	return func(tree *Tree) {
+		tree.zones = &zoneTrees{trees: make(map[string]*Tree)}
+	}
+}
+
+// ErrZoneNotSupported is returned by operations of WithZones tree which do not support IP/mask with zone.
+var ErrZoneNotSupported = errors.New("Zone not supported by the operation")
+
+// zoneTrees has own lock, trees of zones are created by writes holding no lock of the tree.
+type zoneTrees struct {
+	trees map[string]*Tree
+	sync.Mutex
+}
+
+// Zone returns the tree of IP/masks with the zone (created with the options of the tree), nil if there is none.
+func (tree *Tree) Zone(zone string) *Tree {
+	if tree.zones == nil {
+		return nil
+	}
+	tree.zones.Lock()
+	defer tree.zones.Unlock()
+	return tree.zones.trees[zone]
+}
+
+// Zones returns sorted names of zones having tree.
+func (tree *Tree) Zones() []string {
+	if tree.zones == nil {
+		return nil
+	}
+	tree.zones.Lock()
+	defer tree.zones.Unlock()
+	ret := make([]string, 0, len(tree.zones.trees))
+	for zone := range tree.zones.trees {
+		ret = append(ret, zone)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// zoneTree returns tree of the zone of the cidr (created if create is set, nil if there is none) and the cidr
+// without zone, zoned is false if the tree keeps no zones or the cidr is not a valid IPv6 IP/mask with zone.
+func (tree *Tree) zoneTree(cidr string, create bool) (zt *Tree, addr string, zoned bool) {
+	if tree.zones == nil {
+		return nil, cidr, false
+	}
+	addr, zone, ok := splitZone(cidr)
+	if !ok {
+		return nil, cidr, false
+	}
+	zs := tree.zones
+	zs.Lock()
+	defer zs.Unlock()
+	zt = zs.trees[zone]
+	if zt == nil && create {
+		zt = tree.emptyCopy(withoutZones)
+		zs.trees[zone] = zt
+	}
+	return zt, addr, true
+}
+
+// splitZone splits the cidr into IP/mask and zone, ok only for valid IPv6 IP/mask with non-empty zone.
+func splitZone(cidr string) (addr, zone string, ok bool) {
+	p := strings.IndexByte(cidr, '%')
+	if p < 0 {
+		return cidr, "", false
+	}
+	zone = cidr[p+1:]
+	addr = cidr[:p]
+	if m := strings.IndexByte(zone, '/'); m >= 0 {
+		zone, addr = zone[:m], addr+zone[m:]
+	}
+	if zone == "" || isIPv4([]byte(addr)) {
+		return cidr, "", false
+	}
+	if _, _, err := parsecidr6([]byte(addr)); err != nil {
+		return cidr, "", false
+	}
+	return addr, zone, true
+}
+
+// checkZone returns error for cidr with zone if the tree keeps zones: ErrZoneNotSupported for valid IPv6 IP/mask
+// with zone, ErrBadIP otherwise.
+func (tree *Tree) checkZone(cidr []byte) error {
+	if tree.zones == nil || bytes.IndexByte(cidr, '%') < 0 {
+		return nil
+	}
+	if _, _, ok := splitZone(string(cidr)); !ok {
+		return badIP(cidr, "zone of invalid or IPv4 address")
+	}
+	return ErrZoneNotSupported
+}
+
+// eachZone calls fn with every zone and its tree, holding the lock of the zones.
+func (tree *Tree) eachZone(fn func(zone string, zt *Tree)) {
+	if tree.zones == nil {
+		return
+	}
+	tree.zones.Lock()
+	defer tree.zones.Unlock()
+	for zone, zt := range tree.zones.trees {
+		fn(zone, zt)
+	}
+}
+
+func withoutZones(tree *Tree) {
+	tree.zones = nil
+}
+
+// stripZone returns the cidr without zone.
+func stripZone(cidr []byte) []byte {
+	p := bytes.IndexByte(cidr, '%')
+	if p < 0 {
+		return cidr
+	}
+	m := bytes.IndexByte(cidr[p:], '/')
+	if m < 0 {
+		return cidr[:p]
+	}
+	return append(cidr[:p:p], cidr[p+m:]...)
+}
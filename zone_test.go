@@ -0,0 +1,201 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestZoneStripped(t *testing.T) {
+	tr := NewTree()
+	if err := tr.AddCIDR("fe80::%eth0/64", 1); err != nil {
+		t.Error(err)
+	}
+	for _, cidr := range []string{"fe80::1%eth0", "fe80::1%eth1", "fe80::1"} {
+		inf, err := tr.FindCIDR(cidr)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != 1 {
+			t.Errorf("Wrong value for %s, expected 1, got %v", cidr, inf)
+		}
+	}
+	if inf, err := tr.FindExactCIDR("fe80::/64"); err != nil || inf != 1 {
+		t.Errorf("Wrong value, expected 1, got %v %v", inf, err)
+	}
+	if err := tr.DeleteCIDR("fe80::%eth1/64"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWithZones(t *testing.T) {
+	tr := NewTree(WithZones(), WithLocking(true))
+	tr.AddCIDR("fe80::/10", "link-local")
+	tr.AddCIDR("fe80::%eth0/64", "eth0")
+	tr.SetCIDR("fe80::%eth1/64", "eth1")
+
+	for cidr, expected := range map[string]interface{}{
+		"fe80::1%eth0": "eth0",
+		"fe80::1%eth1": "eth1",
+		"fe80::1%eth2": "link-local",
+		"fe80::1":      "link-local",
+	} {
+		inf, err := tr.FindCIDR(cidr)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != expected {
+			t.Errorf("Wrong value for %s, expected %v, got %v", cidr, expected, inf)
+		}
+	}
+	_, n, err := tr.FindCIDRNet("fe80::1%eth2")
+	if err != nil || n.String() != "fe80::/10" {
+		t.Errorf("Wrong net, expected fe80::/10, got %v %v", n.String(), err)
+	}
+	if inf, err := tr.FindExactCIDR("fe80::%eth0/64"); err != nil || inf != "eth0" {
+		t.Errorf("Wrong value, expected eth0, got %v %v", inf, err)
+	}
+	if inf, _ := tr.FindExactCIDR("fe80::/64"); inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+	if zones := tr.Zones(); len(zones) != 2 || zones[0] != "eth0" || zones[1] != "eth1" {
+		t.Errorf("Wrong zones, expected [eth0 eth1], got %v", zones)
+	}
+	if err := tr.DeleteCIDR("fe80::%eth0/64"); err != nil {
+		t.Error(err)
+	}
+	if inf, _ := tr.FindCIDR("fe80::1%eth0"); inf != "link-local" {
+		t.Errorf("Wrong value, expected link-local, got %v", inf)
+	}
+	tr.AddCIDR("fe80::/64", "unzoned")
+	if err := tr.DeleteCIDR("fe80::%eth9/64"); err != ErrNotFound {
+		t.Errorf("Wrong error, expected %v, got %v", ErrNotFound, err)
+	}
+	if inf, err := tr.FindExactCIDR("fe80::%eth9/64"); err != ErrNotFound {
+		t.Errorf("Wrong result, expected %v, got %v %v", ErrNotFound, inf, err)
+	}
+	if tr.Zone("eth1") == nil || tr.Zone("eth9") != nil || NewTree().Zone("eth1") != nil {
+		t.Errorf("Wrong zone trees")
+	}
+}
+
+func TestZonesMutatingVariants(t *testing.T) {
+	tr := NewTree(WithZones())
+	tr.AddCIDR("fe80::/64", "global")
+	if err := tr.AddCIDR("fe80::/64%eth0", "eth0"); err != nil {
+		t.Error(err)
+	}
+	if existing, err := tr.AddCIDRGet("fe80::%eth0/64", "again"); err != ErrNodeBusy || existing != "eth0" {
+		t.Errorf("Wrong AddCIDRGet, expected eth0 %v, got %v %v", ErrNodeBusy, existing, err)
+	}
+	err := tr.AddCIDRMerge("fe80::%eth0/64", "+", func(old, new interface{}) interface{} { return old.(string) + new.(string) })
+	if err != nil {
+		t.Error(err)
+	}
+	if updated, prev, err := tr.SetCIDRReport("fe80::%eth0/64", "eth0!"); err != nil || !updated || prev != "eth0+" {
+		t.Errorf("Wrong SetCIDRReport, expected true eth0+, got %v %v %v", updated, prev, err)
+	}
+	err = tr.UpdateCIDR("fe80::%eth1/64", func(old interface{}, found bool) (interface{}, bool) { return "eth1", false })
+	if err != nil {
+		t.Error(err)
+	}
+	if err = tr.SetCIDRWithTTL("fe80::1%eth1", "host", time.Hour); err != nil {
+		t.Error(err)
+	}
+	for cidr, expected := range map[string]interface{}{
+		"fe80::/64%eth0": "eth0!",
+		"fe80::/64%eth1": "eth1",
+		"fe80::1%eth1":   "host",
+		"fe80::/64":      "global",
+	} {
+		if inf, err := tr.FindExactCIDR(cidr); err != nil || inf != expected {
+			t.Errorf("Wrong value for %s, expected %v, got %v %v", cidr, expected, inf, err)
+		}
+	}
+	if r, err := tr.FindCIDRResult("fe80::2%eth1"); err != nil || r.Value != "eth1" || r.Bits != 64 {
+		t.Errorf("Wrong result, expected eth1/64, got %v %v", r, err)
+	}
+	if contained, exact := tr.ContainsCIDR("fe80::1%eth1"); !contained || !exact {
+		t.Errorf("Wrong containment, expected true true, got %v %v", contained, exact)
+	}
+	if nets, err := tr.Subnets("fe80::/64%eth1"); err != nil || len(nets) != 2 {
+		t.Errorf("Wrong subnets, expected 2, got %v %v", nets, err)
+	}
+
+	if deleted, err := tr.DeleteCIDRIf("fe80::/64%eth0", func(val interface{}) bool { return val == "eth0!" }); err != nil || !deleted {
+		t.Errorf("Wrong DeleteCIDRIf, expected true, got %v %v", deleted, err)
+	}
+	if _, err := tr.FindExactCIDR("fe80::/64%eth0"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if inf, err := tr.FindExactCIDR("fe80::/64"); err != nil || inf != "global" {
+		t.Errorf("Wrong value, expected global, got %v %v", inf, err)
+	}
+	if deleted, err := tr.DeleteCIDRIf("fe80::/64%eth9", func(val interface{}) bool { return true }); err != ErrNotFound || deleted {
+		t.Errorf("Expected ErrNotFound, got %v %v", deleted, err)
+	}
+	if err := tr.DeleteWholeRangeCIDR("fe80::/64%eth1"); err != nil {
+		t.Error(err)
+	}
+	if inf, _ := tr.FindCIDR("fe80::1%eth1"); inf != "global" {
+		t.Errorf("Wrong value after range delete, expected global, got %v", inf)
+	}
+
+	// operations without zone support reject zoned input
+	if _, err := tr.NodeRef("fe80::/64%eth0"); err != ErrZoneNotSupported {
+		t.Errorf("Expected ErrZoneNotSupported, got %v", err)
+	}
+	if _, err := tr.ExtractSubtree("fe80::/64%eth0"); err != ErrZoneNotSupported {
+		t.Errorf("Expected ErrZoneNotSupported, got %v", err)
+	}
+	if _, errs := tr.FindCIDRBatch([]string{"fe80::1", "fe80::1%eth0"}); errs == nil || errs[0] != nil || errs[1] != ErrZoneNotSupported {
+		t.Errorf("Expected ErrZoneNotSupported for the zoned cidr, got %v", errs)
+	}
+}
+
+func TestZonesOtherPaths(t *testing.T) {
+	tr := NewTree(WithZones())
+	err := tr.Apply(func(tx *Txn) error {
+		tx.AddCIDR("fe80::/64%eth0", 1)
+		return nil
+	})
+	if err != ErrZoneNotSupported {
+		t.Errorf("Wrong Apply error, expected %v, got %v", ErrZoneNotSupported, err)
+	}
+	if err = tr.LoadFrom(strings.NewReader("fe80::1%eth1 x\n"), FormatFields, nil); !errors.Is(err, ErrZoneNotSupported) {
+		t.Errorf("Wrong LoadFrom error, expected %v, got %v", ErrZoneNotSupported, err)
+	}
+	for _, cidr := range []string{"zzz%eth0", "10.0.0.1%eth1", "fe80::1%"} {
+		if err = tr.AddCIDR(cidr, 2); !errors.Is(err, ErrBadIP) {
+			t.Errorf("Wrong AddCIDR error for %s, expected %v, got %v", cidr, ErrBadIP, err)
+		}
+	}
+	if inf, _ := tr.FindExactCIDR("fe80::/64"); inf != nil {
+		t.Errorf("Expected no value without zone, got %v", inf)
+	}
+	if zones := tr.Zones(); len(zones) != 0 {
+		t.Errorf("Expected no zones, got %v", zones)
+	}
+
+	tr.AddCIDR("fe80::/64%eth0", "eth0")
+	tr.AddCIDR("10.0.0.0/8", "ten")
+	clone := tr.Clone()
+	if inf, _ := clone.FindExactCIDR("fe80::/64%eth0"); inf != "eth0" {
+		t.Errorf("Wrong cloned zone value, expected eth0, got %v", inf)
+	}
+	if err = clone.Validate(); err != nil {
+		t.Error(err)
+	}
+	tr.Clear()
+	if zones := tr.Zones(); len(zones) != 0 {
+		t.Errorf("Expected no zones after Clear, got %v", zones)
+	}
+	if inf, _ := clone.FindExactCIDR("fe80::/64%eth0"); inf != "eth0" {
+		t.Errorf("Clear changed the clone, expected eth0, got %v", inf)
+	}
+}